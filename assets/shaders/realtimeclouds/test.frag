@@ -11,6 +11,7 @@ layout(binding = 3) uniform sampler2D cloudMapTex;
 // includes                                                                                                           //
 //--------------------------------------------------------------------------------------------------------------------//
 #include "util/camera.glsl"
+#include "util/blocks.glsl"
 #include "util/ray.glsl"
 #include "util/math.glsl"
 //#include "cloud/density.glsl"
@@ -18,25 +19,11 @@ layout(binding = 3) uniform sampler2D cloudMapTex;
 //--------------------------------------------------------------------------------------------------------------------//
 // uniforms                                                                                                           //
 //--------------------------------------------------------------------------------------------------------------------//
-// camera
-uniform Camera uCamera;
-// sun
-uniform vec3   uSunPos           = vec3(40000, -1000, 0);
-// atmosphere
-uniform float  uInnerHeight      = 14000;
-uniform float  uOuterHeight      = 40000;
-uniform float  uExtinctionCoeff  = 1.0/26000.0;
 // clouds
 uniform float  uGlobalDensity    = 0.5;
 uniform float  uGlobalCoverage   = 0.5;
 // animation
 uniform float  uTime             = 0;
-uniform float  uWindSpeed        = 10;
-uniform vec3   uWindDir          = vec3(1, 0, 0);
-// colors
-uniform vec3   uSunColor         = vec3(1, 1, 0);
-uniform vec3   uAmbientColor     = vec3(1, 0, 0);
-uniform vec3   uAtmosphereColor  = vec3(0.6, 0.7, 0.95);
 
 //--------------------------------------------------------------------------------------------------------------------//
 // constants                                                                                                          //
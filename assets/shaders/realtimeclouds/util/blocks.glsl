@@ -0,0 +1,23 @@
+#include "camera.glsl"
+
+// uniform blocks shared by all passes, the layout has to match the Go structs
+// CameraBlock and AtmosphereBlock
+layout(std140) uniform CameraBlock {
+    Camera uCamera;
+};
+
+layout(std140) uniform AtmosphereBlock {
+    // sun
+    vec3  uSunPos;
+    // atmosphere
+    float uInnerHeight;
+    float uOuterHeight;
+    float uExtinctionCoeff;
+    // animation
+    float uWindSpeed;
+    vec3  uWindDir;
+    // colors
+    vec3  uSunColor;
+    vec3  uAmbientColor;
+    vec3  uAtmosphereColor;
+};
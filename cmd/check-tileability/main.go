@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image3d"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/tileability"
+)
+
+// makeSlicePaths creates the paths of numbered slices like dir/base0.png
+// to dir/base<slices-1>.png from the path dir/base.png.
+func makeSlicePaths(path string, slices int) []string {
+	ext := filepath.Ext(path)
+	pathnoext := strings.TrimSuffix(path, ext)
+
+	paths := make([]string, slices)
+	for i := 0; i < slices; i++ {
+		paths[i] = pathnoext + strconv.Itoa(i) + ext
+	}
+	return paths
+}
+
+// isVolumeFile returns true if the path points to a volume, DDS or KTX2 file.
+func isVolumeFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".vol", ".ktx2", ".dds":
+		return true
+	}
+	return false
+}
+
+// loadVolumeFile loads the volume file or the first mip level of the DDS or
+// KTX2 file at the specified path.
+func loadVolumeFile(path string) (image3d.Image3D, error) {
+	var chain image3d.MipChain
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ktx2":
+		chain, err = image3d.LoadKTX2(path)
+	case ".dds":
+		chain, err = image3d.LoadDDS(path)
+	default:
+		return image3d.Load(path)
+	}
+	if err != nil {
+		return image3d.Image3D{}, err
+	}
+	return chain.ToImage()
+}
+
+func main() {
+	slices := flag.Int("slices", 0, "number of numbered slices of a 3D texture, 0 for a 2D texture or a volume file")
+	threshold := flag.Float64("threshold", tileability.DefaultThreshold, "maximum ratio between seam and interior differences")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: check-tileability [-slices N] [-threshold T] path")
+		fmt.Fprintln(os.Stderr, "for 3D textures path dir/base.png loads dir/base0.png to dir/base<N-1>.png")
+		fmt.Fprintln(os.Stderr, "paths ending with .vol, .ktx2 or .dds are loaded as volume files, of which only the first mip level is checked")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	path := flag.Arg(0)
+
+	// measure the seams of the 2D or 3D texture
+	var report tileability.Report
+	if isVolumeFile(path) {
+		image, err := loadVolumeFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		report = tileability.Check3D(&image, *threshold)
+	} else if *slices > 0 {
+		image, err := image3d.MakeFromPath(makeSlicePaths(path, *slices))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		report = tileability.Check3D(&image, *threshold)
+	} else {
+		image, err := image2d.MakeFromPath(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		report = tileability.Check2D(&image, *threshold)
+	}
+
+	fmt.Println(path)
+	fmt.Println(report)
+	if err := report.Err(); err != nil {
+		os.Exit(1)
+	}
+}
@@ -1,16 +1,18 @@
 package main
 
 import (
+	"flag"
 	"math"
 	"runtime"
-	"time"
 
 	"github.com/adrianderstroff/realtime-clouds/pkg/buffer/fbo"
 	"github.com/go-gl/mathgl/mgl32"
 
+	"github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
 	"github.com/adrianderstroff/realtime-clouds/pkg/core/interaction"
 	"github.com/adrianderstroff/realtime-clouds/pkg/core/window"
 	"github.com/adrianderstroff/realtime-clouds/pkg/scene/camera/trackball"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/texture"
 )
 
 const (
@@ -23,15 +25,44 @@ const (
 )
 
 func main() {
+	// parse flags
+	windowoptions := window.AddFlags()
+	capturepath := flag.String("capture", "capture/frame.png", "path of captured png or exr frames, F12 takes a screenshot and F11 starts or stops recording")
+	record := flag.Int("record", 0, "number of frames recorded from the first frame on, negative to record all frames")
+	frametime := flag.Duration("frametime", 0, "advance the time by a fixed amount per frame, e.g. 16ms, for deterministic offline rendering")
+	flag.Parse()
+
 	runtime.LockOSThread()
 
 	// setup window
 	title := "Cloud Visualization"
-	window, _ := window.New(title, int(WIDTH), int(HEIGHT))
+	captureoptions := window.CaptureOptions{Path: *capturepath}
+	window, err := window.NewWithOptions(title, int(WIDTH), int(HEIGHT), *windowoptions)
+	if err != nil {
+		panic(err)
+	}
 	window.LockFPS(60)
+	window.EnableShaderReload()
 	interaction := interaction.New(window)
 	defer window.Close()
 
+	// setup capture
+	capture, err := window.EnableCapture(captureoptions)
+	if err != nil {
+		panic(err)
+	}
+	if *record != 0 {
+		capture.Record(*record)
+	}
+	interaction.AddKeyPressHandler(capture.OnKeyPress)
+
+	// setup clock
+	clock := window.GetClock()
+	if *frametime > 0 {
+		clock.SetVirtual(*frametime)
+	}
+	interaction.AddKeyPressHandler(clock.OnKeyPress)
+
 	// make camera
 	camera := trackball.MakeDefault(WIDTH, HEIGHT, 10.0)
 	interaction.AddInteractable(&camera)
@@ -40,13 +71,13 @@ func main() {
 	fbo1 := fbo.Make(WIDTH, HEIGHT)
 
 	// generate cloud base texture
-	cloudbasetex, err := Make3DCloudTexture(TEX_PATH+"cloud-base/base", 128)
+	cloudbasetex, err := texture.Make3DFromFile(TEX_PATH+"cloud-base/base.ktx2", gl.RGBA, gl.RGBA)
 	if err != nil {
 		panic(err)
 	}
 
 	// generate 3D texture with worley noise
-	clouddetailtex, err := Make3DCloudTexture(TEX_PATH+"cloud-detail/detail", 32)
+	clouddetailtex, err := texture.Make3DFromFile(TEX_PATH+"cloud-detail/detail.ktx2", gl.RGBA, gl.RGBA)
 	if err != nil {
 		panic(err)
 	}
@@ -55,14 +86,13 @@ func main() {
 	// setup raymarching pass
 	raymarchingpass := MakeRaymarchingPass(WIDTH, HEIGHT, SHADER_PATH)
 
-	start := time.Now()
 	// render loop
 	renderloop := func() {
 		// update title
 		window.SetTitle(title + " " + window.GetFPSFormatted())
 
 		// get delta time
-		delta := float32(time.Now().Sub(start).Seconds())
+		delta := float32(clock.GetElapsed())
 
 		// update camera
 		camera.Update()
@@ -78,4 +108,9 @@ func main() {
 		fbo1.CopyToScreen(0, 0, 0, int32(WIDTH), int32(HEIGHT))
 	}
 	window.RunMainLoop(renderloop)
+
+	// report frames that couldn't be captured
+	if err := capture.GetError(); err != nil {
+		panic(err)
+	}
 }
@@ -10,49 +10,100 @@ import (
 
 const (
 	TEX_PATH = "./assets/images/textures/"
+
+	// SEED is the base seed of all noise generators. Changing it results in
+	// a different but reproducible set of textures.
+	SEED int64 = 0
 )
 
+// createWorleyFBM creates a cube of worley noise with three octaves starting
+// with res cells. The octaves use the seeds seed to seed+2.
+func createWorleyFBM(size, res int, seed int64) []uint8 {
+	options := noise.DefaultWorleyOptions(res)
+	options.Octaves = 3
+	field, _, err := noise.Worley3DField(size, size, size, options, seed)
+	if err != nil {
+		panic(err)
+	}
+	return field.ToUint8()
+}
+
+// packVolume creates a cube with the specified size and one channel for each
+// of the provided noise volumes.
+func packVolume(size int, volumes ...[]uint8) (image3d.Image3D, error) {
+	var sources []*image3d.Image3D
+	for _, volume := range volumes {
+		source, err := image3d.MakeFromData(size, size, size, volume)
+		if err != nil {
+			return image3d.Image3D{}, err
+		}
+		sources = append(sources, &source)
+	}
+
+	return image3d.Pack(sources...)
+}
+
+// saveMipChain saves the volume together with all of its mip levels and its
+// parameters to a KTX2 file at path, so that they don't need to be generated
+// at startup. The levels are downsampled with a Kaiser filter that wraps
+// around, as the volumes tile.
+func saveMipChain(image *image3d.Image3D, path string) error {
+	chain, err := image.GenerateMipChain(image3d.KaiserFilter, true)
+	if err != nil {
+		return err
+	}
+	return chain.SaveKTX2(path)
+}
+
 func createCloudBaseTexture() {
 	fmt.Println("Creating Cloud Base Shape")
-	// red
-	p1 := noise.Perlin3D(128, 128, 128, 5, 8)
-	w1 := noise.Worley3D(128, 128, 128, 4)
-	pw1 := remapAll(spread(p1), w1)
-	pw1 = w1
-	// green
-	w2 := noise.Worley3D(128, 128, 128, 8)
-	// blue
-	w3 := noise.Worley3D(128, 128, 128, 16)
-	// alpha
-	w4 := noise.Worley3D(128, 128, 128, 32)
-
-	cloudBaseData := mergeColorChannels(pw1, w2, w3, w4)
-	cloudBaseImage, err := image3d.MakeFromData(128, 128, 128, cloudBaseData)
+	// red, the worley octaves use the seeds SEED+1 to SEED+3
+	// PerlinWorley3D returns nil for invalid parameters, which packVolume rejects
+	pw1 := noise.PerlinWorley3D(128, 128, 128, 4, 5, 4, 3, SEED)
+	// green, blue and alpha are worley fbms of increasing frequency
+	w2 := createWorleyFBM(128, 8, SEED+4)
+	w3 := createWorleyFBM(128, 16, SEED+7)
+	w4 := createWorleyFBM(128, 32, SEED+10)
+
+	cloudBaseImage, err := packVolume(128, pw1, w2, w3, w4)
+	if err != nil {
+		panic(err)
+	}
+	cloudBaseImage.SetParam("seed", SEED)
+	cloudBaseImage.SetParam("r", "perlinworley perlin freq 4 octaves 5, worley freq 4 octaves 3")
+	cloudBaseImage.SetParam("g", "worley fbm res 8 octaves 3")
+	cloudBaseImage.SetParam("b", "worley fbm res 16 octaves 3")
+	cloudBaseImage.SetParam("a", "worley fbm res 32 octaves 3")
+	err = saveMipChain(&cloudBaseImage, TEX_PATH+"cloud-base/base.ktx2")
 	if err != nil {
 		panic(err)
 	}
-	cloudBaseImage.SaveToPath(TEX_PATH + "cloud-base/base.png")
 }
 
 func createCloudDetailTexture() {
 	fmt.Println("Creating Cloud Detail")
-	f1 := noise.Worley3D(32, 32, 32, 5)
-	f2 := noise.Worley3D(32, 32, 32, 6)
-	f3 := noise.Worley3D(32, 32, 32, 7)
-	cloudDetailData := mergeColorChannels(f1, f2, f3)
-	cloudDetailImage, err := image3d.MakeFromData(32, 32, 32, cloudDetailData)
+	// Worley3D returns nil for invalid parameters, which packVolume rejects
+	f1 := noise.Worley3D(32, 32, 32, 5, SEED+13)
+	f2 := noise.Worley3D(32, 32, 32, 6, SEED+14)
+	f3 := noise.Worley3D(32, 32, 32, 7, SEED+15)
+	cloudDetailImage, err := packVolume(32, f1, f2, f3)
+	if err != nil {
+		panic(err)
+	}
+	cloudDetailImage.SetParam("seed", SEED)
+	cloudDetailImage.SetParam("r", "worley res 5")
+	cloudDetailImage.SetParam("g", "worley res 6")
+	cloudDetailImage.SetParam("b", "worley res 7")
+	err = saveMipChain(&cloudDetailImage, TEX_PATH+"cloud-detail/detail.ktx2")
 	if err != nil {
 		panic(err)
 	}
-	cloudDetailImage.SaveToPath(TEX_PATH + "cloud-detail/detail.png")
 }
 
 func createCloudTurbulenceTexture() {
 	fmt.Println("Creating Cloud Turbulence")
-	c1 := noise.Curl2D(128, 128, 5)
-	c2 := noise.Curl2D(128, 128, 6)
-	c3 := noise.Curl2D(128, 128, 7)
-	cloudTurbulenceData := mergeColorChannels(c1, c2, c3)
+	// the divergence-free vectors are stored in the red and green channel
+	cloudTurbulenceData := noise.Curl2D(128, 128, 5, 0.5, SEED+16)
 	cloudTurbulenceImage, err := image2d.MakeFromData(128, 128, cloudTurbulenceData)
 	if err != nil {
 		panic(err)
@@ -62,30 +113,23 @@ func createCloudTurbulenceTexture() {
 
 func createCloudMapTexture() {
 	fmt.Println("Creating cloud map")
-	red := noise.Worley2D(1024, 1024, 8)
-	red = threshold(red, 200)
-
-	/*
-		cp1 := noise.Perlin2D(1024, 1024, 16, 1)
-		cp2 := noise.Perlin2D(1024, 1024, 32, 1)
-		cp3 := noise.Perlin2D(1024, 1024, 64, 1)
-		cw1 := noise.Worley2D(1024, 1024, 16)
-		cw2 := noise.Worley2D(1024, 1024, 32)
-		cw3 := noise.Worley2D(1024, 1024, 64)
-		cpn := combine(cp1, cp2, cp3)
-		cwn := combine(cw1, cw2, cw3)
-		_ = cpn
-		_ = cwn
-	*/
-	green := noise.Perlin2D(1024, 1024, 4, 8)
-
-	blue := createAndFillImage(1024*1024, 125)
-
-	cloudMapData := mergeColorChannels(red, green, blue)
-	cloudMapImage, err := image2d.MakeFromData(1024, 1024, cloudMapData)
+	// Worley2D returns nil for invalid parameters, which MakeFromData rejects
+	red, err := image2d.MakeFromData(1024, 1024, noise.Worley2D(1024, 1024, 8, 128, SEED+17))
+	if err != nil {
+		panic(err)
+	}
+	green, err := image2d.MakeFromData(1024, 1024, noise.Perlin2D(1024, 1024, 4, 8, SEED+18))
+	if err != nil {
+		panic(err)
+	}
+
+	// the blue channel is constant
+	cloudMapImage, err := image2d.Pack(&red, &green, nil)
 	if err != nil {
 		panic(err)
 	}
+	cloudMapImage.Threshold(0, 200.0/255.0)
+	cloudMapImage.FillChannel(2, 125.0/255.0)
 	cloudMapImage.SaveToPath(TEX_PATH + "cloud-map/cloud-map.png")
 }
 
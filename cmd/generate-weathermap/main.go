@@ -1,12 +1,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"runtime"
 
 	"github.com/adrianderstroff/realtime-clouds/pkg/gui"
 
 	"github.com/adrianderstroff/realtime-clouds/pkg/scene/camera/trackball"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
 	"github.com/adrianderstroff/realtime-clouds/pkg/view/texture"
 
 	"github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
@@ -97,14 +99,44 @@ func saveState(state *State) {
 	}
 }
 
+// saveWeatherMap saves the weather map as 32 bit float OpenEXR and as 16 bit png.
+func saveWeatherMap(weathermaptexture *texture.Texture) {
+	weathermap, err := weathermaptexture.ReadImage2D(1024, 1024, 4, gl.FLOAT)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	weathermap.FlipY()
+	if err := weathermap.SaveToPath(OUT_PATH + "weathermap.exr"); err != nil {
+		fmt.Println(err)
+	}
+	weathermap16, err := weathermap.Convert(gl.UNSIGNED_SHORT)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err := weathermap16.SaveToPath(OUT_PATH + "weathermap.png"); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println("Saved weather map to", OUT_PATH+"weathermap.exr")
+}
+
 func main() {
+	// parse flags
+	windowoptions := window.AddFlags()
+	flag.Parse()
+
 	// has to be called when using opengl context
 	runtime.LockOSThread()
 
 	// setup window
 	title := "Generate Weather Map"
-	window, _ := window.New(title, int(WIDTH), int(HEIGHT))
+	window, err := window.NewWithOptions(title, int(WIDTH), int(HEIGHT), *windowoptions)
+	if err != nil {
+		panic(err)
+	}
 	window.LockFPS(60)
+	window.EnableShaderReload()
 	defer window.Close()
 
 	// set initial gl settings
@@ -120,16 +152,22 @@ func main() {
 	saveState(state)
 
 	// make textures
-	image := createAndFillImage(1024*1024*4, 120)
-	weathermaptexture, err := texture.MakeFromData(image, 1024, 1024, gl.RGBA32F, gl.RGBA)
+	image, err := image2d.Make(1024, 1024, 4)
+	if err != nil {
+		panic(err)
+	}
+	for c := 0; c < 4; c++ {
+		image.FillChannel(c, 120.0/255.0)
+	}
+	weathermaptexture, err := texture.MakeFromData(image.GetData(), 1024, 1024, gl.RGBA32F, gl.RGBA)
 	if err != nil {
 		panic(err)
 	}
-	perlintexture, err := texture.MakeFromData(image, 1024, 1024, gl.RGBA32F, gl.RGBA)
+	perlintexture, err := texture.MakeFromData(image.GetData(), 1024, 1024, gl.RGBA32F, gl.RGBA)
 	if err != nil {
 		panic(err)
 	}
-	worleytexture, err := texture.MakeFromData(image, 1024, 1024, gl.RGBA32F, gl.RGBA)
+	worleytexture, err := texture.MakeFromData(image.GetData(), 1024, 1024, gl.RGBA32F, gl.RGBA)
 	if err != nil {
 		panic(err)
 	}
@@ -144,8 +182,11 @@ func main() {
 	merge := MakeMerge(SHADER_PATH)
 	postprocess := MakePostProcess(SHADER_PATH)
 
-	// create gui
-	gamegui := gui.New(window.Window)
+	// create gui, headless windows have no input thus no gui
+	var gamegui *gui.GUI
+	if !window.IsHeadless() {
+		gamegui = gui.New(window.Window)
+	}
 
 	// operations
 	operations := make([]string, 3)
@@ -185,7 +226,15 @@ func main() {
 
 		renderpass.Render(&camera, &weathermaptexture)
 
+		// save the weather map without losing its range
+		if state.SaveTexture {
+			saveWeatherMap(&weathermaptexture)
+		}
+
 		// gui
+		if gamegui == nil {
+			return
+		}
 		gamegui.Begin()
 		if gamegui.BeginWindow("Options", 0, 0, 250, float32(HEIGHT)) {
 			if gamegui.BeginGroup("Perlin", 350) {
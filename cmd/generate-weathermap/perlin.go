@@ -11,7 +11,7 @@ import (
 // Perlin is a gpu perlin noise generator
 type Perlin struct {
 	computeshader shader.Shader
-	permuations   ssbo.SSBO
+	permuations   ssbo.Buffer[int32]
 	// dimensions
 	width   int32
 	height  int32
@@ -71,8 +71,10 @@ func MakePerlin(shaderpath string) Perlin {
 		p[i] = permutation[i%permlen]
 	}
 
-	permutationsbuffer := ssbo.Make(ssbo.Int32, len(p))
-	permutationsbuffer.UploadArrayI32(p)
+	permutationsbuffer, err := ssbo.MakeBufferFrom(p)
+	if err != nil {
+		panic(err)
+	}
 
 	return Perlin{
 		computeshader: computeshader,
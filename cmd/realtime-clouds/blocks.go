@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/adrianderstroff/realtime-clouds/pkg/scene/camera"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// CameraUniform mirrors the Camera struct of util/camera.glsl.
+type CameraUniform struct {
+	Pos    mgl32.Vec3 `std140:"pos"`
+	V      mgl32.Mat4 `std140:"V"`
+	P      mgl32.Mat4 `std140:"P"`
+	Fov    float32    `std140:"fov"`
+	Aspect float32    `std140:"aspect"`
+}
+
+// CameraBlock mirrors the CameraBlock uniform block of util/blocks.glsl.
+type CameraBlock struct {
+	Camera CameraUniform `std140:"uCamera"`
+}
+
+// MakeCameraBlock constructs the CameraBlock from the current state of the camera.
+func MakeCameraBlock(camera camera.Camera, width, height int) CameraBlock {
+	return CameraBlock{
+		Camera: CameraUniform{
+			Pos:    camera.GetPos(),
+			V:      camera.GetView(),
+			P:      camera.GetPerspective(),
+			Fov:    45.0,
+			Aspect: float32(width) / float32(height),
+		},
+	}
+}
+
+// AtmosphereBlock mirrors the AtmosphereBlock uniform block of util/blocks.glsl.
+type AtmosphereBlock struct {
+	// sun
+	SunPos mgl32.Vec3 `std140:"uSunPos"`
+	// atmosphere
+	InnerHeight     float32 `std140:"uInnerHeight"`
+	OuterHeight     float32 `std140:"uOuterHeight"`
+	ExtinctionCoeff float32 `std140:"uExtinctionCoeff"`
+	// animation
+	WindSpeed float32    `std140:"uWindSpeed"`
+	WindDir   mgl32.Vec3 `std140:"uWindDir"`
+	// colors
+	SunColor        mgl32.Vec3 `std140:"uSunColor"`
+	AmbientColor    mgl32.Vec3 `std140:"uAmbientColor"`
+	AtmosphereColor mgl32.Vec3 `std140:"uAtmosphereColor"`
+}
+
+// MakeDefaultAtmosphereBlock constructs the AtmosphereBlock with the values
+// that had been the defaults of the cloud shaders.
+func MakeDefaultAtmosphereBlock() AtmosphereBlock {
+	return AtmosphereBlock{
+		SunPos:          mgl32.Vec3{40000, -1000, 0},
+		InnerHeight:     14000,
+		OuterHeight:     40000,
+		ExtinctionCoeff: 1.0 / 26000.0,
+		WindSpeed:       10,
+		WindDir:         mgl32.Vec3{1, 0, 0},
+		SunColor:        mgl32.Vec3{1, 1, 0},
+		AmbientColor:    mgl32.Vec3{1, 0, 0},
+		AtmosphereColor: mgl32.Vec3{0.6, 0.7, 0.95},
+	}
+}
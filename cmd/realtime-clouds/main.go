@@ -1,8 +1,10 @@
 package main
 
 import (
+	"flag"
 	"runtime"
 
+	"github.com/adrianderstroff/realtime-clouds/pkg/buffer/ubo"
 	"github.com/adrianderstroff/realtime-clouds/pkg/core/interaction"
 	"github.com/adrianderstroff/realtime-clouds/pkg/core/window"
 	"github.com/adrianderstroff/realtime-clouds/pkg/scene/camera/fps"
@@ -17,31 +19,73 @@ const (
 
 	WIDTH  int = 800
 	HEIGHT int = 600
+
+	// animation time per second, the clouds used to advance by 10 per frame at 60 fps
+	ANIMATION_SPEED float32 = 600
 )
 
 func main() {
+	// parse flags
+	windowoptions := window.AddFlags()
+	capturepath := flag.String("capture", "capture/frame.png", "path of captured png or exr frames, F12 takes a screenshot and F11 starts or stops recording")
+	record := flag.Int("record", 0, "number of frames recorded from the first frame on, negative to record all frames")
+	frametime := flag.Duration("frametime", 0, "advance the time by a fixed amount per frame, e.g. 16ms, for deterministic offline rendering")
+	flag.Parse()
+
 	// has to be called when using opengl context
 	runtime.LockOSThread()
 
 	// setup window
 	title := "Realtime Clouds"
-	window, _ := window.New(title, int(WIDTH), int(HEIGHT))
+	captureoptions := window.CaptureOptions{Path: *capturepath}
+	window, err := window.NewWithOptions(title, int(WIDTH), int(HEIGHT), *windowoptions)
+	if err != nil {
+		panic(err)
+	}
 	window.LockFPS(60)
+	window.EnableShaderReload()
 	interaction := interaction.New(window)
 	defer window.Close()
 
+	// setup capture
+	capture, err := window.EnableCapture(captureoptions)
+	if err != nil {
+		panic(err)
+	}
+	if *record != 0 {
+		capture.Record(*record)
+	}
+	interaction.AddKeyPressHandler(capture.OnKeyPress)
+
+	// setup clock
+	clock := window.GetClock()
+	if *frametime > 0 {
+		clock.SetVirtual(*frametime)
+	}
+	interaction.AddKeyPressHandler(clock.OnKeyPress)
+
 	// make camera
 	camera := fps.MakeDefault(WIDTH, HEIGHT, mgl32.Vec3{5, 2, 0}, 20)
 	interaction.AddInteractable(&camera)
 
+	// make uniform blocks shared by all passes
+	camerablock, err := ubo.Make(MakeCameraBlock(&camera, WIDTH, HEIGHT))
+	if err != nil {
+		panic(err)
+	}
+	ubo.Register("CameraBlock", &camerablock)
+	atmosphereblock, err := ubo.Make(MakeDefaultAtmosphereBlock())
+	if err != nil {
+		panic(err)
+	}
+	ubo.Register("AtmosphereBlock", &atmosphereblock)
+
 	// make passes
 	raymarchingpass := MakeRaymarchingPass(WIDTH, HEIGHT, TEX_PATH, SHADER_PATH)
 	interaction.AddInteractable(&raymarchingpass)
 	landscapepass := MakeLandscapePass(SHADER_PATH)
 	_ = landscapepass
 
-	var time float32 = 0
-
 	// render loop
 	renderloop := func() {
 		// update title
@@ -49,12 +93,16 @@ func main() {
 
 		// update camera
 		camera.Update()
+		camerablock.Upload(MakeCameraBlock(&camera, WIDTH, HEIGHT))
 
 		// do raymarching passes
 		//landscapepass.Render(&camera)
-		raymarchingpass.Render(&camera, time)
-
-		time += 10
+		raymarchingpass.Render(float32(clock.GetElapsed()) * ANIMATION_SPEED)
 	}
 	window.RunMainLoop(renderloop)
+
+	// report frames that couldn't be captured
+	if err := capture.GetError(); err != nil {
+		panic(err)
+	}
 }
@@ -1,10 +1,10 @@
 package main
 
 import (
+	"github.com/adrianderstroff/realtime-clouds/pkg/buffer/ubo"
 	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
 	"github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
 	"github.com/adrianderstroff/realtime-clouds/pkg/core/shader"
-	"github.com/adrianderstroff/realtime-clouds/pkg/scene/camera"
 	"github.com/adrianderstroff/realtime-clouds/pkg/view/mesh/plane"
 	"github.com/adrianderstroff/realtime-clouds/pkg/view/texture"
 	"github.com/go-gl/glfw/v3.2/glfw"
@@ -23,17 +23,15 @@ type RaymarchingPass struct {
 }
 
 func MakeRaymarchingPass(width, height int, texpath, shaderpath string) RaymarchingPass {
-	// create textures
-	cloudbasefbo, err := texture.Make3DFromPath(MakePathsFromDirectory(texpath+"cloud-base/", "base", "png", 0, 127), gl.RGBA, gl.RGBA)
+	// create textures, the mip levels of the noise volumes are generated by create-noise-textures
+	cloudbasefbo, err := texture.Make3DFromFile(texpath+"cloud-base/base.ktx2", gl.RGBA, gl.RGBA)
 	if err != nil {
 		panic(err)
 	}
-	cloudbasefbo.GenMipmap()
-	clouddetailfbo, err := texture.Make3DFromPath(MakePathsFromDirectory(texpath+"cloud-detail/", "detail", "png", 0, 31), gl.RGBA, gl.RGBA)
+	clouddetailfbo, err := texture.Make3DFromFile(texpath+"cloud-detail/detail.ktx2", gl.RGBA, gl.RGBA)
 	if err != nil {
 		panic(err)
 	}
-	clouddetailfbo.GenMipmap()
 	turbulencefbo, err := texture.MakeFromPath(texpath+"cloud-turbulence/turbulence.png", gl.RGBA, gl.RGBA)
 	if err != nil {
 		panic(err)
@@ -58,6 +56,7 @@ func MakeRaymarchingPass(width, height int, texpath, shaderpath string) Raymarch
 		panic(err)
 	}
 	raymarchshader.AddRenderable(plane)
+	ubo.Connect(&raymarchshader)
 
 	return RaymarchingPass{
 		cloudbasefbo:   cloudbasefbo,
@@ -72,18 +71,13 @@ func MakeRaymarchingPass(width, height int, texpath, shaderpath string) Raymarch
 }
 
 // Render draws the clouds
-func (rmp *RaymarchingPass) Render(camera camera.Camera, time float32) {
+func (rmp *RaymarchingPass) Render(time float32) {
 	rmp.cloudbasefbo.Bind(0)
 	rmp.clouddetailfbo.Bind(1)
 	rmp.turbulencefbo.Bind(2)
 	rmp.cloudmapfbo.Bind(3)
 
 	rmp.raymarchshader.Use()
-	rmp.raymarchshader.UpdateVec3("uCamera.pos", camera.GetPos())
-	rmp.raymarchshader.UpdateMat4("uCamera.V", camera.GetView())
-	rmp.raymarchshader.UpdateMat4("uCamera.P", camera.GetPerspective())
-	rmp.raymarchshader.UpdateFloat32("uCamera.fov", 45.0)
-	rmp.raymarchshader.UpdateFloat32("uCamera.aspect", 800.0/600.0)
 	rmp.raymarchshader.UpdateMat4("M", mgl32.Ident4())
 	rmp.raymarchshader.UpdateFloat32("uTime", time)
 	rmp.raymarchshader.Render()
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"math"
 	"runtime"
 	"time"
@@ -26,15 +27,36 @@ const (
 )
 
 func main() {
+	// parse flags
+	windowoptions := window.AddFlags()
+	capturepath := flag.String("capture", "capture/frame.png", "path of captured png or exr frames, F12 takes a screenshot and F11 starts or stops recording")
+	record := flag.Int("record", 0, "number of frames recorded from the first frame on, negative to record all frames")
+	flag.Parse()
+
 	runtime.LockOSThread()
 
 	// setup window
 	title := "Volume raymarching"
-	window, _ := window.New(title, int(WIDTH), int(HEIGHT))
+	captureoptions := window.CaptureOptions{Path: *capturepath}
+	window, err := window.NewWithOptions(title, int(WIDTH), int(HEIGHT), *windowoptions)
+	if err != nil {
+		panic(err)
+	}
 	window.LockFPS(60)
+	window.EnableShaderReload()
 	interaction := interaction.New(window)
 	defer window.Close()
 
+	// setup capture
+	capture, err := window.EnableCapture(captureoptions)
+	if err != nil {
+		panic(err)
+	}
+	if *record != 0 {
+		capture.Record(*record)
+	}
+	interaction.AddKeyPressHandler(capture.OnKeyPress)
+
 	// make camera
 	camera := trackball.MakeDefault(WIDTH, HEIGHT, 10.0)
 	interaction.AddInteractable(&camera)
@@ -46,32 +68,33 @@ func main() {
 	fbo4 := fbo.Make(WIDTH, HEIGHT)
 	fbo5 := fbo.MakeEmpty()
 
-	// generate 3D texture with worley noise
-	worleydata := noise.Worley3D(128, 128, 128, 5)
+	// generate 3D texture with worley noise, the resolutions are positive
+	// thus Worley3D doesn't return nil
+	worleydata := noise.Worley3D(128, 128, 128, 5, 0)
 	worleytex, err := texture.Make3DFromData(worleydata, 128, 128, 128, gl.RED, gl.RED)
 	if err != nil {
 		panic(err)
 	}
-	worleydata = noise.Worley3D(128, 128, 128, 10)
+	worleydata = noise.Worley3D(128, 128, 128, 10, 1)
 	worleytex2, err := texture.Make3DFromData(worleydata, 128, 128, 128, gl.RED, gl.RED)
 	if err != nil {
 		panic(err)
 	}
 
 	// generate 3D texture with perlin noise
-	perlindata := noise.Perlin3D(128, 128, 128, 2, 16)
+	perlindata := noise.Perlin3D(128, 128, 128, 2, 16, 2)
 	perlintex, err := texture.Make3DFromData(perlindata, 128, 128, 128, gl.RED, gl.RED)
 	if err != nil {
 		panic(err)
 	}
-	perlindata = noise.Perlin3D(128, 128, 128, 3, 1)
+	perlindata = noise.Perlin3D(128, 128, 128, 3, 1, 3)
 	perlintex2, err := texture.Make3DFromData(perlindata, 128, 128, 128, gl.RED, gl.RED)
 	if err != nil {
 		panic(err)
 	}
 
 	// generate 2D texture with curl noise
-	curldata := noise.Perlin2D(128, 128, 10, 1)
+	curldata := noise.Perlin2D(128, 128, 10, 1, 4)
 	curltex, err := texture.MakeFromData(curldata, 128, 128, gl.RGB, gl.RED)
 	if err != nil {
 		panic(err)
@@ -110,4 +133,9 @@ func main() {
 		fbo5.CopyToScreenRegion(0, 0, 0, 128, 128, int32(WIDTH/2-100), int32(HEIGHT/2-100), 200, 200)
 	}
 	window.RunMainLoop(renderloop)
+
+	// report frames that couldn't be captured
+	if err := capture.GetError(); err != nil {
+		panic(err)
+	}
 }
@@ -17,6 +17,11 @@ type FBO struct {
 	textureType   uint32
 }
 
+// GetHandle returns the OpenGL handle of this FBO.
+func (fbo *FBO) GetHandle() uint32 {
+	return fbo.handle
+}
+
 // GetColorTexture returns the color texture at the position of the specified index.
 func (fbo *FBO) GetColorTexture(index uint32) *tex.Texture {
 	return fbo.colorTextures[index]
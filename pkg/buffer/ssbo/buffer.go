@@ -0,0 +1,305 @@
+package ssbo
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// storageFlags allow updating a Buffer.
+const storageFlags = gl.DYNAMIC_STORAGE_BIT
+
+// mappableStorageFlags additionally allow persistently mapping a Buffer.
+const mappableStorageFlags = storageFlags | gl.MAP_READ_BIT | gl.MAP_WRITE_BIT | gl.MAP_PERSISTENT_BIT | gl.MAP_COHERENT_BIT
+
+// mapFlags map a range of a Buffer for reading and writing while it is used by shaders.
+const mapFlags = gl.MAP_READ_BIT | gl.MAP_WRITE_BIT | gl.MAP_PERSISTENT_BIT | gl.MAP_COHERENT_BIT
+
+// Buffer is a shader storage buffer of elements of type T, which corresponds to
+// an array of a GLSL type in a std430 buffer block. The memory layout of T is
+// validated on construction, thus elements are copied to and from the GPU as they are.
+// Only buffers constructed with MakeMappableBuffer or MakeMappableBufferFrom can
+// be mapped, since persistently mappable storage can be slower on some drivers.
+type Buffer[T any] struct {
+	handle   uint32
+	layout   Layout
+	mappable bool
+	len      int
+	pos      int32
+	mapped   []T
+	mapStart int
+}
+
+// MakeBuffer constructs a Buffer with len zero initialized elements.
+func MakeBuffer[T any](len int) (Buffer[T], error) {
+	return makeBuffer(make([]T, len), false)
+}
+
+// MakeBufferFrom constructs a Buffer holding a copy of the values.
+func MakeBufferFrom[T any](values []T) (Buffer[T], error) {
+	return makeBuffer(values, false)
+}
+
+// MakeMappableBuffer constructs a Buffer with len zero initialized elements
+// that can be mapped with MapRange. It requires OpenGL 4.4 or ARB_buffer_storage.
+func MakeMappableBuffer[T any](len int) (Buffer[T], error) {
+	return makeBuffer(make([]T, len), true)
+}
+
+// MakeMappableBufferFrom constructs a Buffer holding a copy of the values that
+// can be mapped with MapRange. It requires OpenGL 4.4 or ARB_buffer_storage.
+func MakeMappableBufferFrom[T any](values []T) (Buffer[T], error) {
+	return makeBuffer(values, true)
+}
+
+// makeBuffer validates the layout of T and creates a buffer with the values.
+func makeBuffer[T any](values []T, mappable bool) (Buffer[T], error) {
+	layout, err := LayoutOf[T]()
+	if err != nil {
+		return Buffer[T]{}, err
+	}
+	if mappable && !gl.SupportsBufferStorage() {
+		return Buffer[T]{}, errors.New("mappable buffers require OpenGL 4.4 or GL_ARB_buffer_storage")
+	}
+
+	buffer := Buffer[T]{
+		handle:   0,
+		layout:   layout,
+		mappable: mappable,
+		len:      len(values),
+		pos:      -1,
+	}
+	buffer.handle = buffer.create(len(values), getPtr(values))
+	return buffer, nil
+}
+
+// Delete destroys the Buffer and deletes the buffer data on the GPU.
+func (buffer *Buffer[T]) Delete() {
+	// unbind and unmap if not done yet
+	if buffer.pos != -1 {
+		buffer.Unbind()
+	}
+	buffer.Unmap()
+
+	gl.DeleteBuffers(1, &buffer.handle)
+	buffer.handle = 0
+	buffer.len = 0
+}
+
+// Len returns the number of elements of the Buffer.
+func (buffer *Buffer[T]) Len() int {
+	return buffer.len
+}
+
+// GetHandle returns the handle to the buffer on the GPU.
+func (buffer *Buffer[T]) GetHandle() uint32 {
+	return buffer.handle
+}
+
+// GetLayout returns the std430 layout of one element.
+func (buffer *Buffer[T]) GetLayout() Layout {
+	return buffer.layout
+}
+
+// Bind makes this buffer available at the specified position.
+// The pos attribute has to coincide with the binding of the buffer block within the shader.
+func (buffer *Buffer[T]) Bind(pos int32) {
+	buffer.pos = pos
+	gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, uint32(buffer.pos), buffer.handle)
+}
+
+// Unbind makes this buffer unavailable for reading and writing.
+func (buffer *Buffer[T]) Unbind() {
+	if buffer.pos == -1 {
+		return
+	}
+
+	gl.BindBufferBase(gl.SHADER_STORAGE_BUFFER, uint32(buffer.pos), 0)
+	buffer.pos = -1
+}
+
+// Resize changes the number of elements while preserving the elements that
+// fit into the new size. New elements are zero. A mapped range is unmapped,
+// thus MapRange has to be called again.
+func (buffer *Buffer[T]) Resize(len int) {
+	// early return if size stayed the same
+	if buffer.len == len {
+		return
+	}
+	buffer.Unmap()
+
+	// create new buffer of destination size
+	newhandle := buffer.create(len, getPtr(make([]T, len)))
+
+	// copy data from old to new buffer
+	minlen := buffer.len
+	if len < minlen {
+		minlen = len
+	}
+	if minlen > 0 {
+		gl.BindBuffer(gl.COPY_READ_BUFFER, buffer.handle)
+		gl.BindBuffer(gl.COPY_WRITE_BUFFER, newhandle)
+		gl.CopyBufferSubData(gl.COPY_READ_BUFFER, gl.COPY_WRITE_BUFFER, 0, 0, minlen*buffer.layout.Stride)
+		gl.BindBuffer(gl.COPY_READ_BUFFER, 0)
+		gl.BindBuffer(gl.COPY_WRITE_BUFFER, 0)
+	}
+
+	// replace the old buffer, which also needs to be rebound
+	gl.DeleteBuffers(1, &buffer.handle)
+	buffer.handle = newhandle
+	buffer.len = len
+	if buffer.pos != -1 {
+		buffer.Bind(buffer.pos)
+	}
+}
+
+// Upload replaces the elements of the Buffer with the values.
+// The Buffer is resized to the number of values.
+func (buffer *Buffer[T]) Upload(values []T) {
+	buffer.Resize(len(values))
+	buffer.UploadRange(0, values)
+}
+
+// UploadRange replaces the elements starting at start with the values.
+func (buffer *Buffer[T]) UploadRange(start int, values []T) error {
+	if err := buffer.checkRange(start, len(values)); err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, buffer.handle)
+	gl.BufferSubData(gl.SHADER_STORAGE_BUFFER, start*buffer.layout.Stride, len(values)*buffer.layout.Stride, getPtr(values))
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+	return nil
+}
+
+// Download returns a copy of all elements on the GPU. Writes of shaders
+// are only visible after gl.MemoryBarrier(gl.BUFFER_UPDATE_BARRIER_BIT).
+func (buffer *Buffer[T]) Download() []T {
+	values, _ := buffer.DownloadRange(0, buffer.len)
+	return values
+}
+
+// DownloadRange returns a copy of len elements starting at start.
+func (buffer *Buffer[T]) DownloadRange(start, len int) ([]T, error) {
+	if err := buffer.checkRange(start, len); err != nil {
+		return nil, err
+	}
+	values := make([]T, len)
+	if len == 0 {
+		return values, nil
+	}
+
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, buffer.handle)
+	gl.GetBufferSubData(gl.SHADER_STORAGE_BUFFER, start*buffer.layout.Stride, len*buffer.layout.Stride, getPtr(values))
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+	return values, nil
+}
+
+// MapRange persistently maps len elements starting at start and returns them
+// as a slice, which stays valid while shaders use the Buffer until Unmap, Delete,
+// Resize or Upload with a different length is called. Writes to the slice are
+// visible to subsequent draw calls. Writes of shaders are visible after calling
+// gl.MemoryBarrier(gl.CLIENT_MAPPED_BUFFER_BARRIER_BIT) followed by Sync.
+// A previously mapped range is unmapped. The Buffer has to be constructed with
+// MakeMappableBuffer or MakeMappableBufferFrom.
+func (buffer *Buffer[T]) MapRange(start, len int) ([]T, error) {
+	if !buffer.mappable {
+		return nil, errors.New("cannot map a buffer that wasn't made mappable")
+	}
+	if err := buffer.checkRange(start, len); err != nil {
+		return nil, err
+	}
+	if len == 0 {
+		return nil, errors.New("cannot map an empty range")
+	}
+	buffer.Unmap()
+
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, buffer.handle)
+	ptr := gl.MapBufferRange(gl.SHADER_STORAGE_BUFFER, start*buffer.layout.Stride, len*buffer.layout.Stride, mapFlags)
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+	if ptr == nil {
+		return nil, fmt.Errorf("failed to map elements %v to %v: %v", start, start+len, gl.GetError())
+	}
+
+	buffer.mapped = unsafe.Slice((*T)(ptr), len)
+	buffer.mapStart = start
+	return buffer.mapped, nil
+}
+
+// GetMapped returns the mapped range and the index of its first element.
+// The range is nil if the Buffer isn't mapped.
+func (buffer *Buffer[T]) GetMapped() ([]T, int) {
+	return buffer.mapped, buffer.mapStart
+}
+
+// Unmap releases the mapped range. The slice returned by MapRange must not be used afterwards.
+func (buffer *Buffer[T]) Unmap() {
+	if buffer.mapped == nil {
+		return
+	}
+
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, buffer.handle)
+	gl.UnmapBuffer(gl.SHADER_STORAGE_BUFFER)
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+	buffer.mapped = nil
+	buffer.mapStart = 0
+}
+
+// Sync blocks until the GPU has executed all previously issued commands,
+// thus results of shaders can be read from the mapped range afterwards.
+func (buffer *Buffer[T]) Sync() error {
+	sync := gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	defer gl.DeleteSync(sync)
+
+	// wait without timeout
+	if gl.ClientWaitSync(sync, gl.SYNC_FLUSH_COMMANDS_BIT, ^uint64(0)) == gl.WAIT_FAILED {
+		return fmt.Errorf("failed to wait for the GPU: %v", gl.GetError())
+	}
+	return nil
+}
+
+// create allocates a buffer with space for at least one element and
+// initializes it with data, which may be nil. The storage is immutable if
+// BufferStorage is available, which mappable buffers require.
+func (buffer *Buffer[T]) create(len int, data unsafe.Pointer) uint32 {
+	// buffer must be at least of length 1
+	if len < 1 {
+		len, data = 1, nil
+	}
+
+	var handle uint32
+	gl.GenBuffers(1, &handle)
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, handle)
+	switch {
+	case buffer.mappable:
+		gl.BufferStorage(gl.SHADER_STORAGE_BUFFER, len*buffer.layout.Stride, data, mappableStorageFlags)
+	case gl.SupportsBufferStorage():
+		gl.BufferStorage(gl.SHADER_STORAGE_BUFFER, len*buffer.layout.Stride, data, storageFlags)
+	default:
+		gl.BufferData(gl.SHADER_STORAGE_BUFFER, len*buffer.layout.Stride, data, gl.DYNAMIC_COPY)
+	}
+	gl.BindBuffer(gl.SHADER_STORAGE_BUFFER, 0)
+	return handle
+}
+
+// checkRange returns an error if the range of len elements starting at start
+// exceeds the Buffer.
+func (buffer *Buffer[T]) checkRange(start, len int) error {
+	if start < 0 || len < 0 || start+len > buffer.len {
+		return fmt.Errorf("range %v to %v exceeds buffer of %v elements", start, start+len, buffer.len)
+	}
+	return nil
+}
+
+// getPtr returns a pointer to the first value or nil if there are no values.
+func getPtr[T any](values []T) unsafe.Pointer {
+	if len(values) == 0 {
+		return nil
+	}
+	return unsafe.Pointer(&values[0])
+}
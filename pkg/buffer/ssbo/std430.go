@@ -0,0 +1,153 @@
+package ssbo
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Field is a member of a struct in std430 layout. Nested members are named like "particle.pos".
+type Field struct {
+	Name   string
+	Offset int
+	Size   int
+}
+
+// Layout is the std430 layout of one element of a shader storage buffer.
+// Stride is the distance between two elements of an array of the type.
+type Layout struct {
+	Type   reflect.Type
+	Align  int
+	Size   int
+	Stride int
+	Fields []Field
+}
+
+// types of mgl32 that map to GLSL vectors and matrices
+var (
+	vec2Type = reflect.TypeOf(mgl32.Vec2{})
+	vec3Type = reflect.TypeOf(mgl32.Vec3{})
+	vec4Type = reflect.TypeOf(mgl32.Vec4{})
+	mat3Type = reflect.TypeOf(mgl32.Mat3{})
+	mat4Type = reflect.TypeOf(mgl32.Mat4{})
+)
+
+// MakeLayout calculates the std430 layout of the type and validates that it
+// matches the memory layout of the type in Go, such that values can be copied
+// to the GPU as they are. Supported are float32, float64, int32 and uint32,
+// mgl32.Vec2, Vec3, Vec4 and Mat4 as well as structs and fixed size arrays of
+// them. Padding that std430 requires needs to be added to structs explicitly,
+// e.g. with a field like "_ float32" after a mgl32.Vec3.
+func MakeLayout(t reflect.Type) (Layout, error) {
+	if t == nil {
+		return Layout{}, fmt.Errorf("std430 layout requires a type")
+	}
+
+	layout := Layout{Type: t}
+	align, size, err := layout.addType(t, "", 0)
+	if err != nil {
+		return Layout{}, err
+	}
+	layout.Align = align
+	layout.Size = size
+	layout.Stride = roundUp(size, align)
+
+	if layout.Stride != int(t.Size()) {
+		return Layout{}, fmt.Errorf("%v has %v bytes in Go but a stride of %v bytes in std430, add %v bytes of padding at the end",
+			t, t.Size(), layout.Stride, layout.Stride-int(t.Size()))
+	}
+	return layout, nil
+}
+
+// LayoutOf calculates and validates the std430 layout of T as described in MakeLayout.
+func LayoutOf[T any]() (Layout, error) {
+	var value T
+	return MakeLayout(reflect.TypeOf(value))
+}
+
+// addType validates the type at the offset and returns its std430 base alignment and size.
+func (layout *Layout) addType(t reflect.Type, name string, offset int) (int, int, error) {
+	switch t {
+	case vec2Type:
+		return 8, 8, nil
+	case vec3Type:
+		return 16, 12, nil
+	case vec4Type:
+		return 16, 16, nil
+	case mat4Type:
+		return 16, 64, nil
+	case mat3Type:
+		return 0, 0, fmt.Errorf("%v has 36 bytes in Go but 48 bytes in std430, use mgl32.Mat4 or [3]mgl32.Vec4", describe(name, t))
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Int32, reflect.Uint32:
+		return 4, 4, nil
+	case reflect.Float64:
+		return 8, 8, nil
+	case reflect.Array:
+		align, size, err := layout.addType(t.Elem(), name+"[0]", offset)
+		if err != nil {
+			return 0, 0, err
+		}
+		stride := roundUp(size, align)
+		if stride != int(t.Elem().Size()) {
+			return 0, 0, fmt.Errorf("%v has elements of %v bytes in Go but a stride of %v bytes in std430",
+				describe(name, t), t.Elem().Size(), stride)
+		}
+		return align, stride * t.Len(), nil
+	case reflect.Struct:
+		return layout.addStruct(t, name, offset)
+	}
+	return 0, 0, fmt.Errorf("%v is not supported by std430", describe(name, t))
+}
+
+// addStruct validates the offsets of all members of the struct and returns
+// the alignment of the struct, which is the largest alignment of its members,
+// and its size.
+func (layout *Layout) addStruct(t reflect.Type, name string, offset int) (int, int, error) {
+	end, align := 0, 4
+	for i := 0; i < t.NumField(); i++ {
+		member := t.Field(i)
+		memberName := member.Name
+		if name != "" {
+			memberName = name + "." + member.Name
+		}
+
+		// nested members are added after the member itself
+		idx := len(layout.Fields)
+		memberAlign, memberSize, err := layout.addType(member.Type, memberName, offset+int(member.Offset))
+		if err != nil {
+			return 0, 0, err
+		}
+		expected := roundUp(end, memberAlign)
+		if int(member.Offset) != expected {
+			return 0, 0, fmt.Errorf("%v has offset %v in Go but %v in std430, add %v bytes of padding before it",
+				describe(memberName, member.Type), member.Offset, expected, expected-int(member.Offset))
+		}
+		if member.Name != "_" {
+			field := Field{memberName, offset + expected, memberSize}
+			layout.Fields = append(layout.Fields[:idx], append([]Field{field}, layout.Fields[idx:]...)...)
+		}
+
+		end = expected + memberSize
+		if memberAlign > align {
+			align = memberAlign
+		}
+	}
+	return align, roundUp(end, align), nil
+}
+
+// describe names the member of type t for error messages.
+func describe(name string, t reflect.Type) string {
+	if name == "" {
+		return fmt.Sprint(t)
+	}
+	return fmt.Sprintf("member %v of type %v", name, t)
+}
+
+// roundUp rounds x up to the next multiple of align.
+func roundUp(x, align int) int {
+	return (x + align - 1) / align * align
+}
@@ -0,0 +1,129 @@
+package ssbo
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type particle struct {
+	Pos      mgl32.Vec3
+	Mass     float32
+	Velocity mgl32.Vec3
+	_        float32
+}
+
+type emitter struct {
+	Model     mgl32.Mat4
+	Particles [2]particle
+	Weights   [3]float32
+	Count     uint32
+	Time      float64
+	Offset    mgl32.Vec2
+	Seed      int32
+	_         [3]float32
+}
+
+func TestLayoutOffsets(t *testing.T) {
+	layout, err := LayoutOf[emitter]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Align != 16 || layout.Size != 176 || layout.Stride != 176 {
+		t.Errorf("align %v, size %v and stride %v instead of 16, 176 and 176", layout.Align, layout.Size, layout.Stride)
+	}
+
+	// nested members follow their parent and padding isn't listed
+	expected := []Field{
+		{"Model", 0, 64},
+		{"Particles", 64, 64},
+		{"Particles[0].Pos", 64, 12},
+		{"Particles[0].Mass", 76, 4},
+		{"Particles[0].Velocity", 80, 12},
+		// scalar arrays aren't padded in std430
+		{"Weights", 128, 12},
+		{"Count", 140, 4},
+		{"Time", 144, 8},
+		{"Offset", 152, 8},
+		{"Seed", 160, 4},
+	}
+	if !reflect.DeepEqual(layout.Fields, expected) {
+		t.Errorf("fields are %v instead of %v", layout.Fields, expected)
+	}
+}
+
+func TestLayoutScalars(t *testing.T) {
+	tests := []struct {
+		value  interface{}
+		align  int
+		stride int
+	}{
+		{float32(0), 4, 4},
+		{int32(0), 4, 4},
+		{uint32(0), 4, 4},
+		{float64(0), 8, 8},
+		{mgl32.Vec2{}, 8, 8},
+		{mgl32.Vec4{}, 16, 16},
+		{mgl32.Mat4{}, 16, 64},
+		{[4]float32{}, 4, 16},
+		{[2]mgl32.Vec4{}, 16, 32},
+		{struct{ A, B float32 }{}, 4, 8},
+		{struct {
+			A mgl32.Vec3
+			B float32
+		}{}, 16, 16},
+	}
+	for _, test := range tests {
+		layout, err := MakeLayout(reflect.TypeOf(test.value))
+		if err != nil {
+			t.Errorf("%T: %v", test.value, err)
+			continue
+		}
+		if layout.Align != test.align || layout.Stride != test.stride {
+			t.Errorf("%T: align %v and stride %v instead of %v and %v", test.value, layout.Align, layout.Stride, test.align, test.stride)
+		}
+	}
+}
+
+func TestLayoutInvalid(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		err   string
+	}{
+		// a vec3 alone has a stride of 16 bytes but 12 bytes in Go
+		{mgl32.Vec3{}, "add 4 bytes of padding at the end"},
+		{struct{ M mgl32.Mat3 }{}, "use mgl32.Mat4"},
+		{[2]mgl32.Vec3{}, "stride of 16 bytes"},
+		{struct {
+			A float32
+			B mgl32.Vec4
+		}{}, "add 12 bytes of padding before it"},
+		{struct {
+			A mgl32.Vec3
+			B mgl32.Vec3
+			_ [2]float32
+		}{}, "member B of type mgl32.Vec3 has offset 12"},
+		{struct{ B bool }{}, "not supported by std430"},
+		{struct{ S []float32 }{}, "not supported by std430"},
+		{struct {
+			A mgl32.Vec4
+			B float32
+		}{}, "add 12 bytes of padding at the end"},
+	}
+	for _, test := range tests {
+		_, err := MakeLayout(reflect.TypeOf(test.value))
+		if err == nil {
+			t.Errorf("%T: expected an error", test.value)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%T: error %q doesn't contain %q", test.value, err, test.err)
+		}
+	}
+
+	if _, err := MakeLayout(nil); err == nil {
+		t.Error("nil type: expected an error")
+	}
+}
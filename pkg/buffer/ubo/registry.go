@@ -0,0 +1,77 @@
+package ubo
+
+import (
+	"github.com/adrianderstroff/realtime-clouds/pkg/core/shader"
+)
+
+// Registry assigns each uniform block name its own binding point, such that
+// a UBO can be shared by all shaders that declare a block of that name.
+type Registry struct {
+	bindings map[string]uint32
+	buffers  map[string]*UBO
+}
+
+// MakeRegistry constructs an empty Registry.
+func MakeRegistry() Registry {
+	return Registry{
+		bindings: make(map[string]uint32),
+		buffers:  make(map[string]*UBO),
+	}
+}
+
+// Register binds the UBO to the binding point of the uniform block with the
+// specified name and returns the binding point. A new binding point is assigned
+// to names that haven't been registered yet.
+func (registry *Registry) Register(blockName string, ubo *UBO) uint32 {
+	binding, ok := registry.bindings[blockName]
+	if !ok {
+		binding = uint32(len(registry.bindings))
+		registry.bindings[blockName] = binding
+	}
+
+	// replace a previously registered buffer
+	if previous, ok := registry.buffers[blockName]; ok && previous != ubo {
+		previous.Unbind()
+	}
+	registry.buffers[blockName] = ubo
+	ubo.Bind(int32(binding))
+
+	return binding
+}
+
+// GetBinding returns the binding point of the uniform block with the specified name.
+func (registry *Registry) GetBinding(blockName string) (uint32, bool) {
+	binding, ok := registry.bindings[blockName]
+	return binding, ok
+}
+
+// GetBuffer returns the UBO that is registered for the uniform block with the specified name.
+func (registry *Registry) GetBuffer(blockName string) (*UBO, bool) {
+	ubo, ok := registry.buffers[blockName]
+	return ubo, ok
+}
+
+// Connect binds each active uniform block of the shader to the binding point
+// of the registered block of the same name. Blocks that aren't registered
+// are left untouched.
+func (registry *Registry) Connect(s *shader.Shader) {
+	for _, block := range s.GetUniformBlocks() {
+		if binding, ok := registry.bindings[block.Name]; ok {
+			s.BindUniformBlock(block.Name, binding)
+		}
+	}
+}
+
+// defaultRegistry is shared by all passes of an application.
+var defaultRegistry = MakeRegistry()
+
+// Register registers the UBO for the uniform block with the specified name
+// in the default Registry and returns its binding point.
+func Register(blockName string, ubo *UBO) uint32 {
+	return defaultRegistry.Register(blockName, ubo)
+}
+
+// Connect binds the uniform blocks of the shader to the binding points of the default Registry.
+func Connect(s *shader.Shader) {
+	defaultRegistry.Connect(s)
+}
@@ -0,0 +1,297 @@
+package ubo
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Field is a member of a uniform block at a byte offset of the buffer.
+// Nested members are named like "camera.pos" and array elements like "lights[1]".
+type Field struct {
+	Name   string
+	Offset int
+	Size   int
+	Type   reflect.Type
+}
+
+// Layout is the std140 layout of a Go struct. Members of the struct are named
+// by their std140 tag or otherwise by their field name. Fields with the tag "-"
+// and unexported fields are skipped.
+//
+// Supported are float32, int32, uint32 and bool as well as mgl32.Vec2, Vec3,
+// Vec4, Mat3 and Mat4, structs of these and fixed size arrays of all of them.
+type Layout struct {
+	Type   reflect.Type
+	Size   int
+	Fields []Field
+	fields map[string]int
+}
+
+// types of mgl32 that map to GLSL vectors and matrices
+var (
+	vec2Type = reflect.TypeOf(mgl32.Vec2{})
+	vec3Type = reflect.TypeOf(mgl32.Vec3{})
+	vec4Type = reflect.TypeOf(mgl32.Vec4{})
+	mat3Type = reflect.TypeOf(mgl32.Mat3{})
+	mat4Type = reflect.TypeOf(mgl32.Mat4{})
+)
+
+// MakeLayout calculates the std140 layout of the struct value or pointer to struct.
+func MakeLayout(value interface{}) (Layout, error) {
+	t := reflect.TypeOf(value)
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct || isGLSLType(t) {
+		return Layout{}, fmt.Errorf("std140 layout requires a struct, got %v", t)
+	}
+
+	layout := Layout{
+		Type:   t,
+		fields: make(map[string]int),
+	}
+	_, size, err := layout.addFields(t, "", 0)
+	if err != nil {
+		return Layout{}, err
+	}
+	layout.Size = size
+	return layout, nil
+}
+
+// GetField returns the member with the specified name.
+func (layout *Layout) GetField(name string) (Field, bool) {
+	idx, ok := layout.fields[name]
+	if !ok {
+		return Field{}, false
+	}
+	return layout.Fields[idx], true
+}
+
+// Pack writes the value in std140 layout into data, which has to be at least Size bytes long.
+// The value can be a struct or a pointer to a struct, but not a nil pointer.
+func (layout *Layout) Pack(value interface{}, data []byte) error {
+	v, err := indirect(value)
+	if err != nil {
+		return err
+	}
+	if v.Type() != layout.Type {
+		return fmt.Errorf("cannot pack %v with layout of %v", v.Type(), layout.Type)
+	}
+	if len(data) < layout.Size {
+		return fmt.Errorf("buffer of %v bytes is too small for %v bytes", len(data), layout.Size)
+	}
+	pack(v, data)
+	return nil
+}
+
+// PackField writes the value of the member with the specified name into data,
+// which contains the whole block. The value needs to have the type of the member.
+func (layout *Layout) PackField(name string, value interface{}, data []byte) (Field, error) {
+	field, ok := layout.GetField(name)
+	if !ok {
+		return Field{}, fmt.Errorf("%v has no member %v", layout.Type, name)
+	}
+	v, err := indirect(value)
+	if err != nil {
+		return Field{}, fmt.Errorf("member %v: %v", name, err)
+	}
+	if v.Type() != field.Type {
+		return Field{}, fmt.Errorf("member %v has type %v, cannot set it to %v", name, field.Type, v.Type())
+	}
+	if len(data) < field.Offset+field.Size {
+		return Field{}, fmt.Errorf("buffer of %v bytes is too small for member %v ending at %v bytes", len(data), name, field.Offset+field.Size)
+	}
+	pack(v, data[field.Offset:])
+	return field, nil
+}
+
+// indirect returns the value or the value it points to. An error is returned
+// for nil and nil pointers.
+func indirect(value interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(value)
+	if !v.IsValid() {
+		return reflect.Value{}, fmt.Errorf("cannot pack nil")
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, fmt.Errorf("cannot pack nil %v", v.Type())
+		}
+		v = v.Elem()
+	}
+	return v, nil
+}
+
+// addFields adds the members of the struct at the specified offset and returns
+// the alignment and the size of the struct.
+func (layout *Layout) addFields(t reflect.Type, prefix string, offset int) (int, int, error) {
+	offsets, align, size, err := getStructLayout(t)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, idx := range getMembers(t) {
+		member := t.Field(idx)
+		name := prefix + getMemberName(member)
+		if err := layout.addField(member.Type, name, offset+offsets[i]); err != nil {
+			return 0, 0, err
+		}
+	}
+	return align, size, nil
+}
+
+// addField adds a member and all of its nested members.
+func (layout *Layout) addField(t reflect.Type, name string, offset int) error {
+	_, size, err := getTypeLayout(t)
+	if err != nil {
+		return fmt.Errorf("member %v: %v", name, err)
+	}
+	layout.fields[name] = len(layout.Fields)
+	layout.Fields = append(layout.Fields, Field{Name: name, Offset: offset, Size: size, Type: t})
+
+	switch {
+	case isGLSLType(t):
+		return nil
+	case t.Kind() == reflect.Struct:
+		_, _, err := layout.addFields(t, name+".", offset)
+		return err
+	case t.Kind() == reflect.Array:
+		stride, _ := getArrayStride(t)
+		for i := 0; i < t.Len(); i++ {
+			if err := layout.addField(t.Elem(), fmt.Sprintf("%v[%v]", name, i), offset+i*stride); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// getTypeLayout returns the std140 base alignment and size of the type.
+func getTypeLayout(t reflect.Type) (int, int, error) {
+	switch t {
+	case vec2Type:
+		return 8, 8, nil
+	case vec3Type:
+		return 16, 12, nil
+	case vec4Type:
+		return 16, 16, nil
+	case mat3Type:
+		return 16, 48, nil
+	case mat4Type:
+		return 16, 64, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Float32, reflect.Int32, reflect.Uint32, reflect.Bool:
+		return 4, 4, nil
+	case reflect.Array:
+		stride, err := getArrayStride(t)
+		return 16, stride * t.Len(), err
+	case reflect.Struct:
+		_, align, size, err := getStructLayout(t)
+		return align, size, err
+	}
+	return 0, 0, fmt.Errorf("type %v is not supported by std140", t)
+}
+
+// getArrayStride returns the distance between two elements of the array,
+// which is rounded up to a multiple of a vec4.
+func getArrayStride(t reflect.Type) (int, error) {
+	_, size, err := getTypeLayout(t.Elem())
+	return roundUp(size, 16), err
+}
+
+// getStructLayout returns the offsets of the members, the alignment and the
+// size of the struct. Both are rounded up to a multiple of a vec4.
+func getStructLayout(t reflect.Type) ([]int, int, int, error) {
+	var offsets []int
+	offset, align := 0, 16
+	for _, idx := range getMembers(t) {
+		member := t.Field(idx)
+		memberAlign, memberSize, err := getTypeLayout(member.Type)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("member %v: %v", member.Name, err)
+		}
+		offset = roundUp(offset, memberAlign)
+		offsets = append(offsets, offset)
+		offset += memberSize
+	}
+	return offsets, align, roundUp(offset, align), nil
+}
+
+// getMembers returns the indices of the fields of the struct that are part of the block.
+func getMembers(t reflect.Type) []int {
+	var members []int
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("std140") == "-" {
+			continue
+		}
+		members = append(members, i)
+	}
+	return members
+}
+
+// getMemberName returns the std140 tag of the field or its name.
+func getMemberName(field reflect.StructField) string {
+	if name := strings.TrimSpace(field.Tag.Get("std140")); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// isGLSLType returns true for types that map to GLSL vectors and matrices.
+func isGLSLType(t reflect.Type) bool {
+	return t == vec2Type || t == vec3Type || t == vec4Type || t == mat3Type || t == mat4Type
+}
+
+// pack writes the value in std140 layout to the start of data.
+func pack(v reflect.Value, data []byte) {
+	switch v.Type() {
+	case vec2Type, vec3Type, vec4Type, mat4Type:
+		for i := 0; i < v.Len(); i++ {
+			binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(float32(v.Index(i).Float())))
+		}
+		return
+	case mat3Type:
+		// each column is padded to a vec4
+		for i := 0; i < v.Len(); i++ {
+			offset := (i/3)*16 + (i%3)*4
+			binary.LittleEndian.PutUint32(data[offset:], math.Float32bits(float32(v.Index(i).Float())))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Float32:
+		binary.LittleEndian.PutUint32(data, math.Float32bits(float32(v.Float())))
+	case reflect.Int32:
+		binary.LittleEndian.PutUint32(data, uint32(int32(v.Int())))
+	case reflect.Uint32:
+		binary.LittleEndian.PutUint32(data, uint32(v.Uint()))
+	case reflect.Bool:
+		var b uint32
+		if v.Bool() {
+			b = 1
+		}
+		binary.LittleEndian.PutUint32(data, b)
+	case reflect.Array:
+		stride, _ := getArrayStride(v.Type())
+		for i := 0; i < v.Len(); i++ {
+			pack(v.Index(i), data[i*stride:])
+		}
+	case reflect.Struct:
+		offsets, _, _, _ := getStructLayout(v.Type())
+		for i, idx := range getMembers(v.Type()) {
+			pack(v.Field(idx), data[offsets[i]:])
+		}
+	}
+}
+
+// roundUp rounds x up to the next multiple of align.
+func roundUp(x, align int) int {
+	return (x + align - 1) / align * align
+}
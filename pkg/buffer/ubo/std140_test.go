@@ -0,0 +1,278 @@
+package ubo
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+type light struct {
+	Color     mgl32.Vec3
+	Intensity float32
+}
+
+type material struct {
+	Albedo    mgl32.Vec2
+	Roughness float32
+}
+
+// block covers all supported types, padding rules and tags.
+type block struct {
+	Pos      mgl32.Vec3 `std140:"pos"`
+	Scale    float32
+	Normal   mgl32.Mat3
+	Weights  [3]float32
+	Dirs     [2]mgl32.Vec3
+	Lights   [2]light
+	Material material
+	Count    int32
+	Mask     uint32
+	Enabled  bool
+	Ignored  float32 `std140:"-"`
+	hidden   float32
+	Proj     mgl32.Mat4
+}
+
+// expectedFields are the std140 offsets and sizes of the members of block.
+var expectedFields = []Field{
+	{Name: "pos", Offset: 0, Size: 12},
+	{Name: "Scale", Offset: 12, Size: 4},
+	// each column of a mat3 is padded to a vec4
+	{Name: "Normal", Offset: 16, Size: 48},
+	// the elements of arrays are padded to a vec4
+	{Name: "Weights", Offset: 64, Size: 48},
+	{Name: "Weights[0]", Offset: 64, Size: 4},
+	{Name: "Weights[2]", Offset: 96, Size: 4},
+	{Name: "Dirs", Offset: 112, Size: 32},
+	{Name: "Dirs[1]", Offset: 128, Size: 12},
+	{Name: "Lights", Offset: 144, Size: 32},
+	{Name: "Lights[0].Color", Offset: 144, Size: 12},
+	{Name: "Lights[0].Intensity", Offset: 156, Size: 4},
+	{Name: "Lights[1].Color", Offset: 160, Size: 12},
+	{Name: "Lights[1].Intensity", Offset: 172, Size: 4},
+	// structs are aligned to a vec4 and their size is rounded up to a vec4
+	{Name: "Material", Offset: 176, Size: 16},
+	{Name: "Material.Albedo", Offset: 176, Size: 8},
+	{Name: "Material.Roughness", Offset: 184, Size: 4},
+	{Name: "Count", Offset: 192, Size: 4},
+	{Name: "Mask", Offset: 196, Size: 4},
+	{Name: "Enabled", Offset: 200, Size: 4},
+	{Name: "Proj", Offset: 208, Size: 64},
+}
+
+// readFloat returns the float32 at the byte offset of data.
+func readFloat(data []byte, offset int) float32 {
+	return math.Float32frombits(binary.LittleEndian.Uint32(data[offset:]))
+}
+
+// checkFloats fails if the floats starting at offset differ from expected.
+func checkFloats(t *testing.T, name string, data []byte, offset int, expected ...float32) {
+	for i, val := range expected {
+		if actual := readFloat(data, offset+i*4); actual != val {
+			t.Errorf("%v: float %v at offset %v is %v instead of %v", name, i, offset+i*4, actual, val)
+		}
+	}
+}
+
+func TestLayoutOffsets(t *testing.T) {
+	layout, err := MakeLayout(block{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Size != 272 {
+		t.Errorf("size is %v instead of 272", layout.Size)
+	}
+	for _, expected := range expectedFields {
+		field, ok := layout.GetField(expected.Name)
+		if !ok {
+			t.Errorf("member %v is missing", expected.Name)
+			continue
+		}
+		if field.Offset != expected.Offset || field.Size != expected.Size {
+			t.Errorf("member %v has offset %v and size %v instead of %v and %v",
+				expected.Name, field.Offset, field.Size, expected.Offset, expected.Size)
+		}
+	}
+	for _, name := range []string{"Pos", "Ignored", "hidden"} {
+		if _, ok := layout.GetField(name); ok {
+			t.Errorf("member %v shouldn't be part of the layout", name)
+		}
+	}
+
+	// a pointer results in the same layout
+	pointerLayout, err := MakeLayout(&block{})
+	if err != nil || pointerLayout.Size != layout.Size || len(pointerLayout.Fields) != len(layout.Fields) {
+		t.Errorf("layout of a pointer differs: %v", err)
+	}
+}
+
+func TestLayoutUnsupported(t *testing.T) {
+	values := []interface{}{
+		nil,
+		3,
+		mgl32.Vec3{},
+		struct{ A float64 }{},
+		struct{ A []float32 }{},
+		struct{ A struct{ B string } }{},
+	}
+	for _, value := range values {
+		if _, err := MakeLayout(value); err == nil {
+			t.Errorf("layout of %T: expected an error", value)
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	layout, err := MakeLayout(block{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := block{
+		Pos:     mgl32.Vec3{1, 2, 3},
+		Scale:   4,
+		Normal:  mgl32.Mat3{5, 6, 7, 8, 9, 10, 11, 12, 13},
+		Weights: [3]float32{14, 15, 16},
+		Dirs:    [2]mgl32.Vec3{{17, 18, 19}, {20, 21, 22}},
+		Lights:  [2]light{{mgl32.Vec3{23, 24, 25}, 26}, {mgl32.Vec3{27, 28, 29}, 30}},
+		Material: material{
+			Albedo:    mgl32.Vec2{31, 32},
+			Roughness: 33,
+		},
+		Count:   -2,
+		Mask:    0xdeadbeef,
+		Enabled: true,
+		Ignored: 99,
+		Proj:    mgl32.Ident4(),
+	}
+
+	// padding has to stay untouched
+	data := make([]byte, layout.Size)
+	for i := range data {
+		data[i] = 0xff
+	}
+	if err := layout.Pack(&value, data); err != nil {
+		t.Fatal(err)
+	}
+
+	checkFloats(t, "pos and scale", data, 0, 1, 2, 3, 4)
+	checkFloats(t, "normal column 0", data, 16, 5, 6, 7)
+	checkFloats(t, "normal column 1", data, 32, 8, 9, 10)
+	checkFloats(t, "normal column 2", data, 48, 11, 12, 13)
+	for i, offset := range []int{64, 80, 96} {
+		checkFloats(t, "weights", data, offset, 14+float32(i))
+	}
+	checkFloats(t, "dirs", data, 112, 17, 18, 19)
+	checkFloats(t, "dirs", data, 128, 20, 21, 22)
+	checkFloats(t, "lights", data, 144, 23, 24, 25, 26, 27, 28, 29, 30)
+	checkFloats(t, "material", data, 176, 31, 32, 33)
+	checkFloats(t, "proj", data, 208, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1, 0, 0, 0, 0, 1)
+	if count := int32(binary.LittleEndian.Uint32(data[192:])); count != -2 {
+		t.Errorf("count is %v instead of -2", count)
+	}
+	if mask := binary.LittleEndian.Uint32(data[196:]); mask != 0xdeadbeef {
+		t.Errorf("mask is %x instead of deadbeef", mask)
+	}
+	if enabled := binary.LittleEndian.Uint32(data[200:]); enabled != 1 {
+		t.Errorf("enabled is %v instead of 1", enabled)
+	}
+
+	// padding after vec3s in arrays, mat3 columns and scalars in arrays
+	for _, offset := range []int{28, 44, 60, 68, 124, 140, 188, 204} {
+		if binary.LittleEndian.Uint32(data[offset:]) != 0xffffffff {
+			t.Errorf("padding at offset %v has been overwritten", offset)
+		}
+	}
+
+	// packing the value instead of a pointer results in the same data
+	copied := make([]byte, layout.Size)
+	for i := range copied {
+		copied[i] = 0xff
+	}
+	if err := layout.Pack(value, copied); err != nil {
+		t.Fatal(err)
+	}
+	if string(copied) != string(data) {
+		t.Error("packing a value differs from packing a pointer")
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	layout, err := MakeLayout(block{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, layout.Size)
+
+	var nilBlock *block
+	if err := layout.Pack(nilBlock, data); err == nil {
+		t.Error("packing a nil pointer: expected an error")
+	}
+	if err := layout.Pack(nil, data); err == nil {
+		t.Error("packing nil: expected an error")
+	}
+	if err := layout.Pack(light{}, data); err == nil {
+		t.Error("packing a different type: expected an error")
+	}
+	if err := layout.Pack(block{}, data[:layout.Size-1]); err == nil {
+		t.Error("packing into a too small buffer: expected an error")
+	}
+
+	var nilVec *mgl32.Vec3
+	if _, err := layout.PackField("pos", nilVec, data); err == nil {
+		t.Error("packing a nil pointer into a member: expected an error")
+	}
+	if _, err := layout.PackField("pos", nil, data); err == nil {
+		t.Error("packing nil into a member: expected an error")
+	}
+	if _, err := layout.PackField("pos", float32(1), data); err == nil {
+		t.Error("packing a different type into a member: expected an error")
+	}
+	if _, err := layout.PackField("Missing", float32(1), data); err == nil {
+		t.Error("packing a missing member: expected an error")
+	}
+	if _, err := layout.PackField("Proj", mgl32.Mat4{}, data[:270]); err == nil {
+		t.Error("packing a member into a too small buffer: expected an error")
+	}
+}
+
+func TestPackField(t *testing.T) {
+	layout, err := MakeLayout(block{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, layout.Size)
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		offset int
+		floats []float32
+	}{
+		{"Scale", float32(2), 12, []float32{2}},
+		{"Normal", mgl32.Mat3{1, 2, 3, 4, 5, 6, 7, 8, 9}, 16, []float32{1, 2, 3, 0, 4, 5, 6, 0, 7, 8, 9}},
+		{"Weights[1]", float32(3), 80, []float32{3}},
+		{"Dirs[1]", &mgl32.Vec3{4, 5, 6}, 128, []float32{4, 5, 6}},
+		{"Lights[1]", light{mgl32.Vec3{7, 8, 9}, 10}, 160, []float32{7, 8, 9, 10}},
+		{"Lights[0].Intensity", float32(11), 156, []float32{11}},
+		{"Material.Roughness", float32(12), 184, []float32{12}},
+	}
+	for _, test := range tests {
+		field, err := layout.PackField(test.name, test.value, data)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if field.Offset != test.offset {
+			t.Errorf("%v: offset is %v instead of %v", test.name, field.Offset, test.offset)
+		}
+		checkFloats(t, test.name, data, test.offset, test.floats...)
+	}
+
+	// only the bytes of the members have been written
+	if readFloat(data, 0) != 0 || readFloat(data, 64) != 0 || readFloat(data, 144) != 0 {
+		t.Error("PackField wrote outside of the member")
+	}
+}
@@ -0,0 +1,110 @@
+// Package ubo contains a uniform buffer whose content is packed from a Go struct
+// in std140 layout, which allows to share uniform blocks between shaders.
+package ubo
+
+import (
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// UBO is a uniform buffer holding a Go struct in std140 layout.
+type UBO struct {
+	handle uint32
+	layout Layout
+	data   []byte
+	pos    int32
+}
+
+// Make constructs a UBO with the layout of the struct value and uploads the value.
+func Make(value interface{}) (UBO, error) {
+	layout, err := MakeLayout(value)
+	if err != nil {
+		return UBO{}, err
+	}
+
+	ubo := UBO{
+		handle: 0,
+		layout: layout,
+		data:   make([]byte, layout.Size),
+		pos:    -1,
+	}
+	if err := layout.Pack(value, ubo.data); err != nil {
+		return UBO{}, err
+	}
+
+	gl.GenBuffers(1, &ubo.handle)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo.handle)
+	gl.BufferData(gl.UNIFORM_BUFFER, len(ubo.data), gl.Ptr(ubo.data), gl.DYNAMIC_DRAW)
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+
+	return ubo, nil
+}
+
+// Delete destroys the UBO and deletes the buffer data on the GPU.
+func (ubo *UBO) Delete() {
+	// unbind if not done yet
+	if ubo.pos != -1 {
+		ubo.Unbind()
+	}
+
+	gl.DeleteBuffers(1, &ubo.handle)
+	ubo.handle = 0
+	ubo.data = nil
+}
+
+// GetHandle returns the handle to the buffer on the GPU.
+func (ubo *UBO) GetHandle() uint32 {
+	return ubo.handle
+}
+
+// GetLayout returns the std140 layout of the buffer.
+func (ubo *UBO) GetLayout() *Layout {
+	return &ubo.layout
+}
+
+// GetSize returns the size of the buffer in bytes.
+func (ubo *UBO) GetSize() int {
+	return len(ubo.data)
+}
+
+// Bind makes this buffer available at the specified uniform buffer binding point.
+func (ubo *UBO) Bind(pos int32) {
+	ubo.pos = pos
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, uint32(ubo.pos), ubo.handle)
+}
+
+// Unbind removes this buffer from its binding point.
+func (ubo *UBO) Unbind() {
+	if ubo.pos == -1 {
+		return
+	}
+
+	gl.BindBufferBase(gl.UNIFORM_BUFFER, uint32(ubo.pos), 0)
+	ubo.pos = -1
+}
+
+// Upload replaces the whole buffer with the value, which needs to be of the
+// same struct type the UBO had been constructed with.
+func (ubo *UBO) Upload(value interface{}) error {
+	if err := ubo.layout.Pack(value, ubo.data); err != nil {
+		return err
+	}
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo.handle)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, 0, len(ubo.data), gl.Ptr(ubo.data))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	return nil
+}
+
+// UploadField replaces only the member with the specified name, e.g. "pos",
+// "camera.pos" or "lights[2]". The value needs to have the type of the member.
+func (ubo *UBO) UploadField(name string, value interface{}) error {
+	field, err := ubo.layout.PackField(name, value, ubo.data)
+	if err != nil {
+		return err
+	}
+
+	gl.BindBuffer(gl.UNIFORM_BUFFER, ubo.handle)
+	gl.BufferSubData(gl.UNIFORM_BUFFER, field.Offset, field.Size, gl.Ptr(ubo.data[field.Offset:]))
+	gl.BindBuffer(gl.UNIFORM_BUFFER, 0)
+	return nil
+}
@@ -70,7 +70,7 @@ func Disable(val uint32) {
 func GetError() error {
 	err := ogl.GetError()
 	if err != NO_ERROR {
-		errorType := string(err)
+		errorType := ""
 		switch err {
 		case INVALID_ENUM:
 			errorType = "INVALID ENUM"
@@ -97,6 +97,33 @@ func GetError() error {
 	}
 }
 
+// IsVersion returns true if the version of the OpenGL context is at least major.minor.
+func IsVersion(major, minor int) bool {
+	var contextMajor, contextMinor int32
+	ogl.GetIntegerv(ogl.MAJOR_VERSION, &contextMajor)
+	ogl.GetIntegerv(ogl.MINOR_VERSION, &contextMinor)
+	return int(contextMajor) > major || int(contextMajor) == major && int(contextMinor) >= minor
+}
+
+// HasExtension returns true if the OpenGL context supports the extension with
+// the specified name, e.g. "GL_ARB_buffer_storage".
+func HasExtension(name string) bool {
+	var count int32
+	ogl.GetIntegerv(ogl.NUM_EXTENSIONS, &count)
+	for i := int32(0); i < count; i++ {
+		if ogl.GoStr(ogl.GetStringi(ogl.EXTENSIONS, uint32(i))) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsBufferStorage returns true if BufferStorage can be used, which is
+// core since OpenGL 4.4 and otherwise requires ARB_buffer_storage.
+func SupportsBufferStorage() bool {
+	return IsVersion(4, 4) || HasExtension("GL_ARB_buffer_storage")
+}
+
 // Functions adapted from go-gl
 var (
 	Ptr                     = ogl.Ptr
@@ -153,7 +180,13 @@ var (
 	CopyBufferSubData       = ogl.CopyBufferSubData
 	BufferSubData           = ogl.BufferSubData
 	MapBuffer               = ogl.MapBuffer
+	MapBufferRange          = ogl.MapBufferRange
 	UnmapBuffer             = ogl.UnmapBuffer
+	BufferStorage           = ogl.BufferStorage
+	GetBufferSubData        = ogl.GetBufferSubData
+	FenceSync               = ogl.FenceSync
+	ClientWaitSync          = ogl.ClientWaitSync
+	DeleteSync              = ogl.DeleteSync
 	CreateProgram           = ogl.CreateProgram
 	CreateShader            = ogl.CreateShader
 	ShaderSource            = ogl.ShaderSource
@@ -170,12 +203,25 @@ var (
 	DispatchCompute         = ogl.DispatchCompute
 	GetUniformLocation      = ogl.GetUniformLocation
 	Uniform1i               = ogl.Uniform1i
+	Uniform1iv              = ogl.Uniform1iv
+	Uniform2iv              = ogl.Uniform2iv
+	Uniform3iv              = ogl.Uniform3iv
+	Uniform4iv              = ogl.Uniform4iv
+	Uniform1ui              = ogl.Uniform1ui
 	Uniform1f               = ogl.Uniform1f
+	Uniform1fv              = ogl.Uniform1fv
 	Uniform2fv              = ogl.Uniform2fv
 	Uniform3fv              = ogl.Uniform3fv
+	Uniform4fv              = ogl.Uniform4fv
+	UniformMatrix3fv        = ogl.UniformMatrix3fv
 	UniformMatrix4fv        = ogl.UniformMatrix4fv
+	UniformBlockBinding     = ogl.UniformBlockBinding
 	GetShaderiv             = ogl.GetShaderiv
 	ReadPixels              = ogl.ReadPixels
+	PixelStorei             = ogl.PixelStorei
+	GetTexImage             = ogl.GetTexImage
+	GetTexLevelParameteriv  = ogl.GetTexLevelParameteriv
+	GetIntegerv             = ogl.GetIntegerv
 	MemoryBarrier           = ogl.MemoryBarrier
 )
 
@@ -278,6 +324,8 @@ const (
 	MAX_3D_TEXTURE_SIZE              = ogl.MAX_3D_TEXTURE_SIZE
 	TEXTURE_BASE_LEVEL               = ogl.TEXTURE_BASE_LEVEL
 	TEXTURE_MAX_LEVEL                = ogl.TEXTURE_MAX_LEVEL
+	UNPACK_ALIGNMENT                 = ogl.UNPACK_ALIGNMENT
+	PACK_ALIGNMENT                   = ogl.PACK_ALIGNMENT
 	MAX_COMBINED_TEXTURE_IMAGE_UNITS = ogl.MAX_COMBINED_TEXTURE_IMAGE_UNITS
 	REPEAT                           = ogl.REPEAT
 	MIRRORED_REPEAT                  = ogl.MIRRORED_REPEAT
@@ -285,6 +333,9 @@ const (
 	CLAMP_TO_BORDER                  = ogl.CLAMP_TO_BORDER
 	LINEAR                           = ogl.LINEAR
 	NEAREST                          = ogl.NEAREST
+	TEXTURE_WIDTH                    = ogl.TEXTURE_WIDTH
+	TEXTURE_HEIGHT                   = ogl.TEXTURE_HEIGHT
+	TEXTURE_DEPTH                    = ogl.TEXTURE_DEPTH
 	TEXTURE_MIN_FILTER               = ogl.TEXTURE_MIN_FILTER
 	TEXTURE_MAG_FILTER               = ogl.TEXTURE_MAG_FILTER
 	TEXTURE_WRAP_R                   = ogl.TEXTURE_WRAP_R
@@ -327,6 +378,7 @@ const (
 	UNSIGNED_INT                     = ogl.UNSIGNED_INT
 	INT                              = ogl.INT
 	FLOAT                            = ogl.FLOAT
+	HALF_FLOAT                       = ogl.HALF_FLOAT
 	UNSIGNED_BYTE_3_3_2              = ogl.UNSIGNED_BYTE_3_3_2
 	UNSIGNED_BYTE_2_3_3_REV          = ogl.UNSIGNED_BYTE_2_3_3_REV
 	UNSIGNED_SHORT_5_6_5             = ogl.UNSIGNED_SHORT_5_6_5
@@ -415,6 +467,7 @@ const (
 	DRAW_INDIRECT_BUFFER             = ogl.DRAW_INDIRECT_BUFFER
 	ELEMENT_ARRAY_BUFFER             = ogl.ELEMENT_ARRAY_BUFFER
 	PIXEL_PACK_BUFFER                = ogl.PIXEL_PACK_BUFFER
+	PIXEL_PACK_BUFFER_BINDING        = ogl.PIXEL_PACK_BUFFER_BINDING
 	PIXEL_UNPACK_BUFFER              = ogl.PIXEL_UNPACK_BUFFER
 	QUERY_BUFFER                     = ogl.QUERY_BUFFER
 	SHADER_STORAGE_BUFFER            = ogl.SHADER_STORAGE_BUFFER
@@ -433,6 +486,8 @@ const (
 	FRAMEBUFFER_COMPLETE             = ogl.FRAMEBUFFER_COMPLETE
 	DRAW_FRAMEBUFFER                 = ogl.DRAW_FRAMEBUFFER
 	READ_FRAMEBUFFER                 = ogl.READ_FRAMEBUFFER
+	READ_FRAMEBUFFER_BINDING         = ogl.READ_FRAMEBUFFER_BINDING
+	READ_BUFFER                      = ogl.READ_BUFFER
 	COLOR_ATTACHMENT0                = ogl.COLOR_ATTACHMENT0
 	COLOR_ATTACHMENT1                = ogl.COLOR_ATTACHMENT1
 	COLOR_ATTACHMENT2                = ogl.COLOR_ATTACHMENT2
@@ -473,4 +528,74 @@ const (
 	TRIANGLES_ADJACENCY              = ogl.TRIANGLES_ADJACENCY
 	PATCHES                          = ogl.PATCHES
 	ALL_BARRIER_BITS                 = ogl.ALL_BARRIER_BITS
+	BUFFER_UPDATE_BARRIER_BIT        = ogl.BUFFER_UPDATE_BARRIER_BIT
+	SHADER_STORAGE_BARRIER_BIT       = ogl.SHADER_STORAGE_BARRIER_BIT
+	CLIENT_MAPPED_BUFFER_BARRIER_BIT = ogl.CLIENT_MAPPED_BUFFER_BARRIER_BIT
+)
+
+// Buffer storage and synchronization
+const (
+	DYNAMIC_STORAGE_BIT        = ogl.DYNAMIC_STORAGE_BIT
+	MAP_READ_BIT               = ogl.MAP_READ_BIT
+	MAP_WRITE_BIT              = ogl.MAP_WRITE_BIT
+	MAP_PERSISTENT_BIT         = ogl.MAP_PERSISTENT_BIT
+	MAP_COHERENT_BIT           = ogl.MAP_COHERENT_BIT
+	SYNC_GPU_COMMANDS_COMPLETE = ogl.SYNC_GPU_COMMANDS_COMPLETE
+	SYNC_FLUSH_COMMANDS_BIT    = ogl.SYNC_FLUSH_COMMANDS_BIT
+	WAIT_FAILED                = ogl.WAIT_FAILED
+	ALREADY_SIGNALED           = ogl.ALREADY_SIGNALED
+	CONDITION_SATISFIED        = ogl.CONDITION_SATISFIED
+	TIMEOUT_EXPIRED            = ogl.TIMEOUT_EXPIRED
+)
+
+// Functions for introspecting linked programs
+var (
+	GetActiveUniform          = ogl.GetActiveUniform
+	GetActiveUniformBlockName = ogl.GetActiveUniformBlockName
+	GetActiveUniformBlockiv   = ogl.GetActiveUniformBlockiv
+	GetProgramInterfaceiv     = ogl.GetProgramInterfaceiv
+	GetProgramResourceName    = ogl.GetProgramResourceName
+	GetProgramResourceiv      = ogl.GetProgramResourceiv
+)
+
+// Program introspection
+const (
+	ACTIVE_UNIFORMS                      = ogl.ACTIVE_UNIFORMS
+	CURRENT_PROGRAM                      = ogl.CURRENT_PROGRAM
+	ACTIVE_UNIFORM_MAX_LENGTH            = ogl.ACTIVE_UNIFORM_MAX_LENGTH
+	ACTIVE_UNIFORM_BLOCKS                = ogl.ACTIVE_UNIFORM_BLOCKS
+	ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH = ogl.ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH
+	UNIFORM_BLOCK_BINDING                = ogl.UNIFORM_BLOCK_BINDING
+	UNIFORM_BLOCK_DATA_SIZE              = ogl.UNIFORM_BLOCK_DATA_SIZE
+	SHADER_STORAGE_BLOCK                 = ogl.SHADER_STORAGE_BLOCK
+	ACTIVE_RESOURCES                     = ogl.ACTIVE_RESOURCES
+	MAX_NAME_LENGTH                      = ogl.MAX_NAME_LENGTH
+	BUFFER_BINDING                       = ogl.BUFFER_BINDING
+	BUFFER_DATA_SIZE                     = ogl.BUFFER_DATA_SIZE
+)
+
+// Uniform types
+const (
+	BOOL                   = ogl.BOOL
+	BOOL_VEC2              = ogl.BOOL_VEC2
+	BOOL_VEC3              = ogl.BOOL_VEC3
+	BOOL_VEC4              = ogl.BOOL_VEC4
+	INT_VEC2               = ogl.INT_VEC2
+	INT_VEC3               = ogl.INT_VEC3
+	INT_VEC4               = ogl.INT_VEC4
+	FLOAT_VEC2             = ogl.FLOAT_VEC2
+	FLOAT_VEC3             = ogl.FLOAT_VEC3
+	FLOAT_VEC4             = ogl.FLOAT_VEC4
+	FLOAT_MAT3             = ogl.FLOAT_MAT3
+	FLOAT_MAT4             = ogl.FLOAT_MAT4
+	SAMPLER_1D             = ogl.SAMPLER_1D
+	SAMPLER_2D             = ogl.SAMPLER_2D
+	SAMPLER_3D             = ogl.SAMPLER_3D
+	SAMPLER_CUBE           = ogl.SAMPLER_CUBE
+	SAMPLER_2D_SHADOW      = ogl.SAMPLER_2D_SHADOW
+	SAMPLER_2D_ARRAY       = ogl.SAMPLER_2D_ARRAY
+	SAMPLER_2D_MULTISAMPLE = ogl.SAMPLER_2D_MULTISAMPLE
+	IMAGE_1D               = ogl.IMAGE_1D
+	IMAGE_2D               = ogl.IMAGE_2D
+	IMAGE_3D               = ogl.IMAGE_3D
 )
@@ -56,6 +56,11 @@ func New(window *window.Window) *Interaction {
 		loopCursor:   false,
 	}
 
+	// headless windows have no input
+	if window.IsHeadless() {
+		return &interaction
+	}
+
 	// add handlers to window
 	window.Window.SetCursorPosCallback(interaction.onCursorPos)
 	window.Window.SetCursorEnterCallback(interaction.onCursorEnter)
@@ -69,7 +74,9 @@ func New(window *window.Window) *Interaction {
 // EnableCursorLoop hides the cursor and loops it inside the window in x and y direction.
 func (interaction *Interaction) EnableCursorLoop() {
 	interaction.loopCursor = true
-	interaction.ctx.Window.SetInputMode(glfw.CursorMode, glfw.CursorHidden)
+	if !interaction.ctx.IsHeadless() {
+		interaction.ctx.Window.SetInputMode(glfw.CursorMode, glfw.CursorHidden)
+	}
 }
 
 // AddInteractable registers all handlers of the interactable in the Windowmanager.
@@ -0,0 +1,178 @@
+package shader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// LogMessage is a single message of a shader info log. Line refers to the
+// code that had been passed to the driver and is 0 if the message has no line.
+type LogMessage struct {
+	SourceString int
+	Line         int
+	Column       int
+	Severity     string
+	Text         string
+}
+
+// logFormats are the info log formats of the common drivers.
+// Each format captures source string, line, column, severity and text.
+var logFormats = []struct {
+	regexp *regexp.Regexp
+	parse  func(match []string) LogMessage
+}{
+	// Mesa: 0:412(5): error: `foo' undeclared
+	{
+		regexp.MustCompile(`^(\d+):(\d+)\((\d+)\): (\w+): (.*)$`),
+		func(match []string) LogMessage {
+			return makeLogMessage(match[1], match[2], match[3], match[4], match[5])
+		},
+	},
+	// NVIDIA: 0(412) : error C1008: undefined variable "foo"
+	{
+		regexp.MustCompile(`^(\d+)\((\d+)\) : (\w+) (\w+: .*)$`),
+		func(match []string) LogMessage {
+			return makeLogMessage(match[1], match[2], "", match[3], match[4])
+		},
+	},
+	// AMD, Intel and Apple: ERROR: 0:412: 'foo' : undeclared identifier
+	{
+		regexp.MustCompile(`^(\w+): (\d+):(\d+): (.*)$`),
+		func(match []string) LogMessage {
+			return makeLogMessage(match[2], match[3], "", match[1], match[4])
+		},
+	},
+	// messages without location: ERROR: 2 compilation errors.  No code generated.
+	{
+		regexp.MustCompile(`^(ERROR|WARNING|error|warning): (.*)$`),
+		func(match []string) LogMessage {
+			return makeLogMessage("", "", "", match[1], match[2])
+		},
+	},
+}
+
+// makeLogMessage constructs a log message from the parts matched by a log format.
+func makeLogMessage(sourceString, line, column, severity, text string) LogMessage {
+	message := LogMessage{
+		Severity: strings.ToLower(severity),
+		Text:     strings.TrimSpace(text),
+	}
+	message.SourceString, _ = strconv.Atoi(sourceString)
+	message.Line, _ = strconv.Atoi(line)
+	message.Column, _ = strconv.Atoi(column)
+	return message
+}
+
+// ParseLog splits a shader info log of the NVIDIA, AMD, Intel, Apple or Mesa
+// driver into messages. Indented lines that match none of the formats are
+// appended to the previous message, all other lines become errors without line.
+func ParseLog(log string) []LogMessage {
+	var messages []LogMessage
+	for _, line := range strings.Split(strings.Trim(log, "\x00"), "\n") {
+		line = strings.TrimRight(line, "\r\x00 ")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parsed := false
+		for _, format := range logFormats {
+			if match := format.regexp.FindStringSubmatch(line); match != nil {
+				messages = append(messages, format.parse(match))
+				parsed = true
+				break
+			}
+		}
+		if parsed {
+			continue
+		}
+
+		// continuation lines like notes belong to the previous message
+		if len(messages) > 0 && strings.HasPrefix(line, " ") {
+			messages[len(messages)-1].Text += "\n" + strings.TrimSpace(line)
+			continue
+		}
+		messages = append(messages, LogMessage{Severity: "error", Text: strings.TrimSpace(line)})
+	}
+	return messages
+}
+
+// CompileMessage is a message of the info log mapped back to the original
+// shader file, along with the text of the line.
+type CompileMessage struct {
+	Location SourceLocation
+	Column   int
+	Severity string
+	Text     string
+	Excerpt  string
+}
+
+// CompileError is returned if a shader stage fails to compile.
+type CompileError struct {
+	Stage    string
+	Path     string
+	Messages []CompileMessage
+	Log      string
+}
+
+// Error lists each message with its original location and source excerpt.
+func (err *CompileError) Error() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "failed to compile %v shader %v", err.Stage, err.Path)
+	for _, message := range err.Messages {
+		builder.WriteString("\n")
+		if message.Location.Line > 0 {
+			builder.WriteString(message.Location.String())
+			if message.Column > 0 {
+				fmt.Fprintf(&builder, ":%v", message.Column)
+			}
+			builder.WriteString(": ")
+		}
+		fmt.Fprintf(&builder, "%v: %v", message.Severity, message.Text)
+		if message.Excerpt != "" {
+			fmt.Fprintf(&builder, "\n    %v", strings.TrimSpace(message.Excerpt))
+		}
+	}
+	return builder.String()
+}
+
+// MakeCompileError constructs a compile error of the specified stage by
+// parsing the info log and mapping the lines back with the source map.
+func MakeCompileError(stage, path string, source *Source, log string) *CompileError {
+	err := &CompileError{
+		Stage: stage,
+		Path:  path,
+		Log:   log,
+	}
+	for _, message := range ParseLog(log) {
+		compileMessage := CompileMessage{
+			Column:   message.Column,
+			Severity: message.Severity,
+			Text:     message.Text,
+		}
+		if location, ok := source.Locate(message.Line); ok {
+			compileMessage.Location = location
+			compileMessage.Excerpt, _ = source.GetLine(location)
+		}
+		err.Messages = append(err.Messages, compileMessage)
+	}
+	return err
+}
+
+// getStageName returns the name of the shader type.
+func getStageName(shaderType uint32) string {
+	switch shaderType {
+	case gl.VERTEX_SHADER:
+		return "vertex"
+	case gl.GEOMETRY_SHADER:
+		return "geometry"
+	case gl.FRAGMENT_SHADER:
+		return "fragment"
+	case gl.COMPUTE_SHADER:
+		return "compute"
+	}
+	return fmt.Sprintf("type %v", shaderType)
+}
@@ -0,0 +1,112 @@
+package shader
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// makeTestSource preprocesses a shader whose main file includes lib.glsl at
+// line 2. The included lines 1 to 10 end up at the lines 2 to 11 of the code
+// and line n > 2 of main.glsl ends up at line n+9.
+func makeTestSource(t *testing.T) Source {
+	var lib, main []string
+	for i := 1; i <= 10; i++ {
+		lib = append(lib, fmt.Sprintf("float lib%v;", i))
+	}
+	main = append(main, "#version 430", "#include \"lib.glsl\"")
+	for i := 3; i <= 500; i++ {
+		main = append(main, fmt.Sprintf("float main%v;", i))
+	}
+
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": strings.Join(main, "\n"),
+		"lib.glsl":  strings.Join(lib, "\n"),
+	})
+	source, err := preprocessor.Process("main.glsl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestMakeCompileError(t *testing.T) {
+	tests := []struct {
+		driver   string
+		log      string
+		expected []CompileMessage
+	}{
+		{
+			"NVIDIA",
+			"0(412) : error C1008: undefined variable \"density\"\n" +
+				"0(5) : warning C7533: global variable gl_FragColor is deprecated after version 120\n" +
+				"0(421) : error C1115: unable to find compatible overloaded function \"texture(sampler3D, vec2)\"\n" +
+				"    candidates are: texture(sampler3D, vec3)\n" +
+				"\x00",
+			[]CompileMessage{
+				{SourceLocation{"main.glsl", 403}, 0, "error", "C1008: undefined variable \"density\"", "float main403;"},
+				{SourceLocation{"lib.glsl", 4}, 0, "warning", "C7533: global variable gl_FragColor is deprecated after version 120", "float lib4;"},
+				{SourceLocation{"main.glsl", 412}, 0, "error", "C1115: unable to find compatible overloaded function \"texture(sampler3D, vec2)\"\ncandidates are: texture(sampler3D, vec3)", "float main412;"},
+			},
+		},
+		{
+			"Mesa",
+			"0:412(5): error: `density' undeclared\n" +
+				"0:412(5): error: operands to arithmetic operators must be numeric\n" +
+				"0:2(12): warning: `unused' declared but never used\n",
+			[]CompileMessage{
+				{SourceLocation{"main.glsl", 403}, 5, "error", "`density' undeclared", "float main403;"},
+				{SourceLocation{"main.glsl", 403}, 5, "error", "operands to arithmetic operators must be numeric", "float main403;"},
+				{SourceLocation{"lib.glsl", 1}, 12, "warning", "`unused' declared but never used", "float lib1;"},
+			},
+		},
+		{
+			"AMD",
+			"ERROR: 0:412: 'density' : undeclared identifier \n" +
+				"ERROR: 0:412: '' : compilation terminated \n" +
+				"  note: the error occurred in function main\n" +
+				"ERROR: 2 compilation errors.  No code generated.\n\n",
+			[]CompileMessage{
+				{SourceLocation{"main.glsl", 403}, 0, "error", "'density' : undeclared identifier", "float main403;"},
+				{SourceLocation{"main.glsl", 403}, 0, "error", "'' : compilation terminated\nnote: the error occurred in function main", "float main403;"},
+				{SourceLocation{}, 0, "error", "2 compilation errors.  No code generated.", ""},
+			},
+		},
+		{
+			"unknown",
+			"something went wrong\n0:9999(1): error: out of range\n",
+			[]CompileMessage{
+				{SourceLocation{}, 0, "error", "something went wrong", ""},
+				{SourceLocation{}, 1, "error", "out of range", ""},
+			},
+		},
+	}
+
+	source := makeTestSource(t)
+	for _, test := range tests {
+		t.Run(test.driver, func(t *testing.T) {
+			err := MakeCompileError("fragment", "main.glsl", &source, test.log)
+			if len(err.Messages) != len(test.expected) {
+				t.Fatalf("expected %v messages but got %v: %+v", len(test.expected), len(err.Messages), err.Messages)
+			}
+			for i, expected := range test.expected {
+				if err.Messages[i] != expected {
+					t.Errorf("message %v: expected %+v but got %+v", i, expected, err.Messages[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCompileErrorMessage(t *testing.T) {
+	source := makeTestSource(t)
+	err := MakeCompileError("fragment", "main.glsl", &source, "0:412(5): error: `density' undeclared\nERROR: 1 compilation errors.")
+
+	expected := "failed to compile fragment shader main.glsl\n" +
+		"main.glsl:403:5: error: `density' undeclared\n" +
+		"    float main403;\n" +
+		"error: 1 compilation errors."
+	if err.Error() != expected {
+		t.Errorf("expected\n%v\nbut got\n%v", expected, err.Error())
+	}
+}
@@ -0,0 +1,507 @@
+package shader
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SourceLocation is a line of an original shader file.
+type SourceLocation struct {
+	File string
+	Line int
+}
+
+// String returns the location as file:line.
+func (location SourceLocation) String() string {
+	return fmt.Sprintf("%v:%v", location.File, location.Line)
+}
+
+// Source is a preprocessed shader source. It maps each line of the code back
+// to the file and line it originates from and knows all files it depends on.
+type Source struct {
+	code      string
+	locations []SourceLocation
+	files     []string
+	original  map[string][]string
+}
+
+// GetCode returns the preprocessed code.
+func (source *Source) GetCode() string {
+	return source.code
+}
+
+// GetFiles returns the paths of the main file and all included files.
+func (source *Source) GetFiles() []string {
+	return source.files
+}
+
+// Locate returns the original location of the specified line of the
+// preprocessed code, with the first line being 1.
+func (source *Source) Locate(line int) (SourceLocation, bool) {
+	if line < 1 || line > len(source.locations) {
+		return SourceLocation{}, false
+	}
+	return source.locations[line-1], true
+}
+
+// GetLine returns the original text at the specified location.
+func (source *Source) GetLine(location SourceLocation) (string, bool) {
+	lines, ok := source.original[location.File]
+	if !ok || location.Line < 1 || location.Line > len(lines) {
+		return "", false
+	}
+	return lines[location.Line-1], true
+}
+
+// definesFile is the file name of the lines that are injected for the defines
+// of the preprocessor.
+const definesFile = "<defines>"
+
+// Preprocessor expands #include directives and evaluates #ifdef, #ifndef,
+// #else and #endif blocks of shader files. Includes are looked up relative to
+// the including file first and then in the include paths. Each file is only
+// included once. Defines of the preprocessor are injected right after the
+// #version directive. All other directives such as #if are left to the driver,
+// as well as #ifdef and #ifndef blocks that are continued with #elif.
+//
+// Blocks left to the driver may or may not be compiled, thus macros that are
+// defined or undefined within them are unknown and #ifdef and #ifndef blocks
+// of unknown macros are left to the driver as well. Each branch of such a
+// block includes files independently of its sibling branches, and after the
+// block a file only counts as included if every branch included it.
+type Preprocessor struct {
+	includePaths []string
+	defines      map[string]string
+	readFile     func(path string) ([]byte, error)
+}
+
+// MakePreprocessor constructs a preprocessor with the specified include paths.
+func MakePreprocessor(includePaths ...string) Preprocessor {
+	return Preprocessor{
+		includePaths: includePaths,
+		defines:      make(map[string]string),
+		readFile:     ioutil.ReadFile,
+	}
+}
+
+// AddIncludePath adds a directory that is searched for included files.
+func (preprocessor *Preprocessor) AddIncludePath(path string) {
+	preprocessor.includePaths = append(preprocessor.includePaths, path)
+}
+
+// Define defines a macro with the specified value. The value may be empty.
+func (preprocessor *Preprocessor) Define(name string, value interface{}) {
+	if preprocessor.defines == nil {
+		preprocessor.defines = make(map[string]string)
+	}
+	preprocessor.defines[name] = strings.TrimSpace(fmt.Sprint(value))
+}
+
+// Undefine removes the macro with the specified name.
+func (preprocessor *Preprocessor) Undefine(name string) {
+	delete(preprocessor.defines, name)
+}
+
+// SetReadFile replaces the function that is used to read files, which
+// allows to preprocess sources that are not stored on disk.
+func (preprocessor *Preprocessor) SetReadFile(readFile func(path string) ([]byte, error)) {
+	preprocessor.readFile = readFile
+}
+
+// clone returns a copy of the preprocessor that doesn't share its defines.
+func (preprocessor *Preprocessor) clone() Preprocessor {
+	clone := *preprocessor
+	clone.includePaths = append([]string(nil), preprocessor.includePaths...)
+	clone.defines = make(map[string]string)
+	for name, value := range preprocessor.defines {
+		clone.defines[name] = value
+	}
+	return clone
+}
+
+// Process preprocesses the shader file at the specified path.
+func (preprocessor *Preprocessor) Process(path string) (Source, error) {
+	state := preprocessState{
+		preprocessor: preprocessor,
+		defines:      make(map[string]bool),
+		unknown:      make(map[string]bool),
+		included:     make(map[string]bool),
+		source:       Source{original: make(map[string][]string)},
+	}
+	for name := range preprocessor.defines {
+		state.defines[name] = true
+	}
+
+	err := state.processFile(filepath.Clean(path))
+	if err != nil {
+		return Source{}, err
+	}
+
+	// defines go after the #version directive or at the top
+	if !state.versionFound {
+		state.injectDefines()
+	}
+	state.source.code = state.builder.String()
+
+	return state.source, nil
+}
+
+// preprocessState stores the state of one run of the preprocessor.
+type preprocessState struct {
+	preprocessor *Preprocessor
+	defines      map[string]bool
+	unknown      map[string]bool
+	included     map[string]bool
+	passthrough  int
+	versionFound bool
+	builder      strings.Builder
+	source       Source
+}
+
+// condition is an open #ifdef, #ifndef or #if block. Blocks of #if
+// directives and blocks with #elif are passed through to the driver.
+// For those the included files at the start of the block and the files
+// that all previous branches included are remembered.
+type condition struct {
+	location    SourceLocation
+	active      bool
+	parent      bool
+	passthrough bool
+	hasElse     bool
+	included    map[string]bool
+	common      map[string]bool
+}
+
+// pushPassthrough opens a block that is passed through to the driver.
+func (state *preprocessState) pushPassthrough(conditions []condition, location SourceLocation, active bool) []condition {
+	state.passthrough++
+	return append(conditions, condition{
+		location:    location,
+		active:      active,
+		parent:      active,
+		passthrough: true,
+		included:    copySet(state.included),
+	})
+}
+
+// nextBranch ends the current branch of a passthrough block, so that the
+// next branch includes files as if the previous branches didn't exist.
+func (state *preprocessState) nextBranch(top *condition) {
+	top.common = intersectSets(top.common, state.included)
+	state.included = copySet(top.included)
+}
+
+// popPassthrough closes a passthrough block. Only files that were included
+// by every branch count as included afterwards. Without #else there is an
+// implicit empty branch.
+func (state *preprocessState) popPassthrough(top *condition) {
+	state.passthrough--
+	common := intersectSets(top.common, state.included)
+	if !top.hasElse {
+		common = intersectSets(common, top.included)
+	}
+	state.included = common
+}
+
+// copySet returns a copy of the set.
+func copySet(set map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(set))
+	for key := range set {
+		copied[key] = true
+	}
+	return copied
+}
+
+// intersectSets returns the keys that are in both sets, where a nil set a
+// contains all keys.
+func intersectSets(a, b map[string]bool) map[string]bool {
+	if a == nil {
+		return copySet(b)
+	}
+	result := make(map[string]bool)
+	for key := range a {
+		if b[key] {
+			result[key] = true
+		}
+	}
+	return result
+}
+
+// processFile appends the lines of the file to the output.
+func (state *preprocessState) processFile(path string) error {
+	// each file is only included once
+	if state.included[path] {
+		return nil
+	}
+	state.included[path] = true
+
+	data, err := state.preprocessor.readFile(path)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.Replace(string(data), "\r\n", "\n", -1), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	state.source.files = append(state.source.files, path)
+	state.source.original[path] = lines
+
+	var conditions []condition
+	active := true
+	inComment := false
+	for i, line := range lines {
+		location := SourceLocation{path, i + 1}
+
+		// directives inside of block comments are ignored
+		directive, args := "", ""
+		if !inComment {
+			directive, args = parseDirective(line)
+		}
+		inComment = updateBlockComment(line, inComment)
+
+		switch directive {
+		case "ifdef", "ifndef":
+			name := firstWord(args)
+			if name == "" {
+				return fmt.Errorf("%v: #%v without macro name", location, directive)
+			}
+			// chains with #elif can't be evaluated without expressions and
+			// macros that are unknown can't be evaluated at all, thus they are passed through
+			if hasElif(lines[i+1:], inComment) || state.unknown[name] {
+				conditions = state.pushPassthrough(conditions, location, active)
+				break
+			}
+			taken := state.defines[name] == (directive == "ifdef")
+			conditions = append(conditions, condition{location: location, active: active && taken, parent: active})
+			active = active && taken
+			continue
+		case "if":
+			conditions = state.pushPassthrough(conditions, location, active)
+		case "elif":
+			if len(conditions) == 0 || !conditions[len(conditions)-1].passthrough {
+				return fmt.Errorf("%v: #elif without #if", location)
+			}
+			state.nextBranch(&conditions[len(conditions)-1])
+		case "else":
+			if len(conditions) == 0 {
+				return fmt.Errorf("%v: #else without #ifdef", location)
+			}
+			top := &conditions[len(conditions)-1]
+			if top.hasElse {
+				return fmt.Errorf("%v: second #else for block starting at %v", location, top.location)
+			}
+			if !top.passthrough {
+				top.hasElse = true
+				top.active = top.parent && !top.active
+				active = top.active
+				continue
+			}
+			state.nextBranch(top)
+			top.hasElse = true
+		case "endif":
+			if len(conditions) == 0 {
+				return fmt.Errorf("%v: #endif without #ifdef", location)
+			}
+			top := conditions[len(conditions)-1]
+			conditions = conditions[:len(conditions)-1]
+			if !top.passthrough {
+				active = top.parent
+				continue
+			}
+			state.popPassthrough(&top)
+		}
+		if !active {
+			continue
+		}
+
+		switch directive {
+		case "include":
+			includePath, err := state.resolveInclude(path, args)
+			if err != nil {
+				return fmt.Errorf("%v: %v", location, err)
+			}
+			if err := state.processFile(includePath); err != nil {
+				return err
+			}
+			continue
+		case "define", "undef":
+			name := firstWord(args)
+			// the driver decides whether passthrough blocks are compiled
+			if state.passthrough > 0 {
+				state.unknown[name] = true
+				break
+			}
+			delete(state.unknown, name)
+			if directive == "define" {
+				state.defines[name] = true
+			} else {
+				delete(state.defines, name)
+			}
+		}
+
+		state.appendLine(line, location)
+		if directive == "version" && !state.versionFound {
+			state.versionFound = true
+			state.injectDefines()
+		}
+	}
+
+	if len(conditions) > 0 {
+		return fmt.Errorf("%v: conditional block without #endif", conditions[len(conditions)-1].location)
+	}
+	return nil
+}
+
+// hasElif returns true if the conditional block that starts right before the
+// lines is continued with an #elif directive before its #endif.
+func hasElif(lines []string, inComment bool) bool {
+	depth := 0
+	for _, line := range lines {
+		directive := ""
+		if !inComment {
+			directive, _ = parseDirective(line)
+		}
+		inComment = updateBlockComment(line, inComment)
+
+		switch directive {
+		case "if", "ifdef", "ifndef":
+			depth++
+		case "elif":
+			if depth == 0 {
+				return true
+			}
+		case "endif":
+			if depth == 0 {
+				return false
+			}
+			depth--
+		}
+	}
+	return false
+}
+
+// resolveInclude returns the path of the file specified by an #include
+// directive of the file at path.
+func (state *preprocessState) resolveInclude(path, args string) (string, error) {
+	args = strings.TrimSpace(args)
+	if len(args) < 2 || !(args[0] == '"' && args[len(args)-1] == '"' || args[0] == '<' && args[len(args)-1] == '>') {
+		return "", fmt.Errorf("malformed #include %v", args)
+	}
+	name := args[1 : len(args)-1]
+
+	// the directory of the including file is searched first
+	dirs := append([]string{filepath.Dir(path)}, state.preprocessor.includePaths...)
+	for _, dir := range dirs {
+		candidate := filepath.Clean(filepath.Join(dir, name))
+		if state.included[candidate] {
+			return candidate, nil
+		}
+		if _, err := state.preprocessor.readFile(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("cannot find include %q in %v", name, strings.Join(dirs, ", "))
+}
+
+// injectDefines appends a #define directive for each define of the preprocessor.
+func (state *preprocessState) injectDefines() {
+	var names []string
+	for name := range state.preprocessor.defines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		line := strings.TrimSpace("#define " + name + " " + state.preprocessor.defines[name])
+		lines = append(lines, line)
+	}
+	state.source.original[definesFile] = lines
+
+	// defines are injected after the #version directive, thus it needs to
+	// be moved to the end of the code if there is no #version directive
+	if !state.versionFound && len(lines) > 0 {
+		code, locations := state.builder.String(), state.source.locations
+		state.builder.Reset()
+		state.source.locations = nil
+		for i, line := range lines {
+			state.appendLine(line, SourceLocation{definesFile, i + 1})
+		}
+		state.builder.WriteString(code)
+		state.source.locations = append(state.source.locations, locations...)
+		return
+	}
+	for i, line := range lines {
+		state.appendLine(line, SourceLocation{definesFile, i + 1})
+	}
+}
+
+// appendLine appends the line to the output and records its location.
+func (state *preprocessState) appendLine(line string, location SourceLocation) {
+	state.builder.WriteString(line)
+	state.builder.WriteByte('\n')
+	state.source.locations = append(state.source.locations, location)
+}
+
+// parseDirective returns the name and the arguments of a preprocessor
+// directive without trailing line comments. Lines without directive
+// return an empty name.
+func parseDirective(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "#") {
+		return "", ""
+	}
+	if idx := strings.Index(line, "//"); idx >= 0 {
+		line = line[:idx]
+	}
+	line = strings.TrimSpace(line[1:])
+
+	name := firstWord(line)
+	return name, strings.TrimSpace(line[len(name):])
+}
+
+// updateBlockComment returns true if a block comment is open after the line.
+func updateBlockComment(line string, inComment bool) bool {
+	for i := 0; i < len(line)-1; i++ {
+		switch {
+		case !inComment && line[i] == '/' && line[i+1] == '/':
+			return false
+		case !inComment && line[i] == '/' && line[i+1] == '*':
+			inComment = true
+			i++
+		case inComment && line[i] == '*' && line[i+1] == '/':
+			inComment = false
+			i++
+		}
+	}
+	return inComment
+}
+
+// firstWord returns the first word of s that consists of letters, digits and underscores.
+func firstWord(s string) string {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) {
+		c := s[end]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			break
+		}
+		end++
+	}
+	return s[:end]
+}
+
+// LoadFileWithIncludes resolves file includes and returns the resulting zero
+// terminated shader source.
+func LoadFileWithIncludes(filepath string) (string, error) {
+	preprocessor := MakePreprocessor()
+	source, err := preprocessor.Process(filepath)
+	if err != nil {
+		return "", err
+	}
+	return source.GetCode() + "\x00", nil
+}
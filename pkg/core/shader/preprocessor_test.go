@@ -0,0 +1,297 @@
+package shader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeTestPreprocessor constructs a preprocessor that reads the files from
+// memory instead of the disk.
+func makeTestPreprocessor(files map[string]string, includePaths ...string) Preprocessor {
+	preprocessor := MakePreprocessor(includePaths...)
+	preprocessor.SetReadFile(func(path string) ([]byte, error) {
+		content, ok := files[filepath.ToSlash(path)]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(content), nil
+	})
+	return preprocessor
+}
+
+// process preprocesses main.glsl and returns the lines of the code.
+func process(t *testing.T, preprocessor Preprocessor) ([]string, Source) {
+	t.Helper()
+	source, err := preprocessor.Process("main.glsl")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(source.GetCode(), "\n"), "\n"), source
+}
+
+func checkLines(t *testing.T, got []string, expected ...string) {
+	t.Helper()
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%v\nbut got\n%v", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPreprocessorIncludes(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl":      "#version 430\n#include \"util/a.glsl\"\n#include <b.glsl>\nvoid main() {}\n",
+		"util/a.glsl":    "float a;\n#include \"c.glsl\"\n",
+		"util/c.glsl":    "float c;\n",
+		"include/b.glsl": "float b;\n",
+	}, "include")
+
+	lines, source := process(t, preprocessor)
+	checkLines(t, lines, "#version 430", "float a;", "float c;", "float b;", "void main() {}")
+
+	expected := []string{"main.glsl", "util/a.glsl", "util/c.glsl", "include/b.glsl"}
+	files := source.GetFiles()
+	for i := range files {
+		files[i] = filepath.ToSlash(files[i])
+	}
+	checkLines(t, files, expected...)
+}
+
+func TestPreprocessorIncludeOnce(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": "#include \"a.glsl\"\n#include \"b.glsl\"\n#include \"a.glsl\"\n",
+		"a.glsl":    "float a;\n#include \"b.glsl\"\n",
+		"b.glsl":    "float b;\n#include \"a.glsl\"\n",
+	})
+
+	lines, _ := process(t, preprocessor)
+	checkLines(t, lines, "float a;", "float b;")
+}
+
+func TestPreprocessorDefines(t *testing.T) {
+	files := map[string]string{
+		"main.glsl": "#version 430 core\n#ifdef STEPS\nint steps = STEPS;\n#endif\n#ifndef DEBUG\nbool debug = false;\n#endif\n",
+	}
+	preprocessor := makeTestPreprocessor(files)
+	preprocessor.Define("STEPS", 64)
+	preprocessor.Define("SHADOWS", "")
+
+	// defines are injected sorted after the #version directive
+	lines, _ := process(t, preprocessor)
+	checkLines(t, lines, "#version 430 core", "#define SHADOWS", "#define STEPS 64", "int steps = STEPS;", "bool debug = false;")
+
+	// without #version the defines go to the top
+	files["main.glsl"] = "#ifdef STEPS\nint steps = STEPS;\n#endif\n"
+	lines, _ = process(t, preprocessor)
+	checkLines(t, lines, "#define SHADOWS", "#define STEPS 64", "int steps = STEPS;")
+
+	// undefined macros remove the block
+	preprocessor.Undefine("STEPS")
+	lines, _ = process(t, preprocessor)
+	checkLines(t, lines, "#define SHADOWS")
+}
+
+func TestPreprocessorNesting(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": strings.Join([]string{
+			"#define LOCAL",
+			"#ifdef A",
+			"  #ifdef B",
+			"a and b;",
+			"  #else",
+			"a not b;",
+			"  #endif",
+			"#else",
+			"  #ifdef LOCAL",
+			"local;",
+			"  #endif",
+			"not a;",
+			"#endif",
+			"#if X > 1",
+			"  #ifdef A",
+			"x and a;",
+			"  #endif",
+			"#elif X > 0",
+			"x;",
+			"#endif",
+		}, "\n"),
+	})
+	preprocessor.Define("A", 1)
+
+	lines, _ := process(t, preprocessor)
+	checkLines(t, lines, "#define A 1", "#define LOCAL", "a not b;", "#if X > 1", "x and a;", "#elif X > 0", "x;", "#endif")
+}
+
+func TestPreprocessorElifChain(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": strings.Join([]string{
+			"#ifdef A",
+			"a;",
+			"#elif defined(B)",
+			"b;",
+			"#else",
+			"none;",
+			"#endif",
+			"#ifndef C",
+			"#ifdef D",
+			"d;",
+			"#endif",
+			"#elif defined(E)",
+			"e;",
+			"#endif",
+		}, "\n"),
+	})
+
+	// chains with #elif are passed through to the driver
+	lines, _ := process(t, preprocessor)
+	checkLines(t, lines,
+		"#ifdef A", "a;", "#elif defined(B)", "b;", "#else", "none;", "#endif",
+		"#ifndef C", "#elif defined(E)", "e;", "#endif")
+}
+
+func TestPreprocessorPassthroughIncludes(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": strings.Join([]string{
+			"#if FOO",
+			"#include \"a.glsl\"",
+			"#include \"b.glsl\"",
+			"#else",
+			"#include \"a.glsl\"",
+			"#endif",
+			"#include \"a.glsl\"",
+			"#include \"b.glsl\"",
+			"#if BAR",
+			"#include \"c.glsl\"",
+			"#endif",
+			"#include \"c.glsl\"",
+		}, "\n"),
+		"a.glsl": "float a;\n",
+		"b.glsl": "float b;\n",
+		"c.glsl": "float c;\n",
+	})
+
+	// each branch includes its files, afterwards only files of all branches count as included
+	lines, _ := process(t, preprocessor)
+	checkLines(t, lines,
+		"#if FOO", "float a;", "float b;", "#else", "float a;", "#endif", "float b;",
+		"#if BAR", "float c;", "#endif", "float c;")
+}
+
+func TestPreprocessorPassthroughDefines(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": strings.Join([]string{
+			"#if 0",
+			"#define BAR",
+			"#undef BAZ",
+			"#endif",
+			"#ifdef BAR",
+			"float bar;",
+			"#endif",
+			"#ifndef BAZ",
+			"float baz;",
+			"#endif",
+			"#define BAR",
+			"#ifdef BAR",
+			"float known;",
+			"#endif",
+		}, "\n"),
+	})
+	preprocessor.Define("BAZ", "")
+
+	// macros that are changed in blocks of the driver are left to the driver
+	lines, _ := process(t, preprocessor)
+	checkLines(t, lines,
+		"#define BAZ",
+		"#if 0", "#define BAR", "#undef BAZ", "#endif",
+		"#ifdef BAR", "float bar;", "#endif",
+		"#ifndef BAZ", "float baz;", "#endif",
+		"#define BAR", "float known;")
+}
+
+func TestPreprocessorComments(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": strings.Join([]string{
+			"/*",
+			"#include \"missing.glsl\"",
+			"#ifdef A",
+			"*/",
+			"#ifdef A // comment",
+			"a;",
+			"#endif /* comment */",
+			"// #include \"missing.glsl\"",
+			"b; /* start",
+			"#endif",
+			"end */ c;",
+		}, "\n"),
+	})
+
+	lines, _ := process(t, preprocessor)
+	checkLines(t, lines, "/*", "#include \"missing.glsl\"", "#ifdef A", "*/", "// #include \"missing.glsl\"", "b; /* start", "#endif", "end */ c;")
+}
+
+func TestPreprocessorErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		code  string
+		error string
+	}{
+		{"missing include", "#include \"missing.glsl\"", "main.glsl:1: cannot find include \"missing.glsl\""},
+		{"malformed include", "#include missing.glsl", "main.glsl:1: malformed #include"},
+		{"ifdef without name", "#ifdef\n#endif", "main.glsl:1: #ifdef without macro name"},
+		{"else without ifdef", "a;\n#else", "main.glsl:2: #else without #ifdef"},
+		{"endif without ifdef", "#endif", "main.glsl:1: #endif without #ifdef"},
+		{"elif without if", "#elif A", "main.glsl:1: #elif without #if"},
+		{"second else", "#ifdef A\n#else\n#else\n#endif", "main.glsl:3: second #else for block starting at main.glsl:1"},
+		{"missing endif", "#ifdef A\n#ifndef B\n#endif", "main.glsl:1: conditional block without #endif"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			preprocessor := makeTestPreprocessor(map[string]string{"main.glsl": test.code})
+			_, err := preprocessor.Process("main.glsl")
+			if err == nil {
+				t.Fatalf("expected error %q", test.error)
+			}
+			if !strings.HasPrefix(err.Error(), test.error) {
+				t.Errorf("expected error %q but got %q", test.error, err.Error())
+			}
+		})
+	}
+}
+
+func TestPreprocessorLocate(t *testing.T) {
+	preprocessor := makeTestPreprocessor(map[string]string{
+		"main.glsl": "#version 430\n#include \"a.glsl\"\n#ifdef A\nskipped;\n#endif\nmain;\n",
+		"a.glsl":    "// a\nfloat a;\n",
+	})
+	preprocessor.Define("B", 2)
+
+	_, source := process(t, preprocessor)
+	expected := []SourceLocation{
+		{"main.glsl", 1},
+		{definesFile, 1},
+		{"a.glsl", 1},
+		{"a.glsl", 2},
+		{"main.glsl", 6},
+	}
+	for i, location := range expected {
+		got, ok := source.Locate(i + 1)
+		if !ok || got != location {
+			t.Errorf("expected line %v at %v but got %v", i+1, location, got)
+		}
+	}
+	if _, ok := source.Locate(0); ok {
+		t.Error("expected line 0 to be invalid")
+	}
+	if _, ok := source.Locate(len(expected) + 1); ok {
+		t.Errorf("expected line %v to be invalid", len(expected)+1)
+	}
+
+	// the original text of the lines is available
+	if line, _ := source.GetLine(SourceLocation{"a.glsl", 2}); line != "float a;" {
+		t.Errorf("expected original line \"float a;\" but got %q", line)
+	}
+	if line, _ := source.GetLine(SourceLocation{definesFile, 1}); line != "#define B 2" {
+		t.Errorf("expected define \"#define B 2\" but got %q", line)
+	}
+}
@@ -6,144 +6,144 @@ package shader
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"strings"
+	"time"
 
 	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
-	"github.com/go-gl/mathgl/mgl32"
 )
 
 // Shader represents a shader program object and contains all Renderables that share the same shader.
+// Copies of a Shader share the same program, thus a reloaded program is used by all copies.
+// The zero value is a Shader without a program, whose state is allocated on first use.
 type Shader struct {
+	*program
+}
+
+// program is the state of a Shader that is shared by all of its copies.
+type program struct {
 	programHandle uint32
 	renderables   []Renderable
+	preprocessor  Preprocessor
+	stages        []stage
+	files         map[string]time.Time
+	strict        bool
+	blockBindings map[string]uint32
+	values        map[string]uniformValue
+	reflection
 }
 
-// Make contrusts a Shader that consists of a vertex and fragment shader.
-func Make(vertexShaderPath, fragmentShaderPath string) (Shader, error) {
-	// loads files
-	vertexShaderSource, err := loadFile(vertexShaderPath)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", vertexShaderPath, err)
-	}
-	fragmentShaderSource, err := loadFile(fragmentShaderPath)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", fragmentShaderPath, err)
-	}
-
-	// compile shaders
-	vertexShader, err := compileShader(vertexShaderSource, gl.VERTEX_SHADER)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", vertexShaderPath, err)
-	}
-	fragmentShader, err := compileShader(fragmentShaderSource, gl.FRAGMENT_SHADER)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", fragmentShaderPath, err)
-	}
-
-	// create and link program
-	program := gl.CreateProgram()
-	gl.AttachShader(program, vertexShader)
-	gl.AttachShader(program, fragmentShader)
-	gl.LinkProgram(program)
-
-	var status int32
-	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
-
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
-
-		return Shader{}, fmt.Errorf("failed to link program: %v", log)
+// init allocates the state of a zero value Shader.
+func (shader *Shader) init() {
+	if shader.program == nil {
+		shader.program = &program{}
 	}
+}
 
-	gl.DetachShader(program, vertexShader)
-	gl.DetachShader(program, fragmentShader)
-	gl.DeleteShader(vertexShader)
-	gl.DeleteShader(fragmentShader)
+// Make contrusts a Shader that consists of a vertex and fragment shader.
+func Make(vertexShaderPath, fragmentShaderPath string) (Shader, error) {
+	preprocessor := MakePreprocessor()
+	return MakeWithPreprocessor(&preprocessor, vertexShaderPath, fragmentShaderPath)
+}
 
-	shaderProgram := Shader{program, nil}
-	return shaderProgram, nil
+// MakeWithPreprocessor contrusts a Shader that consists of a vertex and fragment shader.
+// The shader files are preprocessed with the include paths and defines of the preprocessor.
+func MakeWithPreprocessor(preprocessor *Preprocessor, vertexShaderPath, fragmentShaderPath string) (Shader, error) {
+	return makeShader(preprocessor, []stage{
+		{gl.VERTEX_SHADER, vertexShaderPath},
+		{gl.FRAGMENT_SHADER, fragmentShaderPath},
+	})
 }
 
 // MakeGeomProgram contrusts a Shader that consists of a vertex, geometry and fragment shader.
 func MakeGeom(vertexShaderPath, geometryShaderPath, fragmentShaderPath string) (Shader, error) {
-	// loads files
-	vertexShaderSource, err := loadFile(vertexShaderPath)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", vertexShaderPath, err)
-	}
-	geometryShaderSource, err := loadFile(geometryShaderPath)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", geometryShaderPath, err)
-	}
-	fragmentShaderSource, err := loadFile(fragmentShaderPath)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", fragmentShaderPath, err)
-	}
+	preprocessor := MakePreprocessor()
+	return MakeGeomWithPreprocessor(&preprocessor, vertexShaderPath, geometryShaderPath, fragmentShaderPath)
+}
 
-	// compile shaders
-	vertexShader, err := compileShader(vertexShaderSource, gl.VERTEX_SHADER)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", vertexShaderPath, err)
-	}
-	geometryShader, err := compileShader(geometryShaderSource, gl.GEOMETRY_SHADER)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", geometryShaderPath, err)
-	}
-	fragmentShader, err := compileShader(fragmentShaderSource, gl.FRAGMENT_SHADER)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", fragmentShaderPath, err)
-	}
+// MakeGeomWithPreprocessor contrusts a Shader that consists of a vertex, geometry and fragment shader.
+// The shader files are preprocessed with the include paths and defines of the preprocessor.
+func MakeGeomWithPreprocessor(preprocessor *Preprocessor, vertexShaderPath, geometryShaderPath, fragmentShaderPath string) (Shader, error) {
+	return makeShader(preprocessor, []stage{
+		{gl.VERTEX_SHADER, vertexShaderPath},
+		{gl.GEOMETRY_SHADER, geometryShaderPath},
+		{gl.FRAGMENT_SHADER, fragmentShaderPath},
+	})
+}
 
-	// create and link program
-	program := gl.CreateProgram()
-	gl.AttachShader(program, vertexShader)
-	gl.AttachShader(program, geometryShader)
-	gl.AttachShader(program, fragmentShader)
-	gl.LinkProgram(program)
+// MakeComputeProgram contrusts a Shader that consists of a compute shader.
+func MakeCompute(computeShaderPath string) (Shader, error) {
+	preprocessor := MakePreprocessor()
+	return MakeComputeWithPreprocessor(&preprocessor, computeShaderPath)
+}
 
-	var status int32
-	gl.GetProgramiv(program, gl.LINK_STATUS, &status)
-	if status == gl.FALSE {
-		var logLength int32
-		gl.GetProgramiv(program, gl.INFO_LOG_LENGTH, &logLength)
+// MakeComputeWithPreprocessor contrusts a Shader that consists of a compute shader.
+// The shader file is preprocessed with the include paths and defines of the preprocessor.
+func MakeComputeWithPreprocessor(preprocessor *Preprocessor, computeShaderPath string) (Shader, error) {
+	return makeShader(preprocessor, []stage{
+		{gl.COMPUTE_SHADER, computeShaderPath},
+	})
+}
 
-		log := strings.Repeat("\x00", int(logLength+1))
-		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
+// stage is a shader stage of a program and the path of its shader file.
+type stage struct {
+	shaderType uint32
+	path       string
+}
 
-		return Shader{}, fmt.Errorf("failed to link program: %v", log)
+// makeShader constructs a Shader from the specified stages.
+// The stages and the preprocessor are kept for reloading the shader.
+func makeShader(preprocessor *Preprocessor, stages []stage) (Shader, error) {
+	handle, files, err := makeProgram(preprocessor, stages)
+	if err != nil {
+		return Shader{}, err
 	}
 
-	gl.DetachShader(program, vertexShader)
-	gl.DetachShader(program, geometryShader)
-	gl.DetachShader(program, fragmentShader)
-	gl.DeleteShader(vertexShader)
-	gl.DeleteShader(geometryShader)
-	gl.DeleteShader(fragmentShader)
+	shader := Shader{&program{
+		programHandle: handle,
+		preprocessor:  preprocessor.clone(),
+		stages:        stages,
+		reflection:    reflectProgram(handle),
+	}}
+	shader.watchFiles(files)
+	if newShaderWatcher != nil {
+		newShaderWatcher.Add(shader)
+	}
 
-	shaderProgram := Shader{program, nil}
-	return shaderProgram, nil
+	return shader, nil
 }
 
-// MakeComputeProgram contrusts a Shader that consists of a compute shader.
-func MakeCompute(computeShaderPath string) (Shader, error) {
-	// loads files
-	computeShaderSource, err := loadFile(computeShaderPath)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", computeShaderPath, err)
+// makeProgram preprocesses and compiles all stages and links them into a program.
+// It returns the handle of the program along with the paths of all shader files.
+func makeProgram(preprocessor *Preprocessor, stages []stage) (uint32, []string, error) {
+	// load and compile shaders
+	var shaders []uint32
+	var files []string
+	deleteShaders := func() {
+		for _, shader := range shaders {
+			gl.DeleteShader(shader)
+		}
 	}
-
-	// compile shaders
-	computeShader, err := compileShader(computeShaderSource, gl.COMPUTE_SHADER)
-	if err != nil {
-		return Shader{}, fmt.Errorf("Error on: %v\n%v", computeShaderPath, err)
+	for _, stage := range stages {
+		source, err := preprocessor.Process(stage.path)
+		if err != nil {
+			deleteShaders()
+			return 0, nil, fmt.Errorf("Error on: %v\n%v", stage.path, err)
+		}
+		shader, err := compileShader(&source, stage)
+		if err != nil {
+			deleteShaders()
+			return 0, nil, err
+		}
+		shaders = append(shaders, shader)
+		files = append(files, source.GetFiles()...)
 	}
 
 	// create and link program
 	program := gl.CreateProgram()
-	gl.AttachShader(program, computeShader)
+	for _, shader := range shaders {
+		gl.AttachShader(program, shader)
+	}
 	gl.LinkProgram(program)
 
 	// check status
@@ -156,31 +156,37 @@ func MakeCompute(computeShaderPath string) (Shader, error) {
 		log := strings.Repeat("\x00", int(logLength+1))
 		gl.GetProgramInfoLog(program, logLength, nil, gl.Str(log))
 
-		return Shader{}, fmt.Errorf("failed to link program: %v", log)
+		deleteShaders()
+		gl.DeleteProgram(program)
+		return 0, nil, fmt.Errorf("failed to link program: %v", log)
 	}
 
 	// cleanup shader objects
-	gl.DetachShader(program, computeShader)
-	gl.DeleteShader(computeShader)
+	for _, shader := range shaders {
+		gl.DetachShader(program, shader)
+	}
+	deleteShaders()
 
-	shaderProgram := Shader{program, nil}
-	return shaderProgram, nil
+	return program, files, nil
 }
 
 // AddRenderable adds a Rendereable to the slices of Renderables that should be rendered.
 func (shader *Shader) AddRenderable(renderable Renderable) {
+	shader.init()
 	renderable.Build(shader.programHandle)
 	shader.renderables = append(shader.renderables, renderable)
 }
 
 // RemoveAllRenderables removes all Renderables.
-func (Shader *Shader) RemoveAllRenderables() {
+func (shader *Shader) RemoveAllRenderables() {
 	// TODO: should renderables be deleted?
-	Shader.renderables = nil
+	shader.init()
+	shader.renderables = nil
 }
 
 // Render draws all Renderables that had been added to this Shader.
 func (shader *Shader) Render() {
+	shader.init()
 	for _, renderable := range shader.renderables {
 		renderable.Render()
 	}
@@ -188,6 +194,7 @@ func (shader *Shader) Render() {
 
 // RenderInstances draws all Renderables each multiple times defined by instancecount.
 func (shader *Shader) RenderInstanced(instancecount int32) {
+	shader.init()
 	for _, renderable := range shader.renderables {
 		renderable.RenderInstanced(instancecount)
 	}
@@ -202,6 +209,7 @@ func (shader *Shader) Compute(numgroupsx, numgroupsy, numgroupsz uint32) {
 
 // Use binds the shader for rendering. Call it before calling Render.
 func (shader *Shader) Use() {
+	shader.init()
 	gl.UseProgram(shader.programHandle)
 }
 
@@ -210,62 +218,97 @@ func (shader *Shader) Release() {
 	gl.UseProgram(0)
 }
 
-// Delete deletes the OpenGL Shader handle.
+// Delete deletes the OpenGL Shader handle. Watchers stop watching the shader.
 func (shader *Shader) Delete() {
+	shader.init()
 	gl.DeleteProgram(shader.programHandle)
+	shader.programHandle = 0
 	shader.renderables = nil
 }
 
-// UpdateInt32 updates the value of an 32bit int in the shader.
-func (shader *Shader) UpdateInt32(uniformName string, i32 int32) {
-	location := gl.GetUniformLocation(shader.programHandle, gl.Str(uniformName+"\x00"))
-	if location != -1 {
-		gl.Uniform1i(location, i32)
+// Reload recompiles all shader files and replaces the program if compilation
+// succeeds. Otherwise the previous program is kept and the error is returned.
+// All Renderables are rebuilt for the new program and the uniform values that
+// had been set with the Update functions are set again.
+func (shader *Shader) Reload() error {
+	shader.init()
+	if len(shader.stages) == 0 {
+		return fmt.Errorf("shader %v has no shader files to reload", shader.getName())
 	}
+	handle, files, err := makeProgram(&shader.preprocessor, shader.stages)
+	if err != nil {
+		// remember the modification times to not report the same error again
+		files = nil
+		for path := range shader.files {
+			files = append(files, path)
+		}
+		shader.watchFiles(files)
+		return err
+	}
+
+	old := shader.programHandle
+	gl.DeleteProgram(old)
+	shader.programHandle = handle
+	shader.reflection = reflectProgram(handle)
+	shader.watchFiles(files)
+	for name, binding := range shader.blockBindings {
+		shader.BindUniformBlock(name, binding)
+	}
+	for _, renderable := range shader.renderables {
+		renderable.Build(handle)
+	}
+	shader.restoreValues(old)
+
+	return nil
 }
 
-// UpdateInt32 updates the value of an 32bit float in the shader.
-func (shader *Shader) UpdateFloat32(uniformName string, f32 float32) {
-	location := gl.GetUniformLocation(shader.programHandle, gl.Str(uniformName+"\x00"))
-	if location != -1 {
-		gl.Uniform1f(location, f32)
+// HasChanged returns true if any of the shader files, including all included
+// files, has been modified since the shader had been compiled.
+func (shader *Shader) HasChanged() bool {
+	shader.init()
+	for path, modTime := range shader.files {
+		if !getModTime(path).Equal(modTime) {
+			return true
+		}
 	}
+	return false
 }
 
-// UpdateInt32 updates the value of an vec2 in the shader.
-func (shader *Shader) UpdateVec2(uniformName string, vec2 mgl32.Vec2) {
-	location := gl.GetUniformLocation(shader.programHandle, gl.Str(uniformName+"\x00"))
-	if location != -1 {
-		gl.Uniform2fv(location, 1, &vec2[0])
+// GetFiles returns the paths of all shader files, including all included files.
+func (shader *Shader) GetFiles() []string {
+	shader.init()
+	var files []string
+	for path := range shader.files {
+		files = append(files, path)
 	}
+	return files
 }
 
-// UpdateInt32 updates the value of an vec3 in the shader.
-func (shader *Shader) UpdateVec3(uniformName string, vec3 mgl32.Vec3) {
-	location := gl.GetUniformLocation(shader.programHandle, gl.Str(uniformName+"\x00"))
-	if location != -1 {
-		gl.Uniform3fv(location, 1, &vec3[0])
+// watchFiles records the current modification times of the specified files.
+func (shader *Shader) watchFiles(files []string) {
+	shader.init()
+	shader.files = make(map[string]time.Time)
+	for _, path := range files {
+		shader.files[path] = getModTime(path)
 	}
 }
 
-// UpdateInt32 updates the value of an mat4 in the shader.
-func (shader *Shader) UpdateMat4(uniformName string, mat mgl32.Mat4) {
-	location := gl.GetUniformLocation(shader.programHandle, gl.Str(uniformName+"\x00"))
-	if location != -1 {
-		gl.UniformMatrix4fv(location, 1, false, &mat[0])
+// getModTime returns the modification time of the file at the specified path
+// or the zero time if the file can't be accessed.
+func getModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
 	}
+	return info.ModTime()
 }
 
 // Returns a handle to the Shader.
 func (shader *Shader) GetHandle() uint32 {
+	shader.init()
 	return shader.programHandle
 }
 
-// loadFile returns the contents of a file as a zero terminated string.
-func loadFile(filepath string) (string, error) {
-	return LoadFileWithIncludes(filepath)
-}
-
 func loadFileOld(filepath string) (string, error) {
 	bytes, err := ioutil.ReadFile(filepath)
 
@@ -277,36 +320,38 @@ func loadFileOld(filepath string) (string, error) {
 	return string(bytes), nil
 }
 
-// compileShader compiles a shader with the specified shaderType.
-func compileShader(source string, shaderType uint32) (uint32, error) {
-	shader := gl.CreateShader(shaderType)
+// compileShader compiles the preprocessed source of the specified stage.
+// If compilation fails a *CompileError with the messages of the info log
+// mapped back to the original files is returned.
+func compileShader(source *Source, stage stage) (uint32, error) {
+	shader := gl.CreateShader(stage.shaderType)
 
-	csources, free := gl.Strs(source)
+	csources, free := gl.Strs(source.GetCode() + "\x00")
 	gl.ShaderSource(shader, 1, csources, nil)
 	gl.CompileShader(shader)
 	free()
 
-	err := getGLError(shader, gl.COMPILE_STATUS)
-	if err != nil {
+	log, ok := getGLError(shader, gl.COMPILE_STATUS)
+	if !ok {
 		gl.DeleteShader(shader)
-		return 0, fmt.Errorf("failed to compile\n'%v'\n%v", source, err)
+		return 0, MakeCompileError(getStageName(stage.shaderType), stage.path, source, log)
 	}
 
 	return shader, nil
 }
 
 // getGLError checks for an error during shader compilation.
-// If an error has been occured it will return this error with a human readable error message.
-func getGLError(shader uint32, statusType int) error {
+// If an error has been occured it returns false along with the info log of the shader.
+func getGLError(shader uint32, statusType uint32) (string, bool) {
 	var status int32
-	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	gl.GetShaderiv(shader, statusType, &status)
 	if status == gl.FALSE {
 		var logLength int32
 		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
 		log := strings.Repeat("\x00", int(logLength))
 		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
 
-		return fmt.Errorf(log)
+		return strings.TrimRight(log, "\x00"), false
 	}
-	return nil
+	return "", true
 }
@@ -0,0 +1,514 @@
+package shader
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// Uniform is an active uniform of a linked program. Size is the number of
+// elements for arrays and 1 otherwise.
+type Uniform struct {
+	Name     string
+	Location int32
+	Type     uint32
+	Size     int32
+}
+
+// UniformBlock is an active uniform block of a linked program.
+type UniformBlock struct {
+	Name    string
+	Index   uint32
+	Binding uint32
+	Size    int32
+}
+
+// StorageBlock is an active shader storage block of a linked program.
+type StorageBlock struct {
+	Name    string
+	Index   uint32
+	Binding uint32
+	Size    int32
+}
+
+// reflection is the introspected interface of a linked program.
+// elementLocations holds the location of each element of array uniforms by
+// the name of the array without the [0] suffix, since the driver doesn't
+// have to assign consecutive locations to the elements.
+type reflection struct {
+	uniforms         map[string]Uniform
+	elementLocations map[string][]int32
+	uniformBlocks    []UniformBlock
+	storageBlocks    []StorageBlock
+}
+
+// reflectProgram queries the active uniforms, uniform blocks and shader
+// storage blocks of the linked program. Uniforms of arrays can be found by
+// their name with and without the [0] suffix. Members of blocks are not
+// included since they can't be set with a location.
+func reflectProgram(handle uint32) reflection {
+	result := reflection{
+		uniforms:         make(map[string]Uniform),
+		elementLocations: make(map[string][]int32),
+	}
+
+	// uniforms
+	var count, maxLength int32
+	gl.GetProgramiv(handle, gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(handle, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+	buffer := make([]uint8, maxLength+1)
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		gl.GetActiveUniform(handle, uint32(i), int32(len(buffer)), &length, &size, &xtype, &buffer[0])
+		name := string(buffer[:length])
+
+		location := gl.GetUniformLocation(handle, gl.Str(name+"\x00"))
+		if location == -1 {
+			continue
+		}
+
+		uniform := Uniform{Name: name, Location: location, Type: xtype, Size: size}
+		result.uniforms[name] = uniform
+		if strings.HasSuffix(name, "[0]") {
+			array := strings.TrimSuffix(name, "[0]")
+			result.uniforms[array] = uniform
+
+			locations := make([]int32, size)
+			for idx := range locations {
+				locations[idx] = gl.GetUniformLocation(handle, gl.Str(fmt.Sprintf("%v[%v]\x00", array, idx)))
+			}
+			result.elementLocations[array] = locations
+		}
+	}
+
+	// uniform blocks
+	gl.GetProgramiv(handle, gl.ACTIVE_UNIFORM_BLOCKS, &count)
+	gl.GetProgramiv(handle, gl.ACTIVE_UNIFORM_BLOCK_MAX_NAME_LENGTH, &maxLength)
+	buffer = make([]uint8, maxLength+1)
+	for i := uint32(0); i < uint32(count); i++ {
+		var length, binding, size int32
+		gl.GetActiveUniformBlockName(handle, i, int32(len(buffer)), &length, &buffer[0])
+		gl.GetActiveUniformBlockiv(handle, i, gl.UNIFORM_BLOCK_BINDING, &binding)
+		gl.GetActiveUniformBlockiv(handle, i, gl.UNIFORM_BLOCK_DATA_SIZE, &size)
+		result.uniformBlocks = append(result.uniformBlocks, UniformBlock{
+			Name:    string(buffer[:length]),
+			Index:   i,
+			Binding: uint32(binding),
+			Size:    size,
+		})
+	}
+
+	// shader storage blocks
+	gl.GetProgramInterfaceiv(handle, gl.SHADER_STORAGE_BLOCK, gl.ACTIVE_RESOURCES, &count)
+	gl.GetProgramInterfaceiv(handle, gl.SHADER_STORAGE_BLOCK, gl.MAX_NAME_LENGTH, &maxLength)
+	buffer = make([]uint8, maxLength+1)
+	props := []uint32{gl.BUFFER_BINDING, gl.BUFFER_DATA_SIZE}
+	for i := uint32(0); i < uint32(count); i++ {
+		var length int32
+		params := make([]int32, len(props))
+		gl.GetProgramResourceName(handle, gl.SHADER_STORAGE_BLOCK, i, int32(len(buffer)), &length, &buffer[0])
+		gl.GetProgramResourceiv(handle, gl.SHADER_STORAGE_BLOCK, i, int32(len(props)), &props[0], int32(len(params)), nil, &params[0])
+		result.storageBlocks = append(result.storageBlocks, StorageBlock{
+			Name:    string(buffer[:length]),
+			Index:   i,
+			Binding: uint32(params[0]),
+			Size:    params[1],
+		})
+	}
+
+	return result
+}
+
+// SetStrict enables or disables the strict mode. In strict mode the Update
+// functions return an error if the uniform isn't active or if its type doesn't
+// match the value. Note that the driver removes uniforms that aren't used by
+// the shader. Otherwise such updates are silently ignored.
+func (shader *Shader) SetStrict(strict bool) {
+	shader.init()
+	shader.strict = strict
+}
+
+// IsStrict returns true if the strict mode is enabled.
+func (shader *Shader) IsStrict() bool {
+	shader.init()
+	return shader.strict
+}
+
+// GetUniform returns the active uniform with the specified name. Elements of
+// arrays can be accessed by their index like "lights[2]".
+func (shader *Shader) GetUniform(uniformName string) (Uniform, bool) {
+	shader.init()
+	if uniform, ok := shader.uniforms[uniformName]; ok {
+		return uniform, true
+	}
+
+	// look up the location of array elements, the element keeps the type of
+	// the array and the number of elements up to the end of the array
+	open := strings.LastIndex(uniformName, "[")
+	if open < 0 || !strings.HasSuffix(uniformName, "]") {
+		return Uniform{}, false
+	}
+	idx, err := strconv.Atoi(uniformName[open+1 : len(uniformName)-1])
+	if err != nil || idx < 0 {
+		return Uniform{}, false
+	}
+	uniform, ok := shader.uniforms[uniformName[:open]+"[0]"]
+	locations := shader.elementLocations[uniformName[:open]]
+	if !ok || idx >= len(locations) || locations[idx] == -1 {
+		return Uniform{}, false
+	}
+	uniform.Name = uniformName
+	uniform.Location = locations[idx]
+	uniform.Size -= int32(idx)
+	return uniform, true
+}
+
+// GetUniforms returns all active uniforms sorted by name.
+func (shader *Shader) GetUniforms() []Uniform {
+	shader.init()
+	var uniforms []Uniform
+	for name, uniform := range shader.uniforms {
+		// arrays are registered twice
+		if name == uniform.Name {
+			uniforms = append(uniforms, uniform)
+		}
+	}
+	sort.Slice(uniforms, func(i, j int) bool { return uniforms[i].Name < uniforms[j].Name })
+	return uniforms
+}
+
+// GetUniformBlock returns the active uniform block with the specified name.
+func (shader *Shader) GetUniformBlock(blockName string) (UniformBlock, bool) {
+	shader.init()
+	for _, block := range shader.uniformBlocks {
+		if block.Name == blockName {
+			return block, true
+		}
+	}
+	return UniformBlock{}, false
+}
+
+// BindUniformBlock connects the uniform block with the specified name to the
+// uniform buffer binding point. The binding is restored when the shader is reloaded.
+// It returns an error if the block isn't active.
+func (shader *Shader) BindUniformBlock(blockName string, binding uint32) error {
+	shader.init()
+	if shader.blockBindings == nil {
+		shader.blockBindings = make(map[string]uint32)
+	}
+	shader.blockBindings[blockName] = binding
+
+	for i, block := range shader.uniformBlocks {
+		if block.Name == blockName {
+			gl.UniformBlockBinding(shader.programHandle, block.Index, binding)
+			shader.uniformBlocks[i].Binding = binding
+			return nil
+		}
+	}
+	return fmt.Errorf("uniform block %v is not active in shader %v", blockName, shader.getName())
+}
+
+// GetUniformBlocks returns all active uniform blocks.
+func (shader *Shader) GetUniformBlocks() []UniformBlock {
+	shader.init()
+	return shader.uniformBlocks
+}
+
+// GetStorageBlock returns the active shader storage block with the specified name.
+func (shader *Shader) GetStorageBlock(blockName string) (StorageBlock, bool) {
+	shader.init()
+	for _, block := range shader.storageBlocks {
+		if block.Name == blockName {
+			return block, true
+		}
+	}
+	return StorageBlock{}, false
+}
+
+// GetStorageBlocks returns all active shader storage blocks.
+func (shader *Shader) GetStorageBlocks() []StorageBlock {
+	shader.init()
+	return shader.storageBlocks
+}
+
+// types of uniforms that can be set with the respective Update function
+var (
+	intTypes     = []uint32{gl.INT, gl.BOOL, gl.SAMPLER_1D, gl.SAMPLER_2D, gl.SAMPLER_3D, gl.SAMPLER_CUBE, gl.SAMPLER_2D_SHADOW, gl.SAMPLER_2D_ARRAY, gl.SAMPLER_2D_MULTISAMPLE, gl.IMAGE_1D, gl.IMAGE_2D, gl.IMAGE_3D}
+	samplerTypes = intTypes[2:]
+	boolTypes    = []uint32{gl.BOOL}
+	uintTypes    = []uint32{gl.UNSIGNED_INT, gl.BOOL}
+	floatTypes   = []uint32{gl.FLOAT, gl.BOOL}
+	vec2Types    = []uint32{gl.FLOAT_VEC2, gl.BOOL_VEC2}
+	vec3Types    = []uint32{gl.FLOAT_VEC3, gl.BOOL_VEC3}
+	vec4Types    = []uint32{gl.FLOAT_VEC4, gl.BOOL_VEC4}
+	mat3Types    = []uint32{gl.FLOAT_MAT3}
+	mat4Types    = []uint32{gl.FLOAT_MAT4}
+)
+
+// uniformValue is a value that had been set with an Update function, which is
+// set again when the shader is reloaded. The Update functions of arrays copy
+// their values, since the caller may change them in the meantime.
+type uniformValue struct {
+	types []uint32
+	count int
+	set   func(location int32, count int32)
+}
+
+// update sets the uniform like apply and remembers the value to set it again
+// when the shader is reloaded.
+func (shader *Shader) update(uniformName string, types []uint32, count int, set func(location int32, count int32)) error {
+	if err := shader.apply(uniformName, types, count, set); err != nil {
+		return err
+	}
+	if shader.values == nil {
+		shader.values = make(map[string]uniformValue)
+	}
+	shader.values[uniformName] = uniformValue{types, count, set}
+	return nil
+}
+
+// restoreValues sets all remembered uniform values on the current program of
+// the shader. The previously bound program is bound again afterwards, or the
+// current program if the previous one was the replaced program old.
+func (shader *Shader) restoreValues(old uint32) {
+	if len(shader.values) == 0 {
+		return
+	}
+
+	var previous int32
+	gl.GetIntegerv(gl.CURRENT_PROGRAM, &previous)
+	gl.UseProgram(shader.programHandle)
+	for name, value := range shader.values {
+		// uniforms that are no longer active are skipped even in strict mode
+		shader.apply(name, value.types, value.count, value.set)
+	}
+	if uint32(previous) == old {
+		previous = int32(shader.programHandle)
+	}
+	gl.UseProgram(uint32(previous))
+}
+
+// apply looks up the uniform and calls set with its location if it has one
+// of the specified types and at least count elements.
+func (shader *Shader) apply(uniformName string, types []uint32, count int, set func(location int32, count int32)) error {
+	uniform, ok := shader.GetUniform(uniformName)
+	if !ok {
+		if shader.strict {
+			return fmt.Errorf("uniform %v is not active in shader %v", uniformName, shader.getName())
+		}
+		return nil
+	}
+
+	matches := false
+	for _, xtype := range types {
+		matches = matches || uniform.Type == xtype
+	}
+	if !matches {
+		if shader.strict {
+			return fmt.Errorf("uniform %v of shader %v has type %v, cannot set it to %v",
+				uniformName, shader.getName(), getTypeName(uniform.Type), getTypeName(types[0]))
+		}
+		return nil
+	}
+
+	if int32(count) > uniform.Size {
+		if shader.strict {
+			return fmt.Errorf("uniform %v of shader %v has %v elements, cannot set %v",
+				uniformName, shader.getName(), uniform.Size, count)
+		}
+		count = int(uniform.Size)
+	}
+	if count > 0 {
+		set(uniform.Location, int32(count))
+	}
+	return nil
+}
+
+// getName returns the path of the first shader file to identify the shader in errors.
+func (shader *Shader) getName() string {
+	if len(shader.stages) == 0 {
+		return fmt.Sprint(shader.programHandle)
+	}
+	return shader.stages[0].path
+}
+
+// getTypeName returns the GLSL name of the uniform type.
+func getTypeName(xtype uint32) string {
+	switch xtype {
+	case gl.BOOL:
+		return "bool"
+	case gl.INT:
+		return "int"
+	case gl.UNSIGNED_INT:
+		return "uint"
+	case gl.FLOAT:
+		return "float"
+	case gl.FLOAT_VEC2:
+		return "vec2"
+	case gl.FLOAT_VEC3:
+		return "vec3"
+	case gl.FLOAT_VEC4:
+		return "vec4"
+	case gl.INT_VEC2:
+		return "ivec2"
+	case gl.INT_VEC3:
+		return "ivec3"
+	case gl.INT_VEC4:
+		return "ivec4"
+	case gl.BOOL_VEC2:
+		return "bvec2"
+	case gl.BOOL_VEC3:
+		return "bvec3"
+	case gl.BOOL_VEC4:
+		return "bvec4"
+	case gl.FLOAT_MAT3:
+		return "mat3"
+	case gl.FLOAT_MAT4:
+		return "mat4"
+	case gl.SAMPLER_1D:
+		return "sampler1D"
+	case gl.SAMPLER_2D:
+		return "sampler2D"
+	case gl.SAMPLER_3D:
+		return "sampler3D"
+	case gl.SAMPLER_CUBE:
+		return "samplerCube"
+	case gl.SAMPLER_2D_SHADOW:
+		return "sampler2DShadow"
+	case gl.SAMPLER_2D_ARRAY:
+		return "sampler2DArray"
+	case gl.SAMPLER_2D_MULTISAMPLE:
+		return "sampler2DMS"
+	case gl.IMAGE_1D:
+		return "image1D"
+	case gl.IMAGE_2D:
+		return "image2D"
+	case gl.IMAGE_3D:
+		return "image3D"
+	}
+	return fmt.Sprintf("type 0x%x", xtype)
+}
+
+// UpdateBool updates the value of a bool in the shader.
+func (shader *Shader) UpdateBool(uniformName string, b bool) error {
+	var i32 int32
+	if b {
+		i32 = 1
+	}
+	return shader.update(uniformName, boolTypes, 1, func(location, count int32) {
+		gl.Uniform1i(location, i32)
+	})
+}
+
+// UpdateInt32 updates the value of an 32bit int, a bool or a sampler in the shader.
+func (shader *Shader) UpdateInt32(uniformName string, i32 int32) error {
+	return shader.update(uniformName, intTypes, 1, func(location, count int32) {
+		gl.Uniform1i(location, i32)
+	})
+}
+
+// UpdateUint32 updates the value of an 32bit unsigned int in the shader.
+func (shader *Shader) UpdateUint32(uniformName string, u32 uint32) error {
+	return shader.update(uniformName, uintTypes, 1, func(location, count int32) {
+		gl.Uniform1ui(location, u32)
+	})
+}
+
+// UpdateFloat32 updates the value of an 32bit float in the shader.
+func (shader *Shader) UpdateFloat32(uniformName string, f32 float32) error {
+	return shader.update(uniformName, floatTypes, 1, func(location, count int32) {
+		gl.Uniform1f(location, f32)
+	})
+}
+
+// UpdateVec2 updates the value of a vec2 in the shader.
+func (shader *Shader) UpdateVec2(uniformName string, vec2 mgl32.Vec2) error {
+	return shader.update(uniformName, vec2Types, 1, func(location, count int32) {
+		gl.Uniform2fv(location, 1, &vec2[0])
+	})
+}
+
+// UpdateVec3 updates the value of a vec3 in the shader.
+func (shader *Shader) UpdateVec3(uniformName string, vec3 mgl32.Vec3) error {
+	return shader.update(uniformName, vec3Types, 1, func(location, count int32) {
+		gl.Uniform3fv(location, 1, &vec3[0])
+	})
+}
+
+// UpdateVec4 updates the value of a vec4 in the shader.
+func (shader *Shader) UpdateVec4(uniformName string, vec4 mgl32.Vec4) error {
+	return shader.update(uniformName, vec4Types, 1, func(location, count int32) {
+		gl.Uniform4fv(location, 1, &vec4[0])
+	})
+}
+
+// UpdateMat3 updates the value of a mat3 in the shader.
+func (shader *Shader) UpdateMat3(uniformName string, mat mgl32.Mat3) error {
+	return shader.update(uniformName, mat3Types, 1, func(location, count int32) {
+		gl.UniformMatrix3fv(location, 1, false, &mat[0])
+	})
+}
+
+// UpdateMat4 updates the value of a mat4 in the shader.
+func (shader *Shader) UpdateMat4(uniformName string, mat mgl32.Mat4) error {
+	return shader.update(uniformName, mat4Types, 1, func(location, count int32) {
+		gl.UniformMatrix4fv(location, 1, false, &mat[0])
+	})
+}
+
+// UpdateSampler sets the texture unit of a sampler or the image unit of an image in the shader.
+func (shader *Shader) UpdateSampler(uniformName string, unit int32) error {
+	return shader.update(uniformName, samplerTypes, 1, func(location, count int32) {
+		gl.Uniform1i(location, unit)
+	})
+}
+
+// UpdateInt32Array updates the elements of an int array in the shader
+// starting with the element specified by uniformName, e.g. "values[2]".
+func (shader *Shader) UpdateInt32Array(uniformName string, values []int32) error {
+	values = append([]int32(nil), values...)
+	return shader.update(uniformName, intTypes, len(values), func(location, count int32) {
+		gl.Uniform1iv(location, count, &values[0])
+	})
+}
+
+// UpdateFloat32Array updates the elements of a float array in the shader
+// starting with the element specified by uniformName, e.g. "values[2]".
+func (shader *Shader) UpdateFloat32Array(uniformName string, values []float32) error {
+	values = append([]float32(nil), values...)
+	return shader.update(uniformName, floatTypes, len(values), func(location, count int32) {
+		gl.Uniform1fv(location, count, &values[0])
+	})
+}
+
+// UpdateVec3Array updates the elements of a vec3 array in the shader
+// starting with the element specified by uniformName, e.g. "values[2]".
+func (shader *Shader) UpdateVec3Array(uniformName string, values []mgl32.Vec3) error {
+	values = append([]mgl32.Vec3(nil), values...)
+	return shader.update(uniformName, vec3Types, len(values), func(location, count int32) {
+		gl.Uniform3fv(location, count, &values[0][0])
+	})
+}
+
+// UpdateVec4Array updates the elements of a vec4 array in the shader
+// starting with the element specified by uniformName, e.g. "values[2]".
+func (shader *Shader) UpdateVec4Array(uniformName string, values []mgl32.Vec4) error {
+	values = append([]mgl32.Vec4(nil), values...)
+	return shader.update(uniformName, vec4Types, len(values), func(location, count int32) {
+		gl.Uniform4fv(location, count, &values[0][0])
+	})
+}
+
+// UpdateMat4Array updates the elements of a mat4 array in the shader
+// starting with the element specified by uniformName, e.g. "values[2]".
+func (shader *Shader) UpdateMat4Array(uniformName string, values []mgl32.Mat4) error {
+	values = append([]mgl32.Mat4(nil), values...)
+	return shader.update(uniformName, mat4Types, len(values), func(location, count int32) {
+		gl.UniformMatrix4fv(location, count, false, &values[0][0])
+	})
+}
@@ -0,0 +1,120 @@
+package shader
+
+import (
+	"testing"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// makeReflectedShader constructs a shader with a float "scale", a sampler
+// "tex", a float array "vals" of 3 elements whose locations aren't
+// consecutive and a struct array member "lights[1].color" without a program.
+func makeReflectedShader() Shader {
+	vals := Uniform{Name: "vals[0]", Location: 10, Type: gl.FLOAT, Size: 3}
+	return Shader{&program{
+		stages: []stage{{gl.FRAGMENT_SHADER, "test.frag"}},
+		reflection: reflection{
+			uniforms: map[string]Uniform{
+				"scale":           {Name: "scale", Location: 1, Type: gl.FLOAT, Size: 1},
+				"tex":             {Name: "tex", Location: 2, Type: gl.SAMPLER_2D, Size: 1},
+				"vals[0]":         vals,
+				"vals":            vals,
+				"lights[1].color": {Name: "lights[1].color", Location: 5, Type: gl.FLOAT_VEC3, Size: 1},
+			},
+			elementLocations: map[string][]int32{
+				"vals": {10, 20, 35},
+			},
+		},
+	}}
+}
+
+func TestGetUniform(t *testing.T) {
+	shader := makeReflectedShader()
+	tests := []struct {
+		name     string
+		ok       bool
+		location int32
+		size     int32
+	}{
+		{"scale", true, 1, 1},
+		{"vals", true, 10, 3},
+		{"vals[0]", true, 10, 3},
+		{"vals[1]", true, 20, 2},
+		{"vals[2]", true, 35, 1},
+		{"lights[1].color", true, 5, 1},
+		{"vals[3]", false, 0, 0},
+		{"vals[-1]", false, 0, 0},
+		{"vals[x]", false, 0, 0},
+		{"vals[]", false, 0, 0},
+		{"vals[1", false, 0, 0},
+		{"vals1]", false, 0, 0},
+		{"scale[0]", false, 0, 0},
+		{"missing", false, 0, 0},
+	}
+	for _, test := range tests {
+		uniform, ok := shader.GetUniform(test.name)
+		if ok != test.ok {
+			t.Errorf("%v: found %v instead of %v", test.name, ok, test.ok)
+			continue
+		}
+		// the array itself is found as its first element
+		if ok && (uniform.Location != test.location || uniform.Size != test.size || (uniform.Name != test.name && test.name != "vals")) {
+			t.Errorf("%v: got %v at %v with size %v instead of at %v with size %v",
+				test.name, uniform.Name, uniform.Location, uniform.Size, test.location, test.size)
+		}
+	}
+
+	// arrays are listed once
+	uniforms := shader.GetUniforms()
+	if len(uniforms) != 4 || uniforms[0].Name != "lights[1].color" || uniforms[3].Name != "vals[0]" {
+		t.Errorf("uniforms are %v", uniforms)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name     string
+		uniform  string
+		types    []uint32
+		count    int
+		strict   bool
+		err      bool
+		location int32
+		set      int32
+	}{
+		{"float", "scale", floatTypes, 1, true, false, 1, 1},
+		{"sampler", "tex", samplerTypes, 1, true, false, 2, 1},
+		{"int to sampler", "tex", intTypes, 1, true, false, 2, 1},
+		{"array", "vals", floatTypes, 3, true, false, 10, 3},
+		{"array element", "vals[1]", floatTypes, 2, true, false, 20, 2},
+		{"missing", "missing", floatTypes, 1, false, false, 0, 0},
+		{"missing strict", "missing", floatTypes, 1, true, true, 0, 0},
+		{"type mismatch", "scale", vec3Types, 1, false, false, 0, 0},
+		{"type mismatch strict", "tex", floatTypes, 1, true, true, 0, 0},
+		{"too many elements", "vals[1]", floatTypes, 4, false, false, 20, 2},
+		{"too many elements strict", "vals[1]", floatTypes, 4, true, true, 0, 0},
+		{"too many elements of a single value strict", "scale", floatTypes, 2, true, true, 0, 0},
+		{"out of range element", "vals[3]", floatTypes, 1, false, false, 0, 0},
+		{"no elements", "vals", floatTypes, 0, true, false, 0, 0},
+	}
+	for _, test := range tests {
+		shader := makeReflectedShader()
+		shader.SetStrict(test.strict)
+
+		var location, count int32
+		err := shader.update(test.uniform, test.types, test.count, func(l, c int32) {
+			location, count = l, c
+		})
+		if (err != nil) != test.err {
+			t.Errorf("%v: error is %v", test.name, err)
+		}
+		if location != test.location || count != test.set {
+			t.Errorf("%v: set %v elements at %v instead of %v at %v", test.name, count, location, test.set, test.location)
+		}
+
+		// only values without errors are set again after reloading
+		if _, ok := shader.values[test.uniform]; ok == test.err {
+			t.Errorf("%v: value remembered %v", test.name, ok)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package shader
+
+import (
+	"fmt"
+	"time"
+)
+
+// Watcher polls the files of its shaders and reloads shaders whose files changed.
+type Watcher struct {
+	shaders   []Shader
+	interval  time.Duration
+	lastCheck time.Time
+}
+
+// newShaderWatcher is the watcher that shaders are added to on creation,
+// see WatchNewShaders. It is nil unless hot reloading is enabled.
+var newShaderWatcher *Watcher
+
+// MakeWatcher constructs a Watcher that checks the files of its shaders at
+// most once per interval.
+func MakeWatcher(interval time.Duration) Watcher {
+	return Watcher{
+		interval: interval,
+	}
+}
+
+// WatchNewShaders adds all shaders that are created from now on to the
+// watcher. Passing nil stops adding shaders.
+func WatchNewShaders(watcher *Watcher) {
+	newShaderWatcher = watcher
+}
+
+// Add starts watching the specified shader.
+func (watcher *Watcher) Add(shader Shader) {
+	watcher.shaders = append(watcher.shaders, shader)
+}
+
+// Remove stops watching the specified shader.
+func (watcher *Watcher) Remove(shader Shader) {
+	for i, watched := range watcher.shaders {
+		if watched == shader {
+			watcher.shaders = append(watcher.shaders[:i], watcher.shaders[i+1:]...)
+			return
+		}
+	}
+}
+
+// Update reloads all shaders whose files changed since the last check.
+// It has to be called from the thread of the OpenGL context, e.g. once per
+// frame. If a shader fails to compile the error is printed and the shader
+// keeps its previous program. Shaders that had been deleted are removed.
+func (watcher *Watcher) Update() {
+	now := time.Now()
+	if now.Sub(watcher.lastCheck) < watcher.interval {
+		return
+	}
+	watcher.lastCheck = now
+
+	watched := watcher.shaders[:0]
+	for _, shader := range watcher.shaders {
+		if shader.program == nil || shader.programHandle == 0 {
+			continue
+		}
+		watched = append(watched, shader)
+
+		if !shader.HasChanged() {
+			continue
+		}
+		if err := shader.Reload(); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		fmt.Println("Reloaded shader", shader.getName())
+	}
+	watcher.shaders = watched
+}
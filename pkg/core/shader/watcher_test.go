@@ -0,0 +1,131 @@
+package shader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// writeFile writes the content to the file name in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// touch moves the modification time of the file forward by the offset.
+func touch(t *testing.T, path string, offset time.Duration) {
+	modTime := time.Now().Add(offset)
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// makeTestShader constructs a shader whose fragment stage is the file at path
+// without compiling it. Reloading it fails while preprocessing if the file
+// includes a missing file, thus it doesn't need an OpenGL context.
+func makeTestShader(path string) Shader {
+	shader := Shader{&program{
+		programHandle: 1,
+		preprocessor:  MakePreprocessor(),
+		stages:        []stage{{gl.FRAGMENT_SHADER, path}},
+	}}
+	shader.watchFiles([]string{path})
+	return shader
+}
+
+func TestHasChanged(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, dir, "a.glsl", "a")
+	b := writeFile(t, dir, "b.glsl", "b")
+
+	var shader Shader
+	shader.watchFiles([]string{a, b})
+	if shader.HasChanged() {
+		t.Error("unmodified files changed")
+	}
+	if len(shader.GetFiles()) != 2 {
+		t.Errorf("watching %v instead of 2 files", shader.GetFiles())
+	}
+
+	touch(t, b, time.Hour)
+	if !shader.HasChanged() {
+		t.Error("modified file didn't change")
+	}
+	shader.watchFiles([]string{a, b})
+	if shader.HasChanged() {
+		t.Error("files changed after watching them again")
+	}
+
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if !shader.HasChanged() {
+		t.Error("removed file didn't change")
+	}
+}
+
+func TestZeroShader(t *testing.T) {
+	var shader Shader
+	if shader.HasChanged() || len(shader.GetFiles()) != 0 || shader.GetHandle() != 0 {
+		t.Errorf("zero shader has changed %v, files %v and handle %v", shader.HasChanged(), shader.GetFiles(), shader.GetHandle())
+	}
+	if _, ok := shader.GetUniform("color"); ok {
+		t.Error("zero shader has a uniform")
+	}
+	if err := shader.Reload(); err == nil {
+		t.Error("reloading a zero shader: expected an error")
+	}
+	shader.SetStrict(true)
+	if err := shader.UpdateFloat32("color", 1); err == nil {
+		t.Error("updating a uniform of a strict zero shader: expected an error")
+	}
+}
+
+func TestWatcherUpdate(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "main.frag", "#include \"missing.glsl\"\n")
+	shader := makeTestShader(path)
+	deleted := makeTestShader(writeFile(t, dir, "deleted.frag", ""))
+	deleted.programHandle = 0
+
+	watcher := MakeWatcher(time.Hour)
+	watcher.Add(shader)
+	watcher.Add(deleted)
+
+	// a failed reload keeps the program and doesn't report the error again
+	touch(t, path, time.Hour)
+	watcher.Update()
+	if shader.HasChanged() {
+		t.Error("files of the failed reload changed")
+	}
+	if shader.GetHandle() != 1 {
+		t.Errorf("handle is %v instead of 1", shader.GetHandle())
+	}
+	if len(watcher.shaders) != 1 || watcher.shaders[0] != shader {
+		t.Errorf("watching %v shaders instead of 1", len(watcher.shaders))
+	}
+
+	// files are checked at most once per interval
+	touch(t, path, 2*time.Hour)
+	watcher.Update()
+	if !shader.HasChanged() {
+		t.Error("files were checked before the interval elapsed")
+	}
+	watcher.lastCheck = time.Now().Add(-time.Hour)
+	watcher.Update()
+	if shader.HasChanged() {
+		t.Error("files weren't checked after the interval elapsed")
+	}
+
+	watcher.Remove(shader)
+	if len(watcher.shaders) != 0 {
+		t.Errorf("watching %v shaders after removing the last one", len(watcher.shaders))
+	}
+}
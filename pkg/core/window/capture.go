@@ -0,0 +1,407 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/buffer/fbo"
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// captureBuffers is the number of pixel pack buffers of a Capture. Frames are
+// read back into them asynchronously and saved as soon as the GPU finished
+// writing them, which usually is the case one frame later.
+const captureBuffers = 3
+
+// CaptureOptions specify what a Capture reads back and where it is saved.
+type CaptureOptions struct {
+	// Path of the captured files. Its extension selects between png and exr.
+	// Files are numbered, e.g. capture/frame.png results in capture/frame_00000.png,
+	// capture/frame_00001.png etc. Missing directories are created.
+	Path string
+	// Channels is the number of channels of the captured images, 4 if not specified.
+	Channels int
+	// FBO is read instead of the default frame buffer if specified.
+	FBO *fbo.FBO
+	// Attachment is the index of the color attachment of the FBO that is read.
+	Attachment uint32
+	// Width and Height of the captured images, the size of the window if not specified.
+	Width  int
+	Height int
+}
+
+// readback is a pixel pack buffer that the GPU writes one frame into.
+type readback struct {
+	pbo     uint32
+	sync    uintptr
+	pending bool
+	number  int
+}
+
+// Capture saves frames of the default frame buffer or of an FBO attachment to
+// numbered png or exr files. Pixels are read back into pixel pack buffers
+// without waiting for the GPU and images are saved in the background, thus
+// capturing doesn't stall the render loop. Errors don't interrupt rendering,
+// instead the first one is returned by Finish and GetError.
+type Capture struct {
+	window    *Window
+	options   CaptureOptions
+	base      string
+	ext       string
+	format    uint32
+	pixelType uint32
+	size      int
+
+	readbacks [captureBuffers]readback
+	next      int
+	number    int
+
+	screenshot bool
+	recording  bool
+	frames     int
+	// duration of a recording started by RecordFor and the elapsed time of
+	// the clock at which it ends, which is set when capturing its first frame
+	duration time.Duration
+	until    time.Duration
+
+	writes sync.WaitGroup
+	mutex  sync.Mutex
+	err    error
+}
+
+// EnableCapture creates a Capture that reads back frames after rendering.
+// Frames are only captured after calling Screenshot, Record or RecordFor,
+// or by pressing the hotkeys handled in OnKeyPress.
+func (window *Window) EnableCapture(options CaptureOptions) (*Capture, error) {
+	if options.Channels == 0 {
+		options.Channels = 4
+	}
+	if options.Width == 0 && options.Height == 0 {
+		options.Width = window.Width
+		options.Height = window.Height
+	}
+	if options.Width < 1 || options.Height < 1 {
+		return nil, errors.New("Width and height must be bigger than 0.")
+	}
+	format, err := image2d.GetFormat(options.Channels)
+	if err != nil {
+		return nil, err
+	}
+	base, ext, pixelType, err := splitCapturePath(options.Path)
+	if err != nil {
+		return nil, err
+	}
+	componentSize, _ := image2d.GetComponentSize(pixelType)
+
+	capture := Capture{
+		window:    window,
+		options:   options,
+		base:      base,
+		ext:       ext,
+		format:    format,
+		pixelType: pixelType,
+		size:      options.Width * options.Height * options.Channels * componentSize,
+	}
+	var previousPackBuffer int32
+	gl.GetIntegerv(gl.PIXEL_PACK_BUFFER_BINDING, &previousPackBuffer)
+	for i := range capture.readbacks {
+		readback := &capture.readbacks[i]
+		gl.GenBuffers(1, &readback.pbo)
+		gl.BindBuffer(gl.PIXEL_PACK_BUFFER, readback.pbo)
+		gl.BufferData(gl.PIXEL_PACK_BUFFER, capture.size, nil, gl.STREAM_READ)
+	}
+	gl.BindBuffer(gl.PIXEL_PACK_BUFFER, uint32(previousPackBuffer))
+
+	window.captures = append(window.captures, &capture)
+	return &capture, nil
+}
+
+// splitCapturePath splits the path into the part before the frame number and
+// the extension and returns the pixel type of the extension.
+func splitCapturePath(path string) (string, string, uint32, error) {
+	// png files store 8 bit and exr files store floats
+	ext := filepath.Ext(path)
+	var pixelType uint32
+	switch strings.ToLower(ext) {
+	case ".png":
+		pixelType = gl.UNSIGNED_BYTE
+	case ".exr":
+		pixelType = gl.FLOAT
+	default:
+		return "", "", 0, fmt.Errorf("captures can only be saved as .png or .exr but got %v", path)
+	}
+	return strings.TrimSuffix(path, ext), ext, pixelType, nil
+}
+
+// framePath returns the path of the file of the frame with the specified number.
+func (capture *Capture) framePath(number int) string {
+	return fmt.Sprintf("%v_%05d%v", capture.base, number, capture.ext)
+}
+
+// Screenshot captures the next frame.
+func (capture *Capture) Screenshot() {
+	capture.screenshot = true
+}
+
+// Record captures the specified number of frames starting with the next frame.
+// A negative number of frames records until Stop is called.
+func (capture *Capture) Record(frames int) {
+	capture.recording = true
+	capture.frames = frames
+	capture.duration = 0
+}
+
+// RecordFor captures every frame starting with the next frame until the
+// duration passed on the Clock of the window. Thus the duration is measured in
+// the virtual time if the Clock is virtual, is stretched in slow motion and
+// doesn't pass while paused.
+func (capture *Capture) RecordFor(duration time.Duration) {
+	if duration <= 0 {
+		capture.Record(0)
+		return
+	}
+	capture.recording = true
+	capture.frames = -1
+	capture.duration = duration
+	capture.until = 0
+}
+
+// Stop ends the current recording.
+func (capture *Capture) Stop() {
+	capture.recording = false
+}
+
+// IsRecording returns true if frames are currently being recorded.
+func (capture *Capture) IsRecording() bool {
+	return capture.recording
+}
+
+// OnKeyPress takes a screenshot when F12 is pressed and starts or stops
+// recording every frame when F11 is pressed.
+func (capture *Capture) OnKeyPress(key, action, mods int) bool {
+	if glfw.Action(action) != glfw.Press {
+		return false
+	}
+
+	switch glfw.Key(key) {
+	case glfw.KeyF12:
+		capture.Screenshot()
+		return true
+	case glfw.KeyF11:
+		if capture.recording {
+			capture.Stop()
+		} else {
+			capture.Record(-1)
+		}
+		return true
+	}
+	return false
+}
+
+// Finish waits until all captured frames have been saved and returns the
+// first error that occurred while saving.
+func (capture *Capture) Finish() error {
+	for i := 0; i < captureBuffers; i++ {
+		capture.collect(&capture.readbacks[(capture.next+i)%captureBuffers], true)
+	}
+	capture.writes.Wait()
+	return capture.GetError()
+}
+
+// GetError returns the first error that occurred while capturing or saving frames.
+func (capture *Capture) GetError() error {
+	capture.mutex.Lock()
+	defer capture.mutex.Unlock()
+	return capture.err
+}
+
+// Delete saves all captured frames and deletes the pixel pack buffers.
+func (capture *Capture) Delete() {
+	capture.Finish()
+	for i := range capture.readbacks {
+		gl.DeleteBuffers(1, &capture.readbacks[i].pbo)
+	}
+}
+
+// update saves all frames whose read back finished and starts reading back
+// the current frame if it should be captured. The frame is skipped if the
+// captured size exceeds the frame buffer. The bound read frame buffer, the
+// read buffer of the FBO, the pack alignment and the bound pixel pack buffer
+// are restored afterwards.
+func (capture *Capture) update() {
+	for i := range capture.readbacks {
+		capture.collect(&capture.readbacks[i], false)
+	}
+	if !capture.shouldCapture() {
+		return
+	}
+	if err := capture.checkSize(); err != nil {
+		capture.setError(fmt.Errorf("skipped frame %v: %v", capture.number, err))
+		capture.number++
+		return
+	}
+
+	// only waits if the GPU is multiple frames behind
+	readback := &capture.readbacks[capture.next]
+	capture.collect(readback, true)
+	capture.next = (capture.next + 1) % captureBuffers
+
+	// select the buffer to read from
+	var previousFramebuffer, previousReadBuffer int32
+	gl.GetIntegerv(gl.READ_FRAMEBUFFER_BINDING, &previousFramebuffer)
+	if capture.options.FBO != nil {
+		gl.BindFramebuffer(gl.READ_FRAMEBUFFER, capture.options.FBO.GetHandle())
+		gl.GetIntegerv(gl.READ_BUFFER, &previousReadBuffer)
+		gl.ReadBuffer(gl.COLOR_ATTACHMENT0 + capture.options.Attachment)
+	} else {
+		gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+	}
+
+	// the pixels are written into the buffer instead of client memory, thus this returns immediately
+	var previousAlignment, previousPackBuffer int32
+	gl.GetIntegerv(gl.PACK_ALIGNMENT, &previousAlignment)
+	gl.GetIntegerv(gl.PIXEL_PACK_BUFFER_BINDING, &previousPackBuffer)
+	gl.BindBuffer(gl.PIXEL_PACK_BUFFER, readback.pbo)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(capture.options.Width), int32(capture.options.Height), capture.format, capture.pixelType, gl.PtrOffset(0))
+
+	// restore the previous pack and read state
+	gl.PixelStorei(gl.PACK_ALIGNMENT, previousAlignment)
+	gl.BindBuffer(gl.PIXEL_PACK_BUFFER, uint32(previousPackBuffer))
+	if capture.options.FBO != nil {
+		gl.ReadBuffer(uint32(previousReadBuffer))
+	}
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, uint32(previousFramebuffer))
+
+	readback.sync = gl.FenceSync(gl.SYNC_GPU_COMMANDS_COMPLETE, 0)
+	readback.pending = true
+	readback.number = capture.number
+	capture.number++
+}
+
+// checkSize returns an error if the captured size exceeds the size of the FBO
+// attachment or of the default frame buffer, which changes when resizing the window.
+func (capture *Capture) checkSize() error {
+	var width, height int
+	if capture.options.FBO != nil {
+		texture := capture.options.FBO.GetColorTexture(capture.options.Attachment)
+		if texture == nil {
+			return fmt.Errorf("FBO has no color attachment %v", capture.options.Attachment)
+		}
+		width, height = texture.GetSize()
+	} else {
+		width, height = capture.window.GetFramebufferSize()
+	}
+
+	if capture.options.Width > width || capture.options.Height > height {
+		return fmt.Errorf("capture size %vx%v exceeds frame buffer size %vx%v",
+			capture.options.Width, capture.options.Height, width, height)
+	}
+	return nil
+}
+
+// shouldCapture returns true if the current frame should be captured and
+// advances the screenshot or recording.
+func (capture *Capture) shouldCapture() bool {
+	screenshot := capture.screenshot
+	capture.screenshot = false
+	if !capture.recording {
+		return screenshot
+	}
+
+	// the duration starts with the first recorded frame
+	elapsed := capture.window.clock.elapsed
+	if capture.duration > 0 && capture.until == 0 {
+		capture.until = elapsed + capture.duration
+	}
+
+	// end the recording after the specified number of frames or duration
+	if capture.frames == 0 || (capture.duration > 0 && elapsed >= capture.until) {
+		capture.recording = false
+		return screenshot
+	}
+	if capture.frames > 0 {
+		capture.frames--
+	}
+	return true
+}
+
+// collect saves the frame of the readback in the background if the GPU finished
+// writing it. If wait is true it blocks until the GPU is done.
+func (capture *Capture) collect(readback *readback, wait bool) {
+	if !readback.pending {
+		return
+	}
+
+	var timeout uint64
+	if wait {
+		timeout = ^uint64(0)
+	}
+	switch gl.ClientWaitSync(readback.sync, gl.SYNC_FLUSH_COMMANDS_BIT, timeout) {
+	case gl.TIMEOUT_EXPIRED:
+		return
+	case gl.WAIT_FAILED:
+		capture.setError(fmt.Errorf("failed to wait for frame %v: %v", readback.number, gl.GetError()))
+		gl.DeleteSync(readback.sync)
+		readback.pending = false
+		return
+	}
+	gl.DeleteSync(readback.sync)
+	readback.pending = false
+
+	// copy the pixels out of the buffer so that it can be reused
+	data := make([]uint8, capture.size)
+	var previousPackBuffer int32
+	gl.GetIntegerv(gl.PIXEL_PACK_BUFFER_BINDING, &previousPackBuffer)
+	gl.BindBuffer(gl.PIXEL_PACK_BUFFER, readback.pbo)
+	ptr := gl.MapBufferRange(gl.PIXEL_PACK_BUFFER, 0, capture.size, gl.MAP_READ_BIT)
+	if ptr == nil {
+		gl.BindBuffer(gl.PIXEL_PACK_BUFFER, uint32(previousPackBuffer))
+		capture.setError(fmt.Errorf("failed to map frame %v: %v", readback.number, gl.GetError()))
+		return
+	}
+	copy(data, unsafe.Slice((*uint8)(ptr), capture.size))
+	gl.UnmapBuffer(gl.PIXEL_PACK_BUFFER)
+	gl.BindBuffer(gl.PIXEL_PACK_BUFFER, uint32(previousPackBuffer))
+
+	path := capture.framePath(readback.number)
+	capture.writes.Add(1)
+	go func() {
+		defer capture.writes.Done()
+		if err := capture.save(path, data); err != nil {
+			capture.setError(err)
+		}
+	}()
+}
+
+// save writes the pixels to the file at path.
+func (capture *Capture) save(path string, data []uint8) error {
+	img, err := image2d.MakeFromRawData(capture.options.Width, capture.options.Height, capture.options.Channels, capture.pixelType, data)
+	if err != nil {
+		return err
+	}
+	// OpenGL stores the bottom row first
+	img.FlipY()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return img.SaveToPath(path)
+}
+
+// setError remembers the error if it is the first one.
+func (capture *Capture) setError(err error) {
+	capture.mutex.Lock()
+	defer capture.mutex.Unlock()
+	if capture.err == nil {
+		capture.err = err
+	}
+}
@@ -0,0 +1,117 @@
+package window
+
+import (
+	"testing"
+	"time"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// countCaptures advances the clock of the capture by the specified number of
+// frames and returns the number of frames that are captured.
+func countCaptures(capture *Capture, frames int) int {
+	count := 0
+	for i := 0; i < frames; i++ {
+		capture.window.clock.tick(time.Time{})
+		if capture.shouldCapture() {
+			count++
+		}
+	}
+	return count
+}
+
+func TestShouldCapture(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     func(capture *Capture)
+		frames    int
+		captured  int
+		recording bool
+	}{
+		{"nothing", func(capture *Capture) {}, 10, 0, false},
+		{"screenshot", func(capture *Capture) { capture.Screenshot() }, 10, 1, false},
+		{"record", func(capture *Capture) { capture.Record(4) }, 10, 4, false},
+		{"record none", func(capture *Capture) { capture.Record(0) }, 10, 0, false},
+		{"record until stopped", func(capture *Capture) { capture.Record(-1) }, 10, 10, true},
+		{"screenshot while recording", func(capture *Capture) { capture.Record(2); capture.Screenshot() }, 10, 2, false},
+		// the virtual clock advances 100ms per frame
+		{"record for", func(capture *Capture) { capture.RecordFor(500 * time.Millisecond) }, 10, 5, false},
+		{"record for none", func(capture *Capture) { capture.RecordFor(0) }, 10, 0, false},
+		{"record for in slow motion", func(capture *Capture) {
+			capture.window.clock.SetScale(0.5)
+			capture.RecordFor(500 * time.Millisecond)
+		}, 20, 10, false},
+	}
+
+	for _, test := range tests {
+		capture := Capture{window: &Window{clock: MakeClock()}}
+		capture.window.clock.SetVirtual(100 * time.Millisecond)
+		// the first frame of the clock has no delta
+		countCaptures(&capture, 3)
+
+		test.start(&capture)
+		if captured := countCaptures(&capture, test.frames); captured != test.captured {
+			t.Errorf("%v: captured %v frames instead of %v", test.name, captured, test.captured)
+		}
+		if capture.IsRecording() != test.recording {
+			t.Errorf("%v: recording is %v instead of %v", test.name, capture.IsRecording(), test.recording)
+		}
+	}
+}
+
+func TestRecordStop(t *testing.T) {
+	capture := Capture{window: &Window{clock: MakeClock()}}
+	capture.window.clock.SetVirtual(100 * time.Millisecond)
+	capture.RecordFor(time.Second)
+	if captured := countCaptures(&capture, 3); captured != 3 {
+		t.Errorf("captured %v frames instead of 3", captured)
+	}
+
+	// a paused clock doesn't end the recording
+	capture.window.clock.Pause()
+	if captured := countCaptures(&capture, 20); captured != 20 {
+		t.Errorf("captured %v frames while paused instead of 20", captured)
+	}
+	capture.window.clock.Resume()
+
+	capture.Stop()
+	if captured := countCaptures(&capture, 3); captured != 0 || capture.IsRecording() {
+		t.Errorf("captured %v frames after stopping", captured)
+	}
+}
+
+func TestCapturePath(t *testing.T) {
+	tests := []struct {
+		path      string
+		number    int
+		pixelType uint32
+		frame     string
+	}{
+		{"frame.png", 0, gl.UNSIGNED_BYTE, "frame_00000.png"},
+		{"frame.png", 42, gl.UNSIGNED_BYTE, "frame_00042.png"},
+		{"capture/frame.exr", 7, gl.FLOAT, "capture/frame_00007.exr"},
+		{"capture/v1.2/Frame.PNG", 7, gl.UNSIGNED_BYTE, "capture/v1.2/Frame_00007.PNG"},
+		// numbers with more digits aren't cut off
+		{"frame.png", 123456, gl.UNSIGNED_BYTE, "frame_123456.png"},
+	}
+	for _, test := range tests {
+		base, ext, pixelType, err := splitCapturePath(test.path)
+		if err != nil {
+			t.Errorf("%v: %v", test.path, err)
+			continue
+		}
+		if pixelType != test.pixelType {
+			t.Errorf("%v: pixel type is %v instead of %v", test.path, pixelType, test.pixelType)
+		}
+		capture := Capture{base: base, ext: ext}
+		if frame := capture.framePath(test.number); frame != test.frame {
+			t.Errorf("%v: frame %v is saved to %v instead of %v", test.path, test.number, frame, test.frame)
+		}
+	}
+
+	for _, path := range []string{"frame", "frame.jpg", "frame.png/"} {
+		if _, _, _, err := splitCapturePath(path); err == nil {
+			t.Errorf("%v: expected an error", path)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package window
+
+import (
+	"time"
+
+	"github.com/go-gl/glfw/v3.2/glfw"
+)
+
+// maxFixedSteps limits the number of fixed steps per frame, such that slow
+// frames don't result in even slower frames due to more and more updates.
+const maxFixedSteps = 8
+
+// Clock measures the time of the frames of the main loop. Its time can be
+// paused and scaled for slow motion. In virtual mode every frame advances the
+// time by the same amount independent of how long rendering took, which makes
+// offline rendering deterministic.
+type Clock struct {
+	frames  int
+	last    time.Time
+	delta   time.Duration
+	elapsed time.Duration
+
+	scale    float64
+	paused   bool
+	stepping bool
+
+	virtual   bool
+	frameTime time.Duration
+
+	fixedStep   time.Duration
+	accumulator time.Duration
+}
+
+// MakeClock constructs a Clock that measures the real time with a fixed step of 1/60 seconds.
+func MakeClock() Clock {
+	return Clock{
+		scale:     1.0,
+		fixedStep: time.Second / 60,
+	}
+}
+
+// GetDelta returns the time in seconds that passed since the previous frame.
+// It is 0 for the first frame and while paused and is scaled in slow motion.
+func (clock *Clock) GetDelta() float64 {
+	return clock.delta.Seconds()
+}
+
+// GetElapsed returns the time in seconds that passed since the first frame
+// excluding the time the Clock had been paused.
+func (clock *Clock) GetElapsed() float64 {
+	return clock.elapsed.Seconds()
+}
+
+// Pause stops the time until Resume is called.
+func (clock *Clock) Pause() {
+	clock.paused = true
+}
+
+// Resume continues the time after Pause.
+func (clock *Clock) Resume() {
+	clock.paused = false
+}
+
+// IsPaused returns true if the time is paused.
+func (clock *Clock) IsPaused() bool {
+	return clock.paused
+}
+
+// Step advances the paused time by one fixed step in the next frame.
+func (clock *Clock) Step() {
+	clock.stepping = true
+}
+
+// SetScale sets the speed of the time, e.g. 0.5 for slow motion at half speed.
+// The scale has to be positive.
+func (clock *Clock) SetScale(scale float64) {
+	if scale > 0 {
+		clock.scale = scale
+	}
+}
+
+// GetScale returns the speed of the time.
+func (clock *Clock) GetScale() float64 {
+	return clock.scale
+}
+
+// SetVirtual advances the time of every frame by frameTime instead of the real time.
+func (clock *Clock) SetVirtual(frameTime time.Duration) {
+	clock.virtual = true
+	clock.frameTime = frameTime
+}
+
+// SetRealtime advances the time of every frame by the real time.
+func (clock *Clock) SetRealtime() {
+	clock.virtual = false
+}
+
+// IsVirtual returns true if the time advances by a fixed amount per frame.
+func (clock *Clock) IsVirtual() bool {
+	return clock.virtual
+}
+
+// SetFixedStep sets the step of FixedUpdate. The step has to be positive.
+func (clock *Clock) SetFixedStep(step time.Duration) {
+	if step > 0 {
+		clock.fixedStep = step
+	}
+}
+
+// GetFixedStep returns the step of FixedUpdate in seconds.
+func (clock *Clock) GetFixedStep() float64 {
+	return clock.fixedStep.Seconds()
+}
+
+// FixedUpdate calls update with the fixed step in seconds as often as the time
+// that passed allows, thus simulations advance at the same rate independent of
+// the frame rate. The remaining time is carried over to the next frame and
+// returned as fraction of the step, which can be used to interpolate between
+// the last two states of the simulation.
+func (clock *Clock) FixedUpdate(update func(step float64)) float64 {
+	steps := 0
+	for clock.accumulator >= clock.fixedStep {
+		if steps == maxFixedSteps {
+			// drop the time that can't be caught up with
+			clock.accumulator = 0
+			break
+		}
+		update(clock.fixedStep.Seconds())
+		clock.accumulator -= clock.fixedStep
+		steps++
+	}
+	return float64(clock.accumulator) / float64(clock.fixedStep)
+}
+
+// OnKeyPress pauses or resumes the time when P is pressed, steps the paused
+// time when N is pressed and halves or doubles the speed of the time when
+// the comma or period key is pressed.
+func (clock *Clock) OnKeyPress(key, action, mods int) bool {
+	if glfw.Action(action) != glfw.Press {
+		return false
+	}
+
+	switch glfw.Key(key) {
+	case glfw.KeyP:
+		if clock.paused {
+			clock.Resume()
+		} else {
+			clock.Pause()
+		}
+		return true
+	case glfw.KeyN:
+		clock.Step()
+		return true
+	case glfw.KeyComma:
+		clock.SetScale(clock.scale / 2)
+		return true
+	case glfw.KeyPeriod:
+		clock.SetScale(clock.scale * 2)
+		return true
+	}
+	return false
+}
+
+// tick advances the time at the start of a frame.
+func (clock *Clock) tick(now time.Time) {
+	// calc unscaled time since the previous frame
+	var delta time.Duration
+	if clock.frames > 0 {
+		if clock.virtual {
+			delta = clock.frameTime
+		} else {
+			delta = now.Sub(clock.last)
+		}
+	}
+	clock.last = now
+	clock.frames++
+
+	// calc the time of the frame
+	switch {
+	case clock.stepping:
+		delta = clock.fixedStep
+		clock.stepping = false
+	case clock.paused:
+		delta = 0
+	default:
+		delta = time.Duration(float64(delta) * clock.scale)
+	}
+	clock.delta = delta
+	clock.elapsed += delta
+	clock.accumulator += delta
+}
@@ -0,0 +1,142 @@
+package window
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// tickFrames ticks the clock once per time in ms and returns the deltas in ms.
+func tickFrames(clock *Clock, times ...int) []float64 {
+	start := time.Unix(1000, 0)
+	var deltas []float64
+	for _, ms := range times {
+		clock.tick(start.Add(time.Duration(ms) * time.Millisecond))
+		deltas = append(deltas, clock.GetDelta()*1000)
+	}
+	return deltas
+}
+
+// checkDeltas reports deltas that differ from the expected ones.
+func checkDeltas(t *testing.T, name string, deltas, expected []float64) {
+	if len(deltas) != len(expected) {
+		t.Errorf("%v: %v deltas instead of %v", name, len(deltas), len(expected))
+		return
+	}
+	for i := range deltas {
+		if math.Abs(deltas[i]-expected[i]) > 1e-6 {
+			t.Errorf("%v: delta of frame %v is %vms instead of %vms", name, i, deltas[i], expected[i])
+		}
+	}
+}
+
+func TestClockTick(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(clock *Clock)
+		times    []int
+		expected []float64
+		elapsed  float64
+	}{
+		// the first frame has no delta
+		{"realtime", func(clock *Clock) {}, []int{0, 10, 30, 35}, []float64{0, 10, 20, 5}, 35},
+		{"scale", func(clock *Clock) { clock.SetScale(0.5) }, []int{0, 10, 30}, []float64{0, 5, 10}, 15},
+		{"invalid scale", func(clock *Clock) { clock.SetScale(0); clock.SetScale(-1) }, []int{0, 10}, []float64{0, 10}, 10},
+		{"paused", func(clock *Clock) { clock.Pause() }, []int{0, 10, 30}, []float64{0, 0, 0}, 0},
+		// the real time is ignored in virtual mode
+		{"virtual", func(clock *Clock) { clock.SetVirtual(40 * time.Millisecond) }, []int{0, 10, 500}, []float64{0, 40, 40}, 80},
+		{"virtual scale", func(clock *Clock) {
+			clock.SetVirtual(40 * time.Millisecond)
+			clock.SetScale(0.25)
+		}, []int{0, 10, 500}, []float64{0, 10, 10}, 20},
+		{"virtual paused", func(clock *Clock) {
+			clock.SetVirtual(40 * time.Millisecond)
+			clock.Pause()
+		}, []int{0, 10, 500}, []float64{0, 0, 0}, 0},
+		{"realtime again", func(clock *Clock) {
+			clock.SetVirtual(40 * time.Millisecond)
+			clock.SetRealtime()
+		}, []int{0, 10, 500}, []float64{0, 10, 490}, 500},
+	}
+
+	for _, test := range tests {
+		clock := MakeClock()
+		test.setup(&clock)
+		checkDeltas(t, test.name, tickFrames(&clock, test.times...), test.expected)
+		if elapsed := clock.GetElapsed() * 1000; math.Abs(elapsed-test.elapsed) > 1e-6 {
+			t.Errorf("%v: elapsed time is %vms instead of %vms", test.name, elapsed, test.elapsed)
+		}
+	}
+}
+
+func TestClockPauseStep(t *testing.T) {
+	clock := MakeClock()
+	clock.SetFixedStep(20 * time.Millisecond)
+	checkDeltas(t, "running", tickFrames(&clock, 0, 10), []float64{0, 10})
+
+	// stepping advances the paused time by one fixed step in the next frame only
+	clock.Pause()
+	if !clock.IsPaused() {
+		t.Error("clock isn't paused")
+	}
+	checkDeltas(t, "paused", tickFrames(&clock, 50), []float64{0})
+	clock.Step()
+	checkDeltas(t, "step", tickFrames(&clock, 60, 70), []float64{20, 0})
+
+	// stepping ignores the scale
+	clock.SetScale(4)
+	clock.Step()
+	checkDeltas(t, "scaled step", tickFrames(&clock, 80), []float64{20})
+
+	// the time that passed while paused is skipped after resuming
+	clock.Resume()
+	clock.SetScale(1)
+	checkDeltas(t, "resumed", tickFrames(&clock, 95), []float64{15})
+	if elapsed := clock.GetElapsed() * 1000; math.Abs(elapsed-65) > 1e-6 {
+		t.Errorf("elapsed time is %vms instead of 65ms", elapsed)
+	}
+}
+
+func TestFixedUpdate(t *testing.T) {
+	clock := MakeClock()
+	clock.SetFixedStep(10 * time.Millisecond)
+	if step := clock.GetFixedStep(); math.Abs(step-0.01) > 1e-9 {
+		t.Errorf("fixed step is %v instead of 0.01", step)
+	}
+	clock.SetFixedStep(0)
+	if step := clock.GetFixedStep(); math.Abs(step-0.01) > 1e-9 {
+		t.Errorf("invalid fixed step changed the step to %v", step)
+	}
+
+	tests := []struct {
+		name  string
+		time  int
+		steps int
+		alpha float64
+	}{
+		{"first frame", 0, 0, 0},
+		{"less than a step", 4, 0, 0.4},
+		// the remainder of the previous frame is carried over
+		{"carry over", 17, 1, 0.7},
+		{"multiple steps", 45, 3, 0.5},
+		{"exact", 50, 1, 0},
+		// time that exceeds the maximum number of steps is dropped
+		{"too slow", 50 + (maxFixedSteps+5)*10 + 3, maxFixedSteps, 0},
+		{"after drop", 50 + (maxFixedSteps+5)*10 + 9, 0, 0.6},
+	}
+	start := time.Unix(1000, 0)
+	for _, test := range tests {
+		clock.tick(start.Add(time.Duration(test.time) * time.Millisecond))
+		steps := 0
+		alpha := clock.FixedUpdate(func(step float64) {
+			if math.Abs(step-0.01) > 1e-9 {
+				t.Errorf("%v: step is %v instead of 0.01", test.name, step)
+			}
+			steps++
+		})
+		if steps != test.steps || math.Abs(alpha-test.alpha) > 1e-6 {
+			t.Errorf("%v: %v steps with alpha %v instead of %v steps with alpha %v",
+				test.name, steps, alpha, test.steps, test.alpha)
+		}
+	}
+}
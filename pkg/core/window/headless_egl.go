@@ -0,0 +1,132 @@
+//go:build linux && egl
+
+package window
+
+/*
+#cgo LDFLAGS: -lEGL
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+
+// getDisplay returns the surfaceless display of Mesa, which neither needs a
+// window system nor a GPU, or the default display otherwise.
+static EGLDisplay getDisplay() {
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (getPlatformDisplay != NULL) {
+		EGLDisplay display = getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+		if (display != EGL_NO_DISPLAY) {
+			return display;
+		}
+	}
+	return eglGetDisplay(EGL_DEFAULT_DISPLAY);
+}
+
+static EGLConfig chooseConfig(EGLDisplay display) {
+	EGLint attributes[] = {
+		EGL_SURFACE_TYPE, EGL_PBUFFER_BIT,
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_BIT,
+		EGL_RED_SIZE, 8,
+		EGL_GREEN_SIZE, 8,
+		EGL_BLUE_SIZE, 8,
+		EGL_ALPHA_SIZE, 8,
+		EGL_DEPTH_SIZE, 24,
+		EGL_NONE
+	};
+	EGLConfig config;
+	EGLint count = 0;
+	if (!eglChooseConfig(display, attributes, &config, 1, &count) || count == 0) {
+		return NULL;
+	}
+	return config;
+}
+
+static EGLSurface createSurface(EGLDisplay display, EGLConfig config, EGLint width, EGLint height) {
+	EGLint attributes[] = {
+		EGL_WIDTH, width,
+		EGL_HEIGHT, height,
+		EGL_NONE
+	};
+	return eglCreatePbufferSurface(display, config, attributes);
+}
+
+static EGLContext createContext(EGLDisplay display, EGLConfig config) {
+	EGLint attributes[] = {
+		EGL_CONTEXT_MAJOR_VERSION, 4,
+		EGL_CONTEXT_MINOR_VERSION, 3,
+		EGL_CONTEXT_OPENGL_PROFILE_MASK, EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+		EGL_NONE
+	};
+	return eglCreateContext(display, config, EGL_NO_CONTEXT, attributes);
+}
+*/
+import "C"
+
+import "fmt"
+
+// headlessContext is an offscreen OpenGL context created with EGL. Its default
+// framebuffer is a pbuffer of the size of the window, thus everything that is
+// rendered to the screen ends up in the pbuffer.
+type headlessContext struct {
+	display C.EGLDisplay
+	surface C.EGLSurface
+	context C.EGLContext
+}
+
+// makeHeadlessContext creates an OpenGL 4.3 core context and makes it current.
+// With Mesa no window system is needed and LIBGL_ALWAYS_SOFTWARE=1 selects
+// the llvmpipe software rasterizer.
+func makeHeadlessContext(width, height int) (*headlessContext, error) {
+	display := C.getDisplay()
+	if display == 0 {
+		return nil, fmt.Errorf("failed to get EGL display: %v", getEGLError())
+	}
+	var major, minor C.EGLint
+	if C.eglInitialize(display, &major, &minor) == C.EGL_FALSE {
+		return nil, fmt.Errorf("failed to initialize EGL: %v", getEGLError())
+	}
+
+	context := headlessContext{display: display}
+	config := C.chooseConfig(display)
+	if config == 0 {
+		context.destroy()
+		return nil, fmt.Errorf("no EGL config with OpenGL and pbuffer support: %v", getEGLError())
+	}
+	if C.eglBindAPI(C.EGL_OPENGL_API) == C.EGL_FALSE {
+		context.destroy()
+		return nil, fmt.Errorf("failed to bind OpenGL API: %v", getEGLError())
+	}
+
+	context.surface = C.createSurface(display, config, C.EGLint(width), C.EGLint(height))
+	if context.surface == nil {
+		context.destroy()
+		return nil, fmt.Errorf("failed to create %vx%v pbuffer: %v", width, height, getEGLError())
+	}
+	context.context = C.createContext(display, config)
+	if context.context == nil {
+		context.destroy()
+		return nil, fmt.Errorf("failed to create OpenGL 4.3 core context: %v", getEGLError())
+	}
+	if C.eglMakeCurrent(display, context.surface, context.surface, context.context) == C.EGL_FALSE {
+		context.destroy()
+		return nil, fmt.Errorf("failed to make context current: %v", getEGLError())
+	}
+
+	return &context, nil
+}
+
+// destroy releases the context, the pbuffer and the display.
+func (context *headlessContext) destroy() {
+	C.eglMakeCurrent(context.display, nil, nil, nil)
+	if context.context != nil {
+		C.eglDestroyContext(context.display, context.context)
+	}
+	if context.surface != nil {
+		C.eglDestroySurface(context.display, context.surface)
+	}
+	C.eglTerminate(context.display)
+}
+
+// getEGLError returns the last EGL error code.
+func getEGLError() string {
+	return fmt.Sprintf("EGL error 0x%x", int(C.eglGetError()))
+}
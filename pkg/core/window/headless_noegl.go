@@ -0,0 +1,17 @@
+//go:build !linux || !egl
+
+package window
+
+import "errors"
+
+// headlessContext is not available without EGL.
+type headlessContext struct{}
+
+// makeHeadlessContext returns an error since headless contexts are created
+// with EGL, which is only linked on linux when building with the egl tag.
+func makeHeadlessContext(width, height int) (*headlessContext, error) {
+	return nil, errors.New("headless mode requires EGL, build on linux with -tags egl")
+}
+
+// destroy does nothing.
+func (context *headlessContext) destroy() {}
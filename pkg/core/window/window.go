@@ -3,23 +3,54 @@
 package window
 
 import (
+	"flag"
 	"strconv"
 	"time"
 
 	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+	"github.com/adrianderstroff/realtime-clouds/pkg/core/shader"
 	"github.com/go-gl/glfw/v3.2/glfw"
 )
 
 // Window takes care of window creation and interaction.
+// In headless mode there is no glfw window, thus Window is nil.
 type Window struct {
 	Window *glfw.Window
 	Width  int
 	Height int
 
-	fpsLock float64
-	lastFps float64
+	fpsLock   float64
+	lastFps   float64
+	nextFrame time.Time
+	clock     Clock
 
 	loopCursor bool
+
+	frameHooks    []func()
+	captures      []*Capture
+	shaderWatcher *shader.Watcher
+
+	headless *headlessContext
+	frames   int
+	frame    int
+}
+
+// Options specify how a Window is created.
+type Options struct {
+	// Headless renders offscreen without opening a window.
+	Headless bool
+	// Frames is the number of frames that are rendered in headless mode
+	// before RunMainLoop returns.
+	Frames int
+}
+
+// AddFlags defines the -headless and -frames command line flags and returns
+// the Options they are parsed into by flag.Parse.
+func AddFlags() *Options {
+	options := Options{}
+	flag.BoolVar(&options.Headless, "headless", false, "render a fixed number of frames offscreen and exit, requires building with -tags egl")
+	flag.IntVar(&options.Frames, "frames", 60, "number of frames rendered in headless mode")
+	return &options
 }
 
 // NewWindow returns a pointer to a Window with the specified window title and window width and height.
@@ -54,47 +85,190 @@ func New(title string, width, height int) (*Window, error) {
 		Width:   width,
 		Height:  height,
 		fpsLock: -1.0,
+		clock:   MakeClock(),
 	}
 
 	return &window, nil
 }
 
+// NewHeadless returns a pointer to a Window without visible window, that renders
+// into an offscreen buffer with the specified width and height. RunMainLoop
+// returns after the specified number of frames. The context is created with EGL,
+// which with Mesa needs neither a window system nor a GPU. EGL is only linked on
+// linux when building with -tags egl, otherwise an error is returned.
+func NewHeadless(width, height, frames int) (*Window, error) {
+	context, err := makeHeadlessContext(width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	// init OpenGL
+	if err := gl.Init(); err != nil {
+		context.destroy()
+		return nil, err
+	}
+
+	// set default values
+	window := Window{
+		Window:   nil,
+		Width:    width,
+		Height:   height,
+		fpsLock:  -1.0,
+		clock:    MakeClock(),
+		headless: context,
+		frames:   frames,
+	}
+
+	return &window, nil
+}
+
+// NewWithOptions returns a pointer to a Window that is either a regular or a
+// headless Window depending on the options.
+func NewWithOptions(title string, width, height int, options Options) (*Window, error) {
+	if options.Headless {
+		return NewHeadless(width, height, options.Frames)
+	}
+	return New(title, width, height)
+}
+
 // Close cleans up the Window.
 func (window *Window) Close() {
+	for _, capture := range window.captures {
+		capture.Delete()
+	}
+	window.captures = nil
+	if window.shaderWatcher != nil {
+		shader.WatchNewShaders(nil)
+	}
+	if window.headless != nil {
+		window.headless.destroy()
+		return
+	}
 	glfw.Terminate()
 }
 
+// IsHeadless returns true if the Window renders offscreen.
+func (window *Window) IsHeadless() bool {
+	return window.headless != nil
+}
+
+// GetFramebufferSize returns the size of the default frame buffer in pixels,
+// which changes when the window is resized.
+func (window *Window) GetFramebufferSize() (int, int) {
+	if window.headless != nil {
+		return window.Width, window.Height
+	}
+	return window.Window.GetFramebufferSize()
+}
+
+// GetFrame returns the number of frames that have been rendered so far.
+func (window *Window) GetFrame() int {
+	return window.frame
+}
+
+// shouldClose returns true if the main loop should stop, which is the case
+// after closing the window or after the last frame in headless mode.
+func (window *Window) shouldClose() bool {
+	if window.headless != nil {
+		return window.frame >= window.frames
+	}
+	return window.Window.ShouldClose()
+}
+
 // RunMainLoop calls the specified render function each frame until the window is being closed.
+// In headless mode it returns after the number of frames specified on construction
+// and frames aren't locked. Before returning all captured frames are saved.
 func (window *Window) RunMainLoop(render func()) {
-	for !window.Window.ShouldClose() {
+	for !window.shouldClose() {
 		// set frame start
 		frameStart := time.Now()
+		window.clock.tick(frameStart)
+		// call hooks before rendering
+		for _, hook := range window.frameHooks {
+			hook()
+		}
 		// reset gl states
 		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
 		// render user defined function
 		render()
-		// swap front with back buffer
-		window.Window.SwapBuffers()
-		// get inputs
-		glfw.PollEvents()
-		// get the time after the rendering
-		frameEnd := time.Now()
+		// read back frames that should be captured
+		for _, capture := range window.captures {
+			capture.update()
+		}
+		window.frame++
+		if window.headless == nil {
+			// swap front with back buffer
+			window.Window.SwapBuffers()
+			// get inputs
+			glfw.PollEvents()
+		}
 
 		// frame lock if specified
-		deltaTime := frameEnd.Sub(frameStart).Seconds() * 1000.0
-		timeToWait := (1000.0 / window.fpsLock) - deltaTime
-		if timeToWait > 0.0 && window.fpsLock > 0.0 {
-			time.Sleep(time.Duration(timeToWait/1000) * time.Second)
-			deltaTime = deltaTime + timeToWait
+		if window.fpsLock > 0.0 && window.headless == nil {
+			window.waitForNextFrame(frameStart)
 		}
-		window.lastFps = 1000.0 / deltaTime
+		window.lastFps = 1.0 / time.Since(frameStart).Seconds()
+	}
+
+	// save the frames that are still being read back
+	for _, capture := range window.captures {
+		capture.Finish()
+	}
+}
+
+// waitForNextFrame sleeps until the next frame should start. Frames are
+// scheduled one period apart instead of sleeping for the remaining time of
+// the frame, thus inaccuracies of sleeping don't add up.
+func (window *Window) waitForNextFrame(frameStart time.Time) {
+	period := time.Duration(float64(time.Second) / window.fpsLock)
+	if window.nextFrame.IsZero() {
+		window.nextFrame = frameStart
+	}
+	window.nextFrame = window.nextFrame.Add(period)
+
+	// start over if the frame took too long, otherwise the following frames would be rushed
+	wait := time.Until(window.nextFrame)
+	if wait <= 0 {
+		window.nextFrame = time.Now()
+		return
+	}
+	time.Sleep(wait)
+}
+
+// AddFrameHook adds a function that is called at the start of each frame before rendering.
+func (window *Window) AddFrameHook(hook func()) {
+	window.frameHooks = append(window.frameHooks, hook)
+}
+
+// EnableShaderReload reloads shaders whose files changed at the start of each frame.
+// Only shaders that are created afterwards are watched, others can be added to
+// the watcher returned by GetShaderWatcher.
+func (window *Window) EnableShaderReload() {
+	if window.shaderWatcher != nil {
+		return
 	}
+	watcher := shader.MakeWatcher(500 * time.Millisecond)
+	window.shaderWatcher = &watcher
+	shader.WatchNewShaders(window.shaderWatcher)
+	window.AddFrameHook(window.shaderWatcher.Update)
+}
+
+// GetShaderWatcher returns the watcher of EnableShaderReload or nil if shader
+// reloading isn't enabled.
+func (window *Window) GetShaderWatcher() *shader.Watcher {
+	return window.shaderWatcher
 }
 
 // LockFPS provides an upper bound for the FPS.
 // The fps has to be greater than zero.
 func (window *Window) LockFPS(fps float64) {
 	window.fpsLock = fps
+	window.nextFrame = time.Time{}
+}
+
+// GetClock returns the Clock that measures the time of the frames.
+func (window *Window) GetClock() *Clock {
+	return &window.clock
 }
 
 // GetFPS returns the fps of the previous frame.
@@ -108,7 +282,11 @@ func (window *Window) GetFPSFormatted() string {
 }
 
 // SetTitle updates the window title.
+// Headless windows have no title.
 func (window *Window) SetTitle(title string) {
+	if window.headless != nil {
+		return
+	}
 	window.Window.SetTitle(title)
 }
 
@@ -1,10 +1,27 @@
 package noise
 
 import (
+	"math"
+	"math/rand"
+
 	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
+const (
+	// blueSigma is the standard deviation in pixels of the gaussian that
+	// is used to measure how clustered the points of a pattern are.
+	blueSigma float32 = 1.5
+	// blueRadius is the distance in pixels beyond which the gaussian is
+	// negligible, thus toggling a pixel only updates the energy within it.
+	blueRadius int = 6
+	// blueInitialDensity is the fraction of pixels that are set in the
+	// initial binary pattern.
+	blueInitialDensity float32 = 0.1
+	// goldenRatio is used to offset the slices of a blue noise stack.
+	goldenRatio float32 = 0.61803398875
+)
+
 // ToroidalDistance2D returns the distance between p1 and p2 in toroidal space
 // p1 and p2 have to be between 0 and 1
 func ToroidalDistance2D(p1, p2 mgl32.Vec2) float32 {
@@ -22,9 +39,218 @@ func ToroidalDistance2D(p1, p2 mgl32.Vec2) float32 {
 	return cgm.Sqrt32(dx*dx + dy*dy)
 }
 
-// Blue returns a blue noise image of size (width, height) with n points
-func Blue(width, height, n int) []uint8 {
-	data := []uint8{}
+// Blue returns a tileable blue noise image of size (width, height).
+// The seed determines the initial pattern, thus the same seed always
+// results in the same image.
+// Since distances are measured in toroidal space the width and height
+// should be equal to get isotropic noise.
+func Blue(width, height int, seed int64) []uint8 {
+	field := BlueField(width, height, seed)
+	return field.ToUint8()
+}
+
+// BlueField returns a tileable blue noise field of size (width, height)
+// created with the void-and-cluster algorithm.
+// Each pixel holds its rank in the dither order mapped to 0..1, thus every
+// value occurs exactly once.
+// The seed determines the initial pattern, thus the same seed always
+// results in the same field.
+// Each of the width*height steps searches all pixels for the tightest cluster
+// or the largest void, thus the runtime grows quadratically with the number of
+// pixels. 64x64 takes milliseconds, 128x128 about a second and each doubling
+// of the width and height takes 16 times as long, thus sizes above 256x256
+// are impractical and a smaller texture should be tiled instead.
+// An empty field is returned if the width or height is not positive.
+func BlueField(width, height int, seed int64) Field2D {
+	if width <= 0 || height <= 0 {
+		return MakeField2D(0, 0, 1)
+	}
+	size := width * height
+	bp := makeBluePattern(width, height)
+
+	// setup the initial binary pattern by randomly setting some pixels
+	ones := int(float32(size) * blueInitialDensity)
+	if ones < 1 {
+		ones = 1
+	}
+	r := rand.New(rand.NewSource(seed))
+	for _, idx := range r.Perm(size)[:ones] {
+		bp.toggle(idx)
+	}
+
+	// move points from the tightest cluster into the largest void until
+	// the pattern doesn't change anymore
+	for i := 0; i < size; i++ {
+		cluster := bp.tightestCluster()
+		bp.toggle(cluster)
+		void := bp.largestVoid()
+		bp.toggle(void)
+		if void == cluster {
+			break
+		}
+	}
+	prototype := bp.copy()
+
+	// rank the points of the initial pattern by removing the tightest
+	// clusters one after another
+	ranks := make([]int, size)
+	for rank := ones - 1; rank >= 0; rank-- {
+		cluster := bp.tightestCluster()
+		bp.toggle(cluster)
+		ranks[cluster] = rank
+	}
 
-	return data
+	// rank the remaining pixels by filling the largest voids one after
+	// another, starting from the initial pattern
+	bp = prototype
+	for rank := ones; rank < size; rank++ {
+		void := bp.largestVoid()
+		bp.toggle(void)
+		ranks[void] = rank
+	}
+
+	// map the ranks to 0..1 and save them in the field
+	field := MakeField2D(width, height, 1)
+	for i, rank := range ranks {
+		field.data[i] = (float32(rank) + 0.5) / float32(size)
+	}
+
+	return field
+}
+
+// BlueStack returns a stack of tileable blue noise images of size
+// (width, height, slices) that can be used for temporal jittering.
+// Each slice is the same blue noise field offset by the golden ratio, thus
+// each slice is blue noise and the values of a pixel are evenly distributed
+// over time.
+func BlueStack(width, height, slices int, seed int64) []uint8 {
+	field := BlueStackField(width, height, slices, seed)
+	return field.ToUint8()
+}
+
+// BlueStackField returns a stack of tileable blue noise fields of size
+// (width, height, slices) that can be used for temporal jittering.
+// Each slice is the same blue noise field offset by the golden ratio, thus
+// each slice is blue noise and the values of a pixel are evenly distributed
+// over time.
+func BlueStackField(width, height, slices int, seed int64) Field3D {
+	blue := BlueField(width, height, seed)
+
+	field := MakeField3D(width, height, slices, 1)
+	for z := 0; z < slices; z++ {
+		offset := float32(z) * goldenRatio
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				val := blue.Get(x, y, 0) + offset
+				field.Set(x, y, z, 0, val-cgm.Floor32(val))
+			}
+		}
+	}
+
+	return field
+}
+
+// bluePattern is a binary pattern together with the energy of each pixel.
+// The energy of a pixel is the sum of the gaussian weighted toroidal
+// distances to all set pixels.
+type bluePattern struct {
+	width   int
+	height  int
+	kernel  []float32
+	pattern []bool
+	energy  []float32
+}
+
+// makeBluePattern creates an empty pattern of the specified size.
+func makeBluePattern(width, height int) bluePattern {
+	// precalculate the gaussian weight for each offset
+	size := width * height
+	scale := cgm.Sqrt32(float32(size))
+	kernel := make([]float32, size)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			offset := mgl32.Vec2{float32(x) / float32(width), float32(y) / float32(height)}
+			dist := ToroidalDistance2D(mgl32.Vec2{0, 0}, offset) * scale
+			kernel[x+y*width] = float32(math.Exp(float64(-dist * dist / (2 * blueSigma * blueSigma))))
+		}
+	}
+
+	return bluePattern{
+		width:   width,
+		height:  height,
+		kernel:  kernel,
+		pattern: make([]bool, size),
+		energy:  make([]float32, size),
+	}
+}
+
+// copy returns a deep copy of the pattern.
+func (bp *bluePattern) copy() bluePattern {
+	pattern := make([]bool, len(bp.pattern))
+	copy(pattern, bp.pattern)
+	energy := make([]float32, len(bp.energy))
+	copy(energy, bp.energy)
+
+	return bluePattern{
+		width:   bp.width,
+		height:  bp.height,
+		kernel:  bp.kernel,
+		pattern: pattern,
+		energy:  energy,
+	}
+}
+
+// toggle flips the pixel at idx and updates the energy of the pixels within
+// blueRadius, which takes constant time independent of the size of the pattern.
+func (bp *bluePattern) toggle(idx int) {
+	var sign float32 = 1
+	if bp.pattern[idx] {
+		sign = -1
+	}
+	bp.pattern[idx] = !bp.pattern[idx]
+
+	px, py := idx%bp.width, idx/bp.width
+	xmin, xmax := kernelOffsets(bp.width)
+	ymin, ymax := kernelOffsets(bp.height)
+	for dy := ymin; dy <= ymax; dy++ {
+		y := loop(py+dy, bp.height)
+		ky := loop(dy, bp.height) * bp.width
+		for dx := xmin; dx <= xmax; dx++ {
+			x := loop(px+dx, bp.width)
+			kx := loop(dx, bp.width)
+			bp.energy[x+y*bp.width] += sign * bp.kernel[kx+ky]
+		}
+	}
+}
+
+// kernelOffsets returns the smallest and largest offset to a toggled pixel
+// along an axis of the specified size whose energy has to be updated.
+// Small patterns are updated entirely such that no pixel is updated twice.
+func kernelOffsets(size int) (int, int) {
+	if 2*blueRadius+1 >= size {
+		return 0, size - 1
+	}
+	return -blueRadius, blueRadius
+}
+
+// tightestCluster returns the index of the set pixel with the highest energy.
+func (bp *bluePattern) tightestCluster() int {
+	best := -1
+	for i, set := range bp.pattern {
+		if set && (best == -1 || bp.energy[i] > bp.energy[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+// largestVoid returns the index of the unset pixel with the lowest energy.
+func (bp *bluePattern) largestVoid() int {
+	best := -1
+	for i, set := range bp.pattern {
+		if !set && (best == -1 || bp.energy[i] < bp.energy[best]) {
+			best = i
+		}
+	}
+	return best
 }
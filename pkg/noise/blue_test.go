@@ -0,0 +1,186 @@
+package noise
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// thresholds at which the blue noise is turned into binary dither patterns
+var blueThresholds = []float32{0.1, 0.25, 0.5}
+
+// radialPower returns the power spectrum of the data of size (size, size)
+// averaged over rings of integer radius around the zero frequency.
+func radialPower(data []float64, size int) []float64 {
+	// remove the mean such that the zero frequency doesn't dominate
+	var mean float64
+	for _, val := range data {
+		mean += val
+	}
+	mean /= float64(len(data))
+
+	// calc the 2D DFT separately along the rows and the columns
+	spectrum := make([]complex128, len(data))
+	for i, val := range data {
+		spectrum[i] = complex(val-mean, 0)
+	}
+	twiddles := make([]complex128, size)
+	for k := range twiddles {
+		angle := -2 * math.Pi * float64(k) / float64(size)
+		twiddles[k] = complex(math.Cos(angle), math.Sin(angle))
+	}
+	line := make([]complex128, size)
+	dft := func(get func(i int) complex128, set func(i int, val complex128)) {
+		for k := 0; k < size; k++ {
+			var sum complex128
+			for n := 0; n < size; n++ {
+				sum += get(n) * twiddles[(k*n)%size]
+			}
+			line[k] = sum
+		}
+		for k, val := range line {
+			set(k, val)
+		}
+	}
+	for y := 0; y < size; y++ {
+		dft(func(i int) complex128 { return spectrum[i+y*size] },
+			func(i int, val complex128) { spectrum[i+y*size] = val })
+	}
+	for x := 0; x < size; x++ {
+		dft(func(i int) complex128 { return spectrum[x+i*size] },
+			func(i int, val complex128) { spectrum[x+i*size] = val })
+	}
+
+	// average the power of each ring
+	power := make([]float64, size/2+1)
+	counts := make([]int, size/2+1)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			fx, fy := x, y
+			if fx > size/2 {
+				fx -= size
+			}
+			if fy > size/2 {
+				fy -= size
+			}
+			radius := int(math.Round(math.Sqrt(float64(fx*fx + fy*fy))))
+			if radius > size/2 {
+				continue
+			}
+			val := spectrum[x+y*size]
+			power[radius] += real(val)*real(val) + imag(val)*imag(val)
+			counts[radius]++
+		}
+	}
+	for i := range power {
+		if counts[i] > 0 {
+			power[i] /= float64(counts[i])
+		}
+	}
+	return power
+}
+
+// lowHighRatio returns the mean power of the lowest frequencies divided by the
+// mean power of the highest frequencies of the binary pattern that contains
+// all values below the threshold.
+func lowHighRatio(values []float32, size int, threshold float32) float64 {
+	pattern := make([]float64, len(values))
+	for i, val := range values {
+		if val < threshold {
+			pattern[i] = 1
+		}
+	}
+	power := radialPower(pattern, size)
+
+	mean := func(from, to int) float64 {
+		var sum float64
+		for i := from; i < to; i++ {
+			sum += power[i]
+		}
+		return sum / float64(to-from)
+	}
+	return mean(1, size/8) / mean(size/4, size/2+1)
+}
+
+func TestBlueSpectrum(t *testing.T) {
+	const size = 64
+
+	// white noise has the same power at all frequencies, which makes sure the
+	// ratio actually distinguishes between blue and white noise
+	r := rand.New(rand.NewSource(0))
+	white := make([]float32, size*size)
+	for i := range white {
+		white[i] = r.Float32()
+	}
+	for _, threshold := range blueThresholds {
+		if ratio := lowHighRatio(white, size, threshold); ratio < 0.5 {
+			t.Errorf("white noise at threshold %v has a low to high frequency ratio of %v", threshold, ratio)
+		}
+	}
+
+	for _, seed := range []int64{0, 1} {
+		blue := BlueField(size, size, seed)
+		stack := BlueStackField(size, size, 4, seed)
+		slice := stack.data[3*size*size:]
+		for _, threshold := range blueThresholds {
+			if ratio := lowHighRatio(blue.data, size, threshold); ratio > 0.1 {
+				t.Errorf("blue noise with seed %v at threshold %v has a low to high frequency ratio of %v", seed, threshold, ratio)
+			}
+			if ratio := lowHighRatio(slice, size, threshold); ratio > 0.1 {
+				t.Errorf("slice 3 of blue noise stack with seed %v at threshold %v has a low to high frequency ratio of %v", seed, threshold, ratio)
+			}
+		}
+	}
+}
+
+func TestBlueRanks(t *testing.T) {
+	const size = 32
+	field := BlueField(size, size, 0)
+
+	// every rank occurs exactly once
+	values := append([]float32(nil), field.data...)
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	for i, val := range values {
+		expected := (float32(i) + 0.5) / float32(size*size)
+		if val != expected {
+			t.Fatalf("value %v is %v instead of %v", i, val, expected)
+		}
+	}
+}
+
+func TestBlueSmallSizes(t *testing.T) {
+	// sizes below the kernel radius update every pixel exactly once
+	for _, size := range []int{0, 1, 2, 5, 13, 14} {
+		field := BlueField(size, size, 0)
+		if len(field.data) != size*size {
+			t.Errorf("size %v has %v values", size, len(field.data))
+		}
+		seen := make(map[float32]bool)
+		for _, val := range field.data {
+			if seen[val] {
+				t.Errorf("size %v contains value %v twice", size, val)
+			}
+			seen[val] = true
+		}
+	}
+
+	// empty sizes result in empty fields
+	for _, size := range [][2]int{{0, 4}, {4, 0}, {-1, -1}} {
+		field := BlueField(size[0], size[1], 0)
+		if len(field.data) != 0 || len(Blue(size[0], size[1], 0)) != 0 {
+			t.Errorf("size %v is not empty", size)
+		}
+	}
+}
+
+func BenchmarkBlue(b *testing.B) {
+	for _, size := range []int{32, 64, 128} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				BlueField(size, size, int64(i))
+			}
+		})
+	}
+}
@@ -2,53 +2,77 @@ package noise
 
 import (
 	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
-	"github.com/go-gl/mathgl/mgl32"
 )
 
-func Curl2D(width, height, res int) []uint8 {
-	// create 2D perlin noise
-	perlin := Perlin2D(width, height, res, 1)
+// Curl2D creates a 2D image of curl noise based on perlin noise with the
+// specified number of octaves and persistence. The seed is passed on to the
+// perlin noise.
+// The vectors are encoded into the red and green channel, mapping the range
+// -1..1 to 0..255, thus a value of 128 corresponds to no movement.
+func Curl2D(width, height, octaves int, persistence float32, seed int64) []uint8 {
+	field := Curl2DField(width, height, octaves, persistence, seed)
+	return encodeVectors(field.data)
+}
 
-	// calculate curl
-	var maxval float32 = 0
-	var values []float32
+// Curl2DField creates a 2D field of curl noise based on perlin noise with the
+// specified number of octaves and persistence. The seed is passed on to the
+// perlin noise.
+// It returns a divergence-free vector field with two channels. The vectors
+// are scaled such that the biggest component is 1.
+func Curl2DField(width, height, octaves int, persistence float32, seed int64) Field2D {
+	// create 2D perlin noise as potential
+	potential := Perlin2DField(width, height, octaves, persistence, seed)
+
+	// the curl of a scalar potential p in 2D is (dp/dy, -dp/dx)
+	field := MakeField2D(width, height, 2)
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
-			// calculate curl
-			a := deriveX(perlin, x, y, width, height)
-			b := deriveY(perlin, x, y, width, height)
-			curl := mgl32.Vec2{a, -b}
-
-			// add magnitude
-			val := curl.Len()
-			values = append(values, val)
-
-			// save maximum value for following normalization
-			maxval = cgm.Max32(maxval, val)
+			field.Set(x, y, 0, deriveY(&potential, x, y, 0))
+			field.Set(x, y, 1, -deriveX(&potential, x, y, 0))
 		}
 	}
+	normalizeVectors(field.data)
 
-	// map distance to 0..255 and save in data slice
-	var data []uint8
-	for i := 0; i < len(values); i++ {
-		val := cgm.Map(values[i], 0, maxval, 0, 255)
-		data = append(data, uint8(val))
-	}
+	return field
+}
 
-	return data
+// deriveX calculates the central difference of channel c in x direction.
+// The derivative loops at the edges.
+func deriveX(field *Field2D, x, y, c int) float32 {
+	xp := loop(x+1, field.width)
+	xn := loop(x-1, field.width)
+	return (field.Get(xp, y, c) - field.Get(xn, y, c)) / 2.0
 }
 
-func deriveX(data []uint8, x, y, width, height int) float32 {
-	yp := loop(y+1, height)
-	yn := loop(y-1, height)
-	return (f(data, x, yp, width) - f(data, x, yn, width)) / 2.0
+// deriveY calculates the central difference of channel c in y direction.
+// The derivative loops at the edges.
+func deriveY(field *Field2D, x, y, c int) float32 {
+	yp := loop(y+1, field.height)
+	yn := loop(y-1, field.height)
+	return (field.Get(x, yp, c) - field.Get(x, yn, c)) / 2.0
 }
-func deriveY(data []uint8, x, y, width, height int) float32 {
-	xp := loop(y+1, width)
-	xn := loop(y-1, width)
-	return (f(data, xp, y, width) - f(data, xn, y, width)) / 2.0
+
+// normalizeVectors scales all vector components uniformly such that the
+// biggest absolute component is 1. Scaling uniformly keeps the field
+// divergence-free.
+func normalizeVectors(data []float32) {
+	var maxval float32 = 0
+	for _, val := range data {
+		maxval = cgm.Max32(maxval, cgm.Abs32(val))
+	}
+	if maxval == 0 {
+		return
+	}
+	for i := range data {
+		data[i] /= maxval
+	}
 }
 
-func f(data []uint8, x, y, w int) float32 {
-	return float32(data[y*w+x]) / 255.0
+// encodeVectors maps vector components from -1..1 to 0..255.
+func encodeVectors(data []float32) []uint8 {
+	encoded := make([]float32, len(data))
+	for i, val := range data {
+		encoded[i] = cgm.Map(val, -1, 1, 0, 1)
+	}
+	return toUint8(encoded)
 }
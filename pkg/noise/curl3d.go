@@ -0,0 +1,91 @@
+package noise
+
+import (
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// curlOffsets shift the three perlin potentials of the 3D curl noise apart
+// such that they are uncorrelated.
+var curlOffsets = []mgl32.Vec3{
+	mgl32.Vec3{0, 0, 0},
+	mgl32.Vec3{31.416, 47.853, 12.793},
+	mgl32.Vec3{-17.231, 93.989, 56.417},
+}
+
+// Curl3D creates a 3D image of curl noise based on three perlin potentials
+// with the specified number of octaves and persistence.
+// The seed is passed on to the perlin noise.
+// The vectors are encoded into the red, green and blue channel, mapping the
+// range -1..1 to 0..255, thus a value of 128 corresponds to no movement.
+func Curl3D(width, height, depth, octaves int, persistence float32, seed int64) []uint8 {
+	field := Curl3DField(width, height, depth, octaves, persistence, seed, 0)
+	return encodeVectors(field.data)
+}
+
+// Curl3DField creates a 3D field of curl noise based on three perlin
+// potentials with the specified number of octaves and persistence.
+// The seed is passed on to the perlin noise. The slices are split across the
+// specified number of workers, one per CPU if it is smaller than 1.
+// It returns a divergence-free vector field with three channels. The vectors
+// are scaled such that the biggest component is 1.
+func Curl3DField(width, height, depth, octaves int, persistence float32, seed int64, workers int) Field3D {
+	// setup perlin util
+	perlin := makeperlin(basePeriod, seed)
+
+	// sample the three components of the vector potential from offset
+	// positions of the same perlin noise
+	potential := MakeField3D(width, height, depth, 3)
+	forEachSlice(depth, workers, func(z int) {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				fx := float32(x) / float32(width)
+				fy := float32(y) / float32(height)
+				fz := float32(z) / float32(depth)
+				for c, offset := range curlOffsets {
+					rnd := perlin.tileable(fx+offset.X(), fy+offset.Y(), fz+offset.Z(), octaves, persistence)
+					potential.Set(x, y, z, c, rnd)
+				}
+			}
+		}
+	})
+
+	// the curl of a vector potential p is
+	// (dp3/dy - dp2/dz, dp1/dz - dp3/dx, dp2/dx - dp1/dy)
+	field := MakeField3D(width, height, depth, 3)
+	forEachSlice(depth, workers, func(z int) {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				field.Set(x, y, z, 0, derive3DY(&potential, x, y, z, 2)-derive3DZ(&potential, x, y, z, 1))
+				field.Set(x, y, z, 1, derive3DZ(&potential, x, y, z, 0)-derive3DX(&potential, x, y, z, 2))
+				field.Set(x, y, z, 2, derive3DX(&potential, x, y, z, 1)-derive3DY(&potential, x, y, z, 0))
+			}
+		}
+	})
+	normalizeVectors(field.data)
+
+	return field
+}
+
+// derive3DX calculates the central difference of channel c in x direction.
+// The derivative loops at the edges.
+func derive3DX(field *Field3D, x, y, z, c int) float32 {
+	xp := loop(x+1, field.width)
+	xn := loop(x-1, field.width)
+	return (field.Get(xp, y, z, c) - field.Get(xn, y, z, c)) / 2.0
+}
+
+// derive3DY calculates the central difference of channel c in y direction.
+// The derivative loops at the edges.
+func derive3DY(field *Field3D, x, y, z, c int) float32 {
+	yp := loop(y+1, field.height)
+	yn := loop(y-1, field.height)
+	return (field.Get(x, yp, z, c) - field.Get(x, yn, z, c)) / 2.0
+}
+
+// derive3DZ calculates the central difference of channel c in z direction.
+// The derivative loops at the edges.
+func derive3DZ(field *Field3D, x, y, z, c int) float32 {
+	zp := loop(z+1, field.depth)
+	zn := loop(z-1, field.depth)
+	return (field.Get(x, y, zp, c) - field.Get(x, y, zn, c)) / 2.0
+}
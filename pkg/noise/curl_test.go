@@ -0,0 +1,107 @@
+package noise
+
+import (
+	"testing"
+)
+
+// divergence2D returns the largest absolute divergence of the vector field and
+// the largest absolute partial derivative that contributes to it, both
+// calculated with central differences.
+func divergence2D(field *Field2D) (float32, float32) {
+	var maxdiv, maxderiv float32
+	for y := 0; y < field.height; y++ {
+		for x := 0; x < field.width; x++ {
+			dx := deriveX(field, x, y, 0)
+			dy := deriveY(field, x, y, 1)
+			maxdiv = max32(maxdiv, abs(dx+dy))
+			maxderiv = max32(maxderiv, max32(abs(dx), abs(dy)))
+		}
+	}
+	return maxdiv, maxderiv
+}
+
+// divergence3D returns the largest absolute divergence of the vector field and
+// the largest absolute partial derivative that contributes to it, both
+// calculated with central differences.
+func divergence3D(field *Field3D) (float32, float32) {
+	var maxdiv, maxderiv float32
+	for z := 0; z < field.depth; z++ {
+		for y := 0; y < field.height; y++ {
+			for x := 0; x < field.width; x++ {
+				dx := derive3DX(field, x, y, z, 0)
+				dy := derive3DY(field, x, y, z, 1)
+				dz := derive3DZ(field, x, y, z, 2)
+				maxdiv = max32(maxdiv, abs(dx+dy+dz))
+				maxderiv = max32(maxderiv, max32(abs(dx), max32(abs(dy), abs(dz))))
+			}
+		}
+	}
+	return maxdiv, maxderiv
+}
+
+func max32(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// the divergence relative to the derivatives that is caused by rounding errors
+const maxRelativeDivergence = 1e-4
+
+func TestCurl2DDivergence(t *testing.T) {
+	for _, seed := range []int64{0, 42} {
+		field := Curl2DField(64, 64, 4, 0.5, seed)
+		div, deriv := divergence2D(&field)
+		if deriv == 0 {
+			t.Fatalf("curl 2D with seed %v is constant", seed)
+		}
+		if div/deriv > maxRelativeDivergence {
+			t.Errorf("curl 2D with seed %v has a divergence of %v for derivatives up to %v", seed, div, deriv)
+		}
+	}
+
+	// the gradient of the potential is not divergence-free, which makes sure
+	// the divergence is actually measured
+	potential := Perlin2DField(64, 64, 4, 0.5, 0)
+	gradient := MakeField2D(64, 64, 2)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			gradient.Set(x, y, 0, deriveX(&potential, x, y, 0))
+			gradient.Set(x, y, 1, deriveY(&potential, x, y, 0))
+		}
+	}
+	if div, deriv := divergence2D(&gradient); div/deriv < 0.1 {
+		t.Errorf("gradient 2D has a divergence of %v for derivatives up to %v", div, deriv)
+	}
+}
+
+func TestCurl3DDivergence(t *testing.T) {
+	for _, seed := range []int64{0, 42} {
+		field := Curl3DField(24, 24, 24, 3, 0.5, seed, 0)
+		div, deriv := divergence3D(&field)
+		if deriv == 0 {
+			t.Fatalf("curl 3D with seed %v is constant", seed)
+		}
+		if div/deriv > maxRelativeDivergence {
+			t.Errorf("curl 3D with seed %v has a divergence of %v for derivatives up to %v", seed, div, deriv)
+		}
+	}
+
+	// the gradient of the potential is not divergence-free, which makes sure
+	// the divergence is actually measured
+	potential := Perlin3DField(24, 24, 24, 3, 1, 0, 0)
+	gradient := MakeField3D(24, 24, 24, 3)
+	for z := 0; z < 24; z++ {
+		for y := 0; y < 24; y++ {
+			for x := 0; x < 24; x++ {
+				gradient.Set(x, y, z, 0, derive3DX(&potential, x, y, z, 0))
+				gradient.Set(x, y, z, 1, derive3DY(&potential, x, y, z, 0))
+				gradient.Set(x, y, z, 2, derive3DZ(&potential, x, y, z, 0))
+			}
+		}
+	}
+	if div, deriv := divergence3D(&gradient); div/deriv < 0.1 {
+		t.Errorf("gradient 3D has a divergence of %v for derivatives up to %v", div, deriv)
+	}
+}
@@ -0,0 +1,221 @@
+package noise
+
+import (
+	"math"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
+)
+
+// Field2D stores a 2D noise image with full float precision.
+// The values of each channel are interleaved and stored row by row.
+type Field2D struct {
+	width    int
+	height   int
+	channels int
+	data     []float32
+}
+
+// Field3D stores a 3D noise volume with full float precision.
+// The values of each channel are interleaved and stored slice by slice,
+// each slice row by row.
+type Field3D struct {
+	width    int
+	height   int
+	depth    int
+	channels int
+	data     []float32
+}
+
+// MakeField2D constructs a field of the specified width, height and number of
+// channels with all values set to 0.
+func MakeField2D(width, height, channels int) Field2D {
+	return Field2D{
+		width:    width,
+		height:   height,
+		channels: channels,
+		data:     make([]float32, width*height*channels),
+	}
+}
+
+// MakeField2DFromData constructs a field of the specified width and height
+// from the provided data. The number of channels is derived from the length
+// of data.
+func MakeField2DFromData(width, height int, data []float32) Field2D {
+	return Field2D{
+		width:    width,
+		height:   height,
+		channels: len(data) / (width * height),
+		data:     data,
+	}
+}
+
+// GetWidth returns the width of the field.
+func (field *Field2D) GetWidth() int {
+	return field.width
+}
+
+// GetHeight returns the height of the field.
+func (field *Field2D) GetHeight() int {
+	return field.height
+}
+
+// GetChannels returns the number of channels of the field.
+func (field *Field2D) GetChannels() int {
+	return field.channels
+}
+
+// GetData returns a copy of the field's data.
+func (field *Field2D) GetData() []float32 {
+	cpy := make([]float32, len(field.data))
+	copy(cpy, field.data)
+	return cpy
+}
+
+// Get returns the value of channel c at (x,y).
+func (field *Field2D) Get(x, y, c int) float32 {
+	return field.data[field.getIdx(x, y)+c]
+}
+
+// Set sets the value of channel c at (x,y).
+func (field *Field2D) Set(x, y, c int, val float32) {
+	field.data[field.getIdx(x, y)+c] = val
+}
+
+// Range returns the smallest and biggest value of the field.
+func (field *Field2D) Range() (float32, float32) {
+	return valueRange(field.data)
+}
+
+// ToUint8 quantizes the values of the field to 8 bit.
+// Values are expected to be in the range 0 to 1 and are clamped otherwise.
+func (field *Field2D) ToUint8() []uint8 {
+	return toUint8(field.data)
+}
+
+// ToUint16 quantizes the values of the field to 16 bit.
+// Values are expected to be in the range 0 to 1 and are clamped otherwise.
+func (field *Field2D) ToUint16() []uint16 {
+	return toUint16(field.data)
+}
+
+// getIdx turns the x and y indices into a 1D index.
+func (field *Field2D) getIdx(x, y int) int {
+	return (x + y*field.width) * field.channels
+}
+
+// MakeField3D constructs a field of the specified width, height, depth and
+// number of channels with all values set to 0.
+func MakeField3D(width, height, depth, channels int) Field3D {
+	return Field3D{
+		width:    width,
+		height:   height,
+		depth:    depth,
+		channels: channels,
+		data:     make([]float32, width*height*depth*channels),
+	}
+}
+
+// MakeField3DFromData constructs a field of the specified width, height and
+// depth from the provided data. The number of channels is derived from the
+// length of data.
+func MakeField3DFromData(width, height, depth int, data []float32) Field3D {
+	return Field3D{
+		width:    width,
+		height:   height,
+		depth:    depth,
+		channels: len(data) / (width * height * depth),
+		data:     data,
+	}
+}
+
+// GetWidth returns the width of the field.
+func (field *Field3D) GetWidth() int {
+	return field.width
+}
+
+// GetHeight returns the height of the field.
+func (field *Field3D) GetHeight() int {
+	return field.height
+}
+
+// GetDepth returns the depth of the field.
+func (field *Field3D) GetDepth() int {
+	return field.depth
+}
+
+// GetChannels returns the number of channels of the field.
+func (field *Field3D) GetChannels() int {
+	return field.channels
+}
+
+// GetData returns a copy of the field's data.
+func (field *Field3D) GetData() []float32 {
+	cpy := make([]float32, len(field.data))
+	copy(cpy, field.data)
+	return cpy
+}
+
+// Get returns the value of channel c at (x,y,z).
+func (field *Field3D) Get(x, y, z, c int) float32 {
+	return field.data[field.getIdx(x, y, z)+c]
+}
+
+// Set sets the value of channel c at (x,y,z).
+func (field *Field3D) Set(x, y, z, c int, val float32) {
+	field.data[field.getIdx(x, y, z)+c] = val
+}
+
+// Range returns the smallest and biggest value of the field.
+func (field *Field3D) Range() (float32, float32) {
+	return valueRange(field.data)
+}
+
+// ToUint8 quantizes the values of the field to 8 bit.
+// Values are expected to be in the range 0 to 1 and are clamped otherwise.
+func (field *Field3D) ToUint8() []uint8 {
+	return toUint8(field.data)
+}
+
+// ToUint16 quantizes the values of the field to 16 bit.
+// Values are expected to be in the range 0 to 1 and are clamped otherwise.
+func (field *Field3D) ToUint16() []uint16 {
+	return toUint16(field.data)
+}
+
+// getIdx turns the x, y and z indices into a 1D index.
+func (field *Field3D) getIdx(x, y, z int) int {
+	return (x + y*field.width + z*field.width*field.height) * field.channels
+}
+
+// valueRange returns the smallest and biggest value of data.
+func valueRange(data []float32) (float32, float32) {
+	var (
+		min float32 = math.MaxFloat32
+		max float32 = -math.MaxFloat32
+	)
+	for _, val := range data {
+		min = cgm.Min32(min, val)
+		max = cgm.Max32(max, val)
+	}
+	return min, max
+}
+
+// toUint8 maps values between 0 and 1 to 0..255.
+func toUint8(data []float32) []uint8 {
+	result := make([]uint8, len(data))
+	for i, val := range data {
+		val = cgm.Clamp(val, 0, 1)
+		result[i] = uint8(val*255 + 0.5)
+	}
+	return result
+}
+
+// toUint16 maps values between 0 and 1 to 0..65535.
+func toUint16(data []float32) []uint16 {
+	result := make([]uint16, len(data))
+	for i, val := range data {
+		val = cgm.Clamp(val, 0, 1)
+		result[i] = uint16(val*65535 + 0.5)
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package noise
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFieldQuantize(t *testing.T) {
+	inf := float32(math.Inf(1))
+	tests := []struct {
+		name     string
+		val      float32
+		expected uint8
+		// expected 16 bit value
+		expected16 uint16
+	}{
+		{"zero", 0, 0, 0},
+		{"one", 1, 255, 65535},
+		{"half rounds up", 0.5, 128, 32768},
+		{"below half of a step", 0.49 / 255, 0, 126},
+		{"above half of a step", 0.51 / 255, 1, 131},
+		{"below half of a 16 bit step", 0.49 / 65535, 0, 0},
+		{"above half of a 16 bit step", 0.51 / 65535, 0, 1},
+		{"below one", 1 - 0.49/255, 255, 65409},
+		{"negative", -0.5, 0, 0},
+		{"above one", 2, 255, 65535},
+		{"negative infinity", -inf, 0, 0},
+		{"infinity", inf, 255, 65535},
+	}
+
+	for _, test := range tests {
+		field2d := MakeField2D(1, 1, 1)
+		field2d.Set(0, 0, 0, test.val)
+		field3d := MakeField3D(1, 1, 1, 1)
+		field3d.Set(0, 0, 0, 0, test.val)
+
+		if val := field2d.ToUint8()[0]; val != test.expected {
+			t.Errorf("%v: Field2D.ToUint8 returned %v instead of %v", test.name, val, test.expected)
+		}
+		if val := field3d.ToUint8()[0]; val != test.expected {
+			t.Errorf("%v: Field3D.ToUint8 returned %v instead of %v", test.name, val, test.expected)
+		}
+		if val := field2d.ToUint16()[0]; val != test.expected16 {
+			t.Errorf("%v: Field2D.ToUint16 returned %v instead of %v", test.name, val, test.expected16)
+		}
+		if val := field3d.ToUint16()[0]; val != test.expected16 {
+			t.Errorf("%v: Field3D.ToUint16 returned %v instead of %v", test.name, val, test.expected16)
+		}
+	}
+}
+
+func TestFieldInterleaving(t *testing.T) {
+	// channel c of pixel i has the value (i*3+c)/255, thus the quantized
+	// values count up in memory order
+	field2d := MakeField2D(2, 3, 3)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			for c := 0; c < 3; c++ {
+				field2d.Set(x, y, c, float32((x+y*2)*3+c)/255)
+			}
+		}
+	}
+	field3d := MakeField3D(2, 3, 2, 3)
+	for z := 0; z < 2; z++ {
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 2; x++ {
+				for c := 0; c < 3; c++ {
+					field3d.Set(x, y, z, c, float32((x+y*2+z*6)*3+c)/255)
+				}
+			}
+		}
+	}
+
+	tests := []struct {
+		name   string
+		data   []uint8
+		data16 []uint16
+		length int
+	}{
+		{"Field2D", field2d.ToUint8(), field2d.ToUint16(), 2 * 3 * 3},
+		{"Field3D", field3d.ToUint8(), field3d.ToUint16(), 2 * 3 * 2 * 3},
+	}
+	for _, test := range tests {
+		if len(test.data) != test.length || len(test.data16) != test.length {
+			t.Errorf("%v: %v 8 bit and %v 16 bit values instead of %v", test.name, len(test.data), len(test.data16), test.length)
+			continue
+		}
+		for i := range test.data {
+			if test.data[i] != uint8(i) {
+				t.Errorf("%v: 8 bit value %v is %v", test.name, i, test.data[i])
+			}
+			// 65535/255 is 257, thus 8 bit values map exactly to 16 bit
+			if test.data16[i] != uint16(i*257) {
+				t.Errorf("%v: 16 bit value %v is %v instead of %v", test.name, i, test.data16[i], i*257)
+			}
+		}
+	}
+}
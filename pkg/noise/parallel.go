@@ -0,0 +1,45 @@
+package noise
+
+import (
+	"runtime"
+	"sync"
+)
+
+// forEachSlice calls fn once for each slice between 0 and depth-1.
+// The slices are distributed across the specified number of workers, or
+// across one worker per CPU if workers is smaller than 1. Thus fn must only
+// write to data that belongs to the slice it had been called with.
+func forEachSlice(depth, workers int, fn func(z int)) {
+	// no need for goroutines if there is only one worker
+	n := workers
+	if n < 1 {
+		n = runtime.NumCPU()
+	}
+	if n > depth {
+		n = depth
+	}
+	if n <= 1 {
+		for z := 0; z < depth; z++ {
+			fn(z)
+		}
+		return
+	}
+
+	// hand out the slices to the workers
+	slices := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			for z := range slices {
+				fn(z)
+			}
+		}()
+	}
+	for z := 0; z < depth; z++ {
+		slices <- z
+	}
+	close(slices)
+	wg.Wait()
+}
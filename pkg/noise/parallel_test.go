@@ -0,0 +1,64 @@
+package noise
+
+import (
+	"fmt"
+	"testing"
+)
+
+// generators3D create a 3D field with the specified number of workers.
+var generators3D = []struct {
+	name     string
+	generate func(size, workers int) []float32
+}{
+	{"Worley3D", func(size, workers int) []float32 {
+		options := DefaultWorleyOptions(4)
+		options.Octaves = 2
+		options.Workers = workers
+		field, _, _ := Worley3DField(size, size, size, options, 1)
+		return field.data
+	}},
+	{"Perlin3D", func(size, workers int) []float32 {
+		field := Perlin3DField(size, size, size, 3, 1, 1, workers)
+		return field.data
+	}},
+	{"Curl3D", func(size, workers int) []float32 {
+		field := Curl3DField(size, size, size, 2, 0.5, 1, workers)
+		return field.data
+	}},
+	{"PerlinWorley3D", func(size, workers int) []float32 {
+		field, _ := PerlinWorley3DField(size, size, size, 4, 3, 4, 2, 1, workers)
+		return field.data
+	}},
+}
+
+func TestParallelMatchesSingleThreaded(t *testing.T) {
+	for _, generator := range generators3D {
+		expected := generator.generate(24, 1)
+		for _, workers := range []int{2, 3, 8, 0} {
+			if !equalData(expected, generator.generate(24, workers)) {
+				t.Errorf("%v with %v workers differs from the single-threaded result", generator.name, workers)
+			}
+		}
+	}
+}
+
+// benchmark reports the throughput of generating cubes of 32³, 64³ and 128³ voxels.
+func benchmark(b *testing.B, generate func(size int)) {
+	for _, size := range []int{32, 64, 128} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				generate(size)
+			}
+			voxels := float64(size*size*size) * float64(b.N)
+			b.ReportMetric(voxels/b.Elapsed().Seconds(), "voxels/s")
+		})
+	}
+}
+
+func BenchmarkWorley3D(b *testing.B) {
+	benchmark(b, func(size int) { Worley3D(size, size, size, 4, 1) })
+}
+
+func BenchmarkPerlin3D(b *testing.B) {
+	benchmark(b, func(size int) { Perlin3D(size, size, size, 3, 1, 1) })
+}
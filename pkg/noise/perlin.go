@@ -1,8 +1,9 @@
 package noise
 
 import (
+	"math/rand"
+
 	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
-	"github.com/go-gl/mathgl/mgl32"
 )
 
 // taken from http://flafla2.github.io/2014/08/09/perlinnoise.html
@@ -27,16 +28,28 @@ var permutation = []int{
 	138, 236, 205, 93, 222, 114, 67, 29, 24, 72, 243, 141, 128, 195, 78, 66, 215, 61, 156, 180,
 }
 
-var gradients3d = []mgl32.Vec3{
-	mgl32.Vec3{1, 1, 0}, mgl32.Vec3{-1, 1, 0}, mgl32.Vec3{1, -1, 0}, mgl32.Vec3{-1, -1, 0},
-	mgl32.Vec3{1, 0, 1}, mgl32.Vec3{-1, 0, 1}, mgl32.Vec3{1, 0, -1}, mgl32.Vec3{-1, 0, -1},
-	mgl32.Vec3{0, 1, 1}, mgl32.Vec3{0, -1, 1}, mgl32.Vec3{0, 1, -1}, mgl32.Vec3{0, -1, -1},
-}
-
-func makeperlin(repeat int) perlin {
+// basePeriod is the number of cells of the lowest octave of the tileable
+// perlin noise. With a single cell all lattice corners would wrap to the same
+// corner and thus share one gradient, which makes the lowest octave the same
+// for every seed.
+const basePeriod = 2
+
+// makeperlin sets up the perlin util with a permutation table that is
+// shuffled with the specified seed. The same seed always results in the
+// same permutation table.
+func makeperlin(repeat int, seed int64) perlin {
+	// shuffle a copy of the reference permutation
+	shuffled := make([]int, len(permutation))
+	copy(shuffled, permutation)
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	// repeat the permutation to avoid overflowing the table
 	var p [512]int
 	for i := 0; i < 512; i++ {
-		p[i] = permutation[i%256]
+		p[i] = shuffled[i%256]
 	}
 
 	return perlin{
@@ -45,30 +58,37 @@ func makeperlin(repeat int) perlin {
 	}
 }
 
-func (pln *perlin) octave(x, y, z float32, octaves int, persistence float32) float32 {
+// tileable returns fractal perlin noise at the position (x,y,z) that is
+// tileable between 0 and 1 in each dimension.
+// The lowest octave consists of repeat cells and each following octave
+// doubles the number of cells, thus each octave is tileable on its own.
+func (pln *perlin) tileable(x, y, z float32, octaves int, persistence float32) float32 {
 	var (
 		total     float32 = 0
-		frequency float32 = 1
+		period    int     = pln.repeat
 		amplitude float32 = 1
 		maxValue  float32 = 0
 	)
 	for i := 0; i < octaves; i++ {
-		total += pln.simple(x*frequency, y*frequency, z*frequency) * amplitude
+		frequency := float32(period)
+		total += pln.periodic(x*frequency, y*frequency, z*frequency, period) * amplitude
 
 		maxValue += amplitude
 
 		amplitude *= persistence
-		frequency *= 2
+		period *= 2
 	}
 
 	return total / maxValue
 }
 
-func (pln *perlin) simple(x, y, z float32) float32 {
-	if pln.repeat > 0 {
-		x = cgm.Mod32(x, float32(pln.repeat))
-		y = cgm.Mod32(y, float32(pln.repeat))
-		z = cgm.Mod32(z, float32(pln.repeat))
+// periodic returns perlin noise that repeats after period cells in each
+// dimension. A period of 0 disables the repetition.
+func (pln *perlin) periodic(x, y, z float32, period int) float32 {
+	if period > 0 {
+		x = cgm.Mod32(x, float32(period))
+		y = cgm.Mod32(y, float32(period))
+		z = cgm.Mod32(z, float32(period))
 	}
 
 	xi := int(x) & 255
@@ -81,14 +101,17 @@ func (pln *perlin) simple(x, y, z float32) float32 {
 	v := fade(yf)
 	w := fade(zf)
 
+	xn := inc(xi, period)
+	yn := inc(yi, period)
+	zn := inc(zi, period)
 	aaa := pln.p[pln.p[pln.p[xi]+yi]+zi]
-	aba := pln.p[pln.p[pln.p[xi]+pln.inc(yi)]+zi]
-	aab := pln.p[pln.p[pln.p[xi]+yi]+pln.inc(zi)]
-	abb := pln.p[pln.p[pln.p[xi]+pln.inc(yi)]+pln.inc(zi)]
-	baa := pln.p[pln.p[pln.p[pln.inc(xi)]+yi]+zi]
-	bba := pln.p[pln.p[pln.p[pln.inc(xi)]+pln.inc(yi)]+zi]
-	bab := pln.p[pln.p[pln.p[pln.inc(xi)]+yi]+pln.inc(zi)]
-	bbb := pln.p[pln.p[pln.p[pln.inc(xi)]+pln.inc(yi)]+pln.inc(zi)]
+	aba := pln.p[pln.p[pln.p[xi]+yn]+zi]
+	aab := pln.p[pln.p[pln.p[xi]+yi]+zn]
+	abb := pln.p[pln.p[pln.p[xi]+yn]+zn]
+	baa := pln.p[pln.p[pln.p[xn]+yi]+zi]
+	bba := pln.p[pln.p[pln.p[xn]+yn]+zi]
+	bab := pln.p[pln.p[pln.p[xn]+yi]+zn]
+	bbb := pln.p[pln.p[pln.p[xn]+yn]+zn]
 
 	x1 := cgm.Lerp(grad(aaa, xf, yf, zf), grad(baa, xf-1, yf, zf), u)
 	x2 := cgm.Lerp(grad(aba, xf, yf-1, zf), grad(bba, xf-1, yf-1, zf), u)
@@ -100,10 +123,12 @@ func (pln *perlin) simple(x, y, z float32) float32 {
 	return (cgm.Lerp(y1, y2, w) + 1) / 2
 }
 
-func (pln *perlin) inc(num int) int {
+// inc increments num and wraps it around after period.
+// A period of 0 disables the wrapping.
+func inc(num, period int) int {
 	num++
-	if pln.repeat > 0 {
-		num %= pln.repeat
+	if period > 0 {
+		num %= period
 	}
 	return num
 }
@@ -1,28 +1,32 @@
 package noise
 
-import (
-	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
-)
-
-// Perlin2D creates a 2D image with the specified number of octaves and persistance
-func Perlin2D(width, height, octaves int, persistance float32) []uint8 {
-	// determine maximum value of highest frequency
-	repeat := int(cgm.Pow32(2, float32(octaves-1)))
+// Perlin2D creates a 2D image with the specified number of octaves and persistance.
+// The seed determines the permutation table, thus the same seed always
+// results in the same image.
+func Perlin2D(width, height, octaves int, persistance float32, seed int64) []uint8 {
+	field := Perlin2DField(width, height, octaves, persistance, seed)
+	return field.ToUint8()
+}
 
+// Perlin2DField creates a 2D field with the specified number of octaves and persistance.
+// The lowest octave consists of two cells and each octave is tileable.
+// The seed determines the permutation table, thus the same seed always
+// results in the same field.
+// It returns a one channel field with values between 0 and 1.
+func Perlin2DField(width, height, octaves int, persistance float32, seed int64) Field2D {
 	// setup perlin util
-	perlin := makeperlin(repeat)
+	perlin := makeperlin(basePeriod, seed)
 
 	// calc random value for each pixel
-	var data []uint8
+	field := MakeField2D(width, height, 1)
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
-			fx := float32(x) / float32(width-1)
-			fy := float32(y) / float32(height-1)
-			rnd := perlin.octave(fx, fy, 0, octaves, persistance)
-			val := cgm.Map(rnd, 0, 1, 0, 255)
-			data = append(data, uint8(val))
+			fx := float32(x) / float32(width)
+			fy := float32(y) / float32(height)
+			rnd := perlin.tileable(fx, fy, 0, octaves, persistance)
+			field.Set(x, y, 0, rnd)
 		}
 	}
 
-	return data
+	return field
 }
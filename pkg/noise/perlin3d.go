@@ -1,22 +1,38 @@
 package noise
 
-import "github.com/adrianderstroff/realtime-clouds/pkg/cgm"
+// Perlin3D creates a 3D image of the size width x height x slices with the
+// specified resolution and persistance.
+// The seed determines the permutation table, thus the same seed always
+// results in the same image.
+func Perlin3D(width, height, slices, res, persistance int, seed int64) []uint8 {
+	field := Perlin3DField(width, height, slices, res, persistance, seed, 0)
+	return field.ToUint8()
+}
 
-func Perlin3D(width, height, slices, res, persistance int) []uint8 {
+// Perlin3DField creates a 3D field of the size width x height x slices with the
+// specified resolution and persistance.
+// The lowest octave consists of two cells and each octave is tileable.
+// The seed determines the permutation table, thus the same seed always
+// results in the same field. The slices are split across the specified number
+// of workers, one per CPU if it is smaller than 1.
+// It returns a one channel field with values between 0 and 1.
+func Perlin3DField(width, height, slices, res, persistance int, seed int64, workers int) Field3D {
 	// setup perlin util
-	perlin := makeperlin(4)
+	perlin := makeperlin(basePeriod, seed)
 
-	// calc random value for each pixel
-	var data []uint8
-	for z := 0; z < slices; z++ {
+	// calc random value for each pixel, the slices are processed in parallel
+	field := MakeField3D(width, height, slices, 1)
+	forEachSlice(slices, workers, func(z int) {
 		for y := 0; y < height; y++ {
 			for x := 0; x < width; x++ {
-				rnd := perlin.octave(float32(x)/float32(width-1), float32(y)/float32(height-1), float32(z)/float32(slices-1), res, float32(persistance))
-				val := cgm.Map(rnd, 0, 1, 0, 255)
-				data = append(data, uint8(val))
+				fx := float32(x) / float32(width)
+				fy := float32(y) / float32(height)
+				fz := float32(z) / float32(slices)
+				rnd := perlin.tileable(fx, fy, fz, res, float32(persistance))
+				field.Set(x, y, z, 0, rnd)
 			}
 		}
-	}
+	})
 
-	return data
+	return field
 }
@@ -0,0 +1,94 @@
+package noise
+
+import (
+	"testing"
+)
+
+// seeds that are compared against each other
+var testSeeds = []int64{0, 1, 2, 3, 4, 5}
+
+// checkDistinct fails if two seeds result in the same data or if the data of
+// a seed doesn't vary.
+func checkDistinct(t *testing.T, name string, generate func(seed int64) []float32) {
+	fields := make([][]float32, len(testSeeds))
+	for i, seed := range testSeeds {
+		fields[i] = generate(seed)
+
+		minval, maxval := fields[i][0], fields[i][0]
+		for _, val := range fields[i] {
+			if val < minval {
+				minval = val
+			}
+			if val > maxval {
+				maxval = val
+			}
+		}
+		if maxval-minval < 0.1 {
+			t.Errorf("%v with seed %v only varies between %v and %v", name, seed, minval, maxval)
+		}
+	}
+
+	for i := range fields {
+		for j := i + 1; j < len(fields); j++ {
+			if equalData(fields[i], fields[j]) {
+				t.Errorf("%v with seeds %v and %v is identical", name, testSeeds[i], testSeeds[j])
+			}
+		}
+	}
+}
+
+// checkAxisVariation fails if the lowest octave of the 2D perlin noise doesn't
+// vary along the x or the y axis.
+func checkAxisVariation(t *testing.T, field Field2D, seed int64) {
+	var dx, dy float32
+	for y := 0; y < field.height; y++ {
+		for x := 0; x < field.width; x++ {
+			dx += abs(field.Get(loop(x+1, field.width), y, 0) - field.Get(x, y, 0))
+			dy += abs(field.Get(x, loop(y+1, field.height), 0) - field.Get(x, y, 0))
+		}
+	}
+	if dx == 0 || dy == 0 {
+		t.Errorf("Perlin2DField with seed %v has variation %v along x and %v along y", seed, dx, dy)
+	}
+}
+
+func equalData(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(val float32) float32 {
+	if val < 0 {
+		return -val
+	}
+	return val
+}
+
+func TestPerlin2DSeeds(t *testing.T) {
+	checkDistinct(t, "Perlin2DField", func(seed int64) []float32 {
+		field := Perlin2DField(32, 32, 1, 0.5, seed)
+		checkAxisVariation(t, field, seed)
+		return field.data
+	})
+}
+
+func TestPerlin3DSeeds(t *testing.T) {
+	checkDistinct(t, "Perlin3DField", func(seed int64) []float32 {
+		field := Perlin3DField(16, 16, 16, 1, 1, seed, 0)
+		return field.data
+	})
+}
+
+func TestCurl3DSeeds(t *testing.T) {
+	checkDistinct(t, "Curl3DField", func(seed int64) []float32 {
+		field := Curl3DField(16, 16, 16, 1, 0.5, seed, 0)
+		return field.data
+	})
+}
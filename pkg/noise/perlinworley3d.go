@@ -0,0 +1,89 @@
+package noise
+
+import (
+	"fmt"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
+)
+
+// PerlinWorley3D creates a tileable 3D image of the size width x height x depth
+// that combines perlin and worley noise as used for the cloud base shape.
+// The perlin noise starts with perlinFreq cells and sums up perlinOctaves
+// octaves. The worley noise starts with worleyFreq cells and sums up
+// worleyOctaves octaves with each octave doubling the number of cells.
+// The seed determines the perlin permutation table and the worley points.
+// It returns a 1D slice of uint8 values between 0 and 255, or nil if any of
+// the frequencies or octaves isn't positive.
+func PerlinWorley3D(width, height, depth, perlinFreq, perlinOctaves, worleyFreq, worleyOctaves int, seed int64) []uint8 {
+	field, err := PerlinWorley3DField(width, height, depth, perlinFreq, perlinOctaves, worleyFreq, worleyOctaves, seed, 0)
+	if err != nil {
+		return nil
+	}
+	return field.ToUint8()
+}
+
+// PerlinWorley3DField creates a tileable 3D field of the size width x height x depth
+// that combines perlin and worley noise as used for the cloud base shape.
+// The perlin noise starts with perlinFreq cells and sums up perlinOctaves
+// octaves. The worley noise starts with worleyFreq cells and sums up
+// worleyOctaves octaves with each octave doubling the number of cells.
+// The seed determines the perlin permutation table and the worley points.
+// The slices are split across the specified number of workers, one per CPU if
+// it is smaller than 1.
+// It returns a one channel field with values between 0 and 1, or an error if
+// any of the frequencies or octaves isn't positive.
+func PerlinWorley3DField(width, height, depth, perlinFreq, perlinOctaves, worleyFreq, worleyOctaves int, seed int64, workers int) (Field3D, error) {
+	if perlinFreq < 1 {
+		return Field3D{}, fmt.Errorf("Perlin frequency has to be at least 1 but got %v", perlinFreq)
+	}
+	if perlinOctaves < 1 {
+		return Field3D{}, fmt.Errorf("Perlin octaves have to be at least 1 but got %v", perlinOctaves)
+	}
+
+	// worley noise with halving amplitudes per octave
+	options := DefaultWorleyOptions(worleyFreq)
+	options.Octaves = worleyOctaves
+	options.Workers = workers
+	if err := options.validate(); err != nil {
+		return Field3D{}, err
+	}
+
+	// create tileable perlin noise
+	perlin := makeperlin(perlinFreq, seed)
+	field := MakeField3D(width, height, depth, 1)
+	forEachSlice(depth, workers, func(z int) {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				fx := float32(x) / float32(width)
+				fy := float32(y) / float32(height)
+				fz := float32(z) / float32(depth)
+				rnd := perlin.tileable(fx, fy, fz, perlinOctaves, 0.5)
+				field.Set(x, y, z, 0, rnd)
+			}
+		}
+	})
+
+	// dilate the perlin noise to the full range of 0 to 1. A constant field,
+	// e.g. if only lattice points are sampled, is set to the middle instead.
+	pmin, pmax := field.Range()
+	for i, val := range field.data {
+		if pmin == pmax {
+			field.data[i] = 0.5
+		} else {
+			field.data[i] = cgm.Map(val, pmin, pmax, 0, 1)
+		}
+	}
+
+	// create the worley noise
+	worley, _, err := Worley3DField(width, height, depth, options, seed+1)
+	if err != nil {
+		return Field3D{}, err
+	}
+
+	// remap the perlin noise with the worley noise as the new minimum
+	for i, val := range field.data {
+		field.data[i] = cgm.Map(val, 0, 1, worley.data[i], 1)
+	}
+
+	return field, nil
+}
@@ -0,0 +1,89 @@
+package noise
+
+import (
+	"testing"
+)
+
+// seamRatio returns the mean difference across the seam along the axis
+// specified by the step (dx,dy,dz) divided by the mean difference between
+// all other neighboring pixels along the axis.
+func seamRatio(field *Field3D, dx, dy, dz int) float32 {
+	width, height, depth := field.GetWidth(), field.GetHeight(), field.GetDepth()
+	length := dx*width + dy*height + dz*depth
+
+	var seam, interior float32 = 0, 0
+	for z := 0; z < depth; z++ {
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				diff := abs(field.Get(x, y, z, 0) - field.Get((x+dx)%width, (y+dy)%height, (z+dz)%depth, 0))
+				if dx*x+dy*y+dz*z == length-1 {
+					seam += diff
+				} else {
+					interior += diff
+				}
+			}
+		}
+	}
+	return seam / interior * float32(length-1)
+}
+
+func TestPerlinWorley3DField(t *testing.T) {
+	field, err := PerlinWorley3DField(24, 24, 24, 4, 3, 4, 2, 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNormalized(t, "PerlinWorley3DField", field.GetData())
+	min, max := field.Range()
+	if min == max {
+		t.Errorf("field is constant %v", min)
+	}
+
+	// the field wraps around seamlessly along each axis
+	for axis, step := range [][3]int{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}} {
+		if ratio := seamRatio(&field, step[0], step[1], step[2]); ratio > 1.5 {
+			t.Errorf("seam of axis %v is %v times the interior differences", axis, ratio)
+		}
+	}
+}
+
+func TestPerlinWorley3DFieldConstantPerlin(t *testing.T) {
+	// sampling only the lattice points results in constant perlin noise,
+	// which leaves the worley noise mapped to the upper half
+	field, err := PerlinWorley3DField(4, 4, 4, 4, 2, 2, 1, 7, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkNormalized(t, "PerlinWorley3DField with constant perlin noise", field.GetData())
+	worley, _, err := Worley3DField(4, 4, 4, DefaultWorleyOptions(2), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, val := range field.GetData() {
+		if expected := 0.5 + 0.5*worley.GetData()[i]; abs(val-expected) > 1e-6 {
+			t.Errorf("value %v at %v isn't %v", val, i, expected)
+			break
+		}
+	}
+}
+
+func TestPerlinWorley3DFieldInvalid(t *testing.T) {
+	tests := []struct {
+		name                                                 string
+		perlinFreq, perlinOctaves, worleyFreq, worleyOctaves int
+	}{
+		{"zero perlin frequency", 0, 2, 2, 2},
+		{"negative perlin frequency", -4, 2, 2, 2},
+		{"zero perlin octaves", 2, 0, 2, 2},
+		{"negative perlin octaves", 2, -1, 2, 2},
+		{"zero worley frequency", 2, 2, 0, 2},
+		{"zero worley octaves", 2, 2, 2, 0},
+	}
+	for _, test := range tests {
+		if _, err := PerlinWorley3DField(8, 8, 8, test.perlinFreq, test.perlinOctaves, test.worleyFreq, test.worleyOctaves, 0, 1); err == nil {
+			t.Errorf("PerlinWorley3DField with %v: expected an error", test.name)
+		}
+		if PerlinWorley3D(8, 8, 8, test.perlinFreq, test.perlinOctaves, test.worleyFreq, test.worleyOctaves, 0) != nil {
+			t.Errorf("PerlinWorley3D with %v returned data", test.name)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package noise
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
+	"github.com/go-gl/mathgl/mgl32"
+)
+
+// WorleyOutput specifies which distance is returned by the worley generators.
+// Only the points of the cell of a pixel and of its neighboring cells are
+// searched, thus the distances are approximate. With euclidean and manhattan
+// distances the closest and especially the second closest point can lie two
+// cells away if the neighboring points are at the far sides of their cells.
+// Then a farther point is used and the distance is slightly too big, which
+// affects well below 1% of the pixels for F2 and fewer for F1.
+type WorleyOutput int
+
+const (
+	// InvertedF1 is 1 minus the distance to the closest point.
+	InvertedF1 WorleyOutput = iota
+	// F1 is the distance to the closest point.
+	F1
+	// F2 is the distance to the second closest point.
+	F2
+	// F2MinusF1 is the difference between the distances to the second
+	// closest and the closest point.
+	F2MinusF1
+)
+
+// DistanceMetric specifies how the distance between a pixel and a point is measured.
+type DistanceMetric int
+
+const (
+	// Euclidean is the length of the straight line between two points.
+	Euclidean DistanceMetric = iota
+	// Manhattan is the sum of the absolute differences of each dimension.
+	Manhattan
+	// Chebyshev is the biggest absolute difference of all dimensions.
+	Chebyshev
+)
+
+// NormalizationMode specifies how the upper bound of the distances is chosen.
+// Distances between 0 and the upper bound are mapped to 0..1, bigger
+// distances are clamped.
+type NormalizationMode int
+
+const (
+	// CellDiagonal uses the theoretical biggest distance, which is the
+	// diagonal of a cell for F1 and the diagonal of two neighboring cells
+	// for F2. It doesn't depend on the points, thus all seeds result in the
+	// same brightness.
+	CellDiagonal NormalizationMode = iota
+	// ObservedMax uses the biggest distance of the octave.
+	ObservedMax
+	// Percentile uses the distance below which the fraction specified by
+	// Value of all distances lie. Value has to be bigger than 0 and at most 1.
+	Percentile
+	// FixedRadius uses the distance specified by Value in pixels for the
+	// first octave. The radius is halved with each octave. Value has to be
+	// positive and finite.
+	FixedRadius
+)
+
+// Normalization specifies the normalization mode and its parameter.
+type Normalization struct {
+	Mode  NormalizationMode
+	Value float32
+}
+
+// RangeStats describes the distances of one octave and how they had been
+// normalized.
+// Min and Max are the smallest and biggest distance, Bound is the distance
+// that had been mapped to 1 and Clipped is the fraction of distances that
+// had been bigger than Bound.
+type RangeStats struct {
+	Min     float32
+	Max     float32
+	Bound   float32
+	Clipped float32
+}
+
+// WorleyOptions specifies the shape of the worley noise.
+// Res is the number of cells of the first octave in each dimension. Each
+// following octave doubles the number of cells and multiplies the amplitude
+// with the persistence.
+type WorleyOptions struct {
+	Res           int
+	Octaves       int
+	Persistence   float32
+	Output        WorleyOutput
+	Metric        DistanceMetric
+	Normalization Normalization
+	// Workers is the number of goroutines the slices of 3D noise are split
+	// across, one per CPU if it is smaller than 1. The result doesn't depend on it.
+	Workers int
+}
+
+// DefaultWorleyOptions returns the options for a single octave of inverted F1
+// worley noise with euclidean distances and res cells in each dimension.
+// The distances are normalized by the cell diagonal.
+func DefaultWorleyOptions(res int) WorleyOptions {
+	return WorleyOptions{
+		Res:           res,
+		Octaves:       1,
+		Persistence:   0.5,
+		Output:        InvertedF1,
+		Metric:        Euclidean,
+		Normalization: Normalization{Mode: CellDiagonal},
+	}
+}
+
+// validate returns an error if the options are out of range or would result
+// in an undefined field, e.g. no octaves or an upper bound of 0.
+func (options *WorleyOptions) validate() error {
+	if options.Res < 1 {
+		return fmt.Errorf("Worley resolution has to be at least 1 but got %v", options.Res)
+	}
+	if options.Octaves < 1 {
+		return fmt.Errorf("Worley octaves have to be at least 1 but got %v", options.Octaves)
+	}
+	if !(options.Persistence >= 0) {
+		return fmt.Errorf("Worley persistence can't be negative but got %v", options.Persistence)
+	}
+	if options.Output < InvertedF1 || options.Output > F2MinusF1 {
+		return fmt.Errorf("Unsupported worley output %v", options.Output)
+	}
+	if options.Metric < Euclidean || options.Metric > Chebyshev {
+		return fmt.Errorf("Unsupported distance metric %v", options.Metric)
+	}
+
+	switch options.Normalization.Mode {
+	case CellDiagonal, ObservedMax:
+	case Percentile:
+		if !(options.Normalization.Value > 0 && options.Normalization.Value <= 1) {
+			return fmt.Errorf("Percentile normalization needs a fraction in (0,1] but got %v", options.Normalization.Value)
+		}
+	case FixedRadius:
+		if !(options.Normalization.Value > 0 && options.Normalization.Value <= math.MaxFloat32) {
+			return fmt.Errorf("Fixed radius normalization needs a finite radius bigger than 0 but got %v", options.Normalization.Value)
+		}
+	default:
+		return fmt.Errorf("Unsupported normalization mode %v", options.Normalization.Mode)
+	}
+
+	return nil
+}
+
+// normalizeOctave maps the distances of an octave to 0..1 and returns the
+// statistics of the distances. The cell size of the octave is specified by
+// step, octave is the index of the octave.
+// An error is returned if all distances would be mapped to an upper bound of 0.
+func (options *WorleyOptions) normalizeOctave(distances []float32, step mgl32.Vec3, octave int) (RangeStats, error) {
+	// collect the statistics of the distances
+	stats := RangeStats{Min: math.MaxFloat32, Max: 0}
+	for _, dist := range distances {
+		stats.Min = cgm.Min32(stats.Min, dist)
+		stats.Max = cgm.Max32(stats.Max, dist)
+	}
+
+	// determine the upper bound
+	switch options.Normalization.Mode {
+	case CellDiagonal:
+		// the second closest point can be at most one cell further away
+		if options.Output == F2 || options.Output == F2MinusF1 {
+			step = doubleLargest(step)
+		}
+		stats.Bound = options.Metric.distance3D(mgl32.Vec3{}, step)
+	case ObservedMax:
+		stats.Bound = stats.Max
+	case Percentile:
+		stats.Bound = percentile(distances, options.Normalization.Value)
+	case FixedRadius:
+		stats.Bound = options.Normalization.Value / float32(int(1)<<uint(octave))
+	}
+	if !(stats.Bound > 0) {
+		return stats, fmt.Errorf("Octave %v has an upper bound of %v, thus its distances can't be normalized", octave, stats.Bound)
+	}
+
+	// map distance to 0..1
+	clipped := 0
+	for i, dist := range distances {
+		if dist > stats.Bound {
+			clipped++
+		}
+		distances[i] = options.Output.normalize(dist, stats.Bound)
+	}
+	stats.Clipped = float32(clipped) / float32(len(distances))
+
+	return stats, nil
+}
+
+// doubleLargest doubles the biggest component of v.
+func doubleLargest(v mgl32.Vec3) mgl32.Vec3 {
+	idx := 0
+	for i := 1; i < 3; i++ {
+		if v[i] > v[idx] {
+			idx = i
+		}
+	}
+	v[idx] *= 2
+	return v
+}
+
+// percentile returns the value below which the fraction p of all values lie.
+// p has to be between 0 and 1.
+func percentile(values []float32, p float32) float32 {
+	sorted := make([]float32, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return sorted[int(p*float32(len(sorted)-1))]
+}
+
+// distance2D returns the distance between p1 and p2 using the metric.
+func (metric DistanceMetric) distance2D(p1, p2 mgl32.Vec2) float32 {
+	d := p2.Sub(p1)
+	switch metric {
+	case Manhattan:
+		return cgm.Abs32(d.X()) + cgm.Abs32(d.Y())
+	case Chebyshev:
+		return cgm.Max32(cgm.Abs32(d.X()), cgm.Abs32(d.Y()))
+	}
+	return d.Len()
+}
+
+// distance3D returns the distance between p1 and p2 using the metric.
+func (metric DistanceMetric) distance3D(p1, p2 mgl32.Vec3) float32 {
+	d := p2.Sub(p1)
+	switch metric {
+	case Manhattan:
+		return cgm.Abs32(d.X()) + cgm.Abs32(d.Y()) + cgm.Abs32(d.Z())
+	case Chebyshev:
+		return cgm.Max32(cgm.Max32(cgm.Abs32(d.X()), cgm.Abs32(d.Y())), cgm.Abs32(d.Z()))
+	}
+	return d.Len()
+}
+
+// distance picks the distance specified by output from the distance to the
+// closest point f1 and the distance to the second closest point f2.
+// InvertedF1 picks f1 since the inversion is applied after normalization.
+func (output WorleyOutput) distance(f1, f2 float32) float32 {
+	switch output {
+	case F2:
+		return f2
+	case F2MinusF1:
+		return f2 - f1
+	}
+	return f1
+}
+
+// normalize maps the distance to 0..1 using maxdist as the upper bound.
+func (output WorleyOutput) normalize(dist, maxdist float32) float32 {
+	dist = cgm.Clamp(dist, 0, maxdist)
+	if output == InvertedF1 {
+		return cgm.Map(dist, 0, maxdist, 1, 0)
+	}
+	return cgm.Map(dist, 0, maxdist, 0, 1)
+}
+
+// keepClosest updates the distances to the closest point f1 and the second
+// closest point f2 with the distance dist.
+func keepClosest(f1, f2, dist float32) (float32, float32) {
+	if dist < f1 {
+		return dist, f1
+	}
+	if dist < f2 {
+		return f1, dist
+	}
+	return f1, f2
+}
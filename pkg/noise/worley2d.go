@@ -3,25 +3,89 @@ package noise
 import (
 	"math"
 	"math/rand"
-	"time"
 
 	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
-// Worley2D creates 3D worley noise of the size specified by length x width x height
-// with the specified resolution.
-// It returns a 1D slice of uint8 values between 0 and 255.
-func Worley2D(width, height, res int, radius float32) []uint8 {
-	data := []uint8{}
+// Worley2D creates 2D worley noise of the size specified by width x height
+// with the specified resolution. Distances are normalized by radius.
+// The seed determines the positions of the points, thus the same seed always
+// results in the same image.
+// It returns a 1D slice of uint8 values between 0 and 255, or nil if res or
+// radius isn't positive. Use Worley2DField to get the error instead.
+func Worley2D(width, height, res int, radius float32, seed int64) []uint8 {
+	options := DefaultWorleyOptions(res)
+	options.Normalization = Normalization{Mode: FixedRadius, Value: radius}
+	field, _, err := Worley2DField(width, height, options, seed)
+	if err != nil {
+		return nil
+	}
+	return field.ToUint8()
+}
+
+// Worley2DField creates 2D worley noise of the size specified by width x height
+// with the resolution, octaves, output, metric and normalization specified in
+// options. Each octave uses its own points and is tileable on its own.
+// The seed determines the positions of the points, thus the same seed always
+// results in the same image.
+// It returns a one channel field with values between 0 and 1 and the range
+// statistics of each octave, or an error if the options are invalid.
+func Worley2DField(width, height int, options WorleyOptions, seed int64) (Field2D, []RangeStats, error) {
+	if err := options.validate(); err != nil {
+		return Field2D{}, nil, err
+	}
+	field := MakeField2D(width, height, 1)
+
+	// sum up all octaves
+	var (
+		amplitude float32 = 1
+		maxValue  float32 = 0
+		stats     []RangeStats
+	)
+	for i := 0; i < options.Octaves; i++ {
+		res := options.Res << uint(i)
+		f1, f2 := worleyDistances2D(width, height, res, options.Metric, seed+int64(i))
+
+		// pick the distances specified by the output
+		distances := make([]float32, len(f1))
+		for j := range distances {
+			distances[j] = options.Output.distance(f1[j], f2[j])
+		}
+
+		// map distance to 0..1 and add it to the field
+		step := mgl32.Vec3{float32(width) / float32(res), float32(height) / float32(res), 0}
+		octaveStats, err := options.normalizeOctave(distances, step, i)
+		if err != nil {
+			return Field2D{}, nil, err
+		}
+		stats = append(stats, octaveStats)
+		for j, val := range distances {
+			field.data[j] += val * amplitude
+		}
+
+		maxValue += amplitude
+		amplitude *= options.Persistence
+	}
 
+	// keep the sum between 0 and 1
+	for j := range field.data {
+		field.data[j] /= maxValue
+	}
+
+	return field, stats, nil
+}
+
+// worleyDistances2D calculates the distances to the closest and second closest
+// point for each pixel of an image of size width x height with res x res cells.
+func worleyDistances2D(width, height, res int, metric DistanceMetric, seed int64) ([]float32, []float32) {
 	// divide volume into cells
 	xstep := float32(width) / float32(res)
 	ystep := float32(height) / float32(res)
 
 	// position randomly exactly one point per cell
 	points := make([][]mgl32.Vec2, res)
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r := rand.New(rand.NewSource(seed))
 	for y := 0; y < res; y++ {
 		points[y] = make([]mgl32.Vec2, res)
 		for x := 0; x < res; x++ {
@@ -31,22 +95,25 @@ func Worley2D(width, height, res int, radius float32) []uint8 {
 		}
 	}
 
-	// for each voxel find shortest distance to point in 27-neighborhood
-	// loop at the edges to have tileable noise
-	//var maxdist float32 = 0
-	voxels := make([][]float32, height)
+	// for each pixel find the shortest distances to the points in the
+	// 9-neighborhood, loop at the edges to have tileable noise
+	// the closest points can lie outside of it, see WorleyOutput
+	f1 := make([]float32, width*height)
+	f2 := make([]float32, width*height)
 	for y := 0; y < height; y++ {
-		voxels[y] = make([]float32, width)
 		for x := 0; x < width; x++ {
-			// center of current voxel
-			voxel := mgl32.Vec2{float32(x) + 0.5, float32(y) + 0.5}
+			// center of current pixel
+			pixel := mgl32.Vec2{float32(x) + 0.5, float32(y) + 0.5}
 
-			// get cell index of current voxel
+			// get cell index of current pixel
 			xcell := int(cgm.Floor32(float32(x) / xstep))
 			ycell := int(cgm.Floor32(float32(y) / ystep))
 
-			// calc distance to each point in 27-neighborhood
-			var mindist float32 = math.MaxFloat32
+			// calc distance to each point in 9-neighborhood
+			var (
+				mindist1 float32 = math.MaxFloat32
+				mindist2 float32 = math.MaxFloat32
+			)
 			for yd := -1; yd <= 1; yd++ {
 				for xd := -1; xd <= 1; xd++ {
 					// get position of point in current neighborhood cell
@@ -77,34 +144,18 @@ func Worley2D(width, height, res int, radius float32) []uint8 {
 					point = point.Add(mgl32.Vec2{xoff, yoff})
 
 					// calc distance to this point
-					dist := point.Sub(voxel).Len()
+					dist := metric.distance2D(point, pixel)
 
-					// keep the shortest distance
-					mindist = cgm.Min32(mindist, dist)
+					// keep the two shortest distances
+					mindist1, mindist2 = keepClosest(mindist1, mindist2, dist)
 				}
 			}
-			// each voxel stores the shortest distance to a point in any of
-			// the neighboring cells
-			voxels[y][x] = mindist
-
-			// bookkeeping of the biggest smallest distance for the following
-			// normalization step
-			//maxdist = cgm.Max32(maxdist, mindist)
-		}
-	}
-
-	//maxdist *= 0.9
-	maxdist := radius
-
-	// map distance to 0..255 and save in data slice
-	for y := 0; y < height; y++ {
-		for x := 0; x < width; x++ {
-			val := voxels[y][x]
-			val = cgm.Clamp(float32(val), 0, maxdist)
-			val = cgm.Map(val, 0, maxdist, 255, 0)
-			data = append(data, uint8(val))
+			// each pixel stores the shortest distances to the points in any
+			// of the neighboring cells
+			f1[x+y*width] = mindist1
+			f2[x+y*width] = mindist2
 		}
 	}
 
-	return data
+	return f1, f2
 }
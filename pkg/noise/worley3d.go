@@ -3,18 +3,81 @@ package noise
 import (
 	"math"
 	"math/rand"
-	"time"
 
 	"github.com/adrianderstroff/realtime-clouds/pkg/cgm"
 	"github.com/go-gl/mathgl/mgl32"
 )
 
 // Worley3D creates 3D worley noise of the size specified by length x width x height
-// with the specified resolution.
-// It returns a 1D slice of uint8 values between 0 and 255.
-func Worley3D(width, height, depth, res int) []uint8 {
-	data := []uint8{}
+// with the specified resolution. Distances are normalized by the cell diagonal.
+// The seed determines the positions of the points, thus the same seed always
+// results in the same image.
+// It returns a 1D slice of uint8 values between 0 and 255, or nil if res
+// isn't positive. Use Worley3DField to get the error instead.
+func Worley3D(width, height, depth, res int, seed int64) []uint8 {
+	field, _, err := Worley3DField(width, height, depth, DefaultWorleyOptions(res), seed)
+	if err != nil {
+		return nil
+	}
+	return field.ToUint8()
+}
+
+// Worley3DField creates 3D worley noise of the size specified by length x width x height
+// with the resolution, octaves, output, metric and normalization specified in
+// options. Each octave uses its own points and is tileable on its own.
+// The seed determines the positions of the points, thus the same seed always
+// results in the same volume.
+// It returns a one channel field with values between 0 and 1 and the range
+// statistics of each octave, or an error if the options are invalid.
+func Worley3DField(width, height, depth int, options WorleyOptions, seed int64) (Field3D, []RangeStats, error) {
+	if err := options.validate(); err != nil {
+		return Field3D{}, nil, err
+	}
+	field := MakeField3D(width, height, depth, 1)
+
+	// sum up all octaves
+	var (
+		amplitude float32 = 1
+		maxValue  float32 = 0
+		stats     []RangeStats
+	)
+	for i := 0; i < options.Octaves; i++ {
+		res := options.Res << uint(i)
+		f1, f2 := worleyDistances3D(width, height, depth, res, options.Metric, seed+int64(i), options.Workers)
+
+		// pick the distances specified by the output
+		distances := make([]float32, len(f1))
+		for j := range distances {
+			distances[j] = options.Output.distance(f1[j], f2[j])
+		}
 
+		// map distance to 0..1 and add it to the field
+		step := mgl32.Vec3{float32(width) / float32(res), float32(height) / float32(res), float32(depth) / float32(res)}
+		octaveStats, err := options.normalizeOctave(distances, step, i)
+		if err != nil {
+			return Field3D{}, nil, err
+		}
+		stats = append(stats, octaveStats)
+		for j, val := range distances {
+			field.data[j] += val * amplitude
+		}
+
+		maxValue += amplitude
+		amplitude *= options.Persistence
+	}
+
+	// keep the sum between 0 and 1
+	for j := range field.data {
+		field.data[j] /= maxValue
+	}
+
+	return field, stats, nil
+}
+
+// worleyDistances3D calculates the distances to the closest and second closest
+// point for each voxel of a volume of size width x height x depth with
+// res x res x res cells. The slices are split across the specified number of workers.
+func worleyDistances3D(width, height, depth, res int, metric DistanceMetric, seed int64, workers int) ([]float32, []float32) {
 	// divide volume into cells
 	xstep := float32(width) / float32(res)
 	ystep := float32(height) / float32(res)
@@ -22,7 +85,7 @@ func Worley3D(width, height, depth, res int) []uint8 {
 
 	// position randomly exactly one point per cell
 	points := make([][][]mgl32.Vec3, res)
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r := rand.New(rand.NewSource(seed))
 	for z := 0; z < res; z++ {
 		points[z] = make([][]mgl32.Vec3, res)
 		for y := 0; y < res; y++ {
@@ -36,14 +99,14 @@ func Worley3D(width, height, depth, res int) []uint8 {
 		}
 	}
 
-	// for each voxel find shortest distance to point in 27-neighborhood
+	// for each voxel find shortest distances to the points in 27-neighborhood
 	// loop at the edges to have tileable noise
-	var maxdist float32 = 0
-	voxels := make([][][]float32, depth)
-	for z := 0; z < depth; z++ {
-		voxels[z] = make([][]float32, height)
+	// the closest points can lie outside of it, see WorleyOutput
+	// the slices are processed in parallel
+	f1 := make([]float32, width*height*depth)
+	f2 := make([]float32, width*height*depth)
+	forEachSlice(depth, workers, func(z int) {
 		for y := 0; y < height; y++ {
-			voxels[z][y] = make([]float32, width)
 			for x := 0; x < width; x++ {
 				// center of current voxel
 				voxel := mgl32.Vec3{float32(x) + 0.5, float32(y) + 0.5, float32(z) + 0.5}
@@ -54,7 +117,10 @@ func Worley3D(width, height, depth, res int) []uint8 {
 				zcell := int(cgm.Floor32(float32(z) / zstep))
 
 				// calc distance to each point in 27-neighborhood
-				var mindist float32 = math.MaxFloat32
+				var (
+					mindist1 float32 = math.MaxFloat32
+					mindist2 float32 = math.MaxFloat32
+				)
 				for zd := -1; zd <= 1; zd++ {
 					for yd := -1; yd <= 1; yd++ {
 						for xd := -1; xd <= 1; xd++ {
@@ -94,37 +160,21 @@ func Worley3D(width, height, depth, res int) []uint8 {
 							point = point.Add(mgl32.Vec3{xoff, yoff, zoff})
 
 							// calc distance to this point
-							dist := point.Sub(voxel).Len()
+							dist := metric.distance3D(point, voxel)
 
-							// keep the shortest distance
-							mindist = cgm.Min32(mindist, dist)
+							// keep the two shortest distances
+							mindist1, mindist2 = keepClosest(mindist1, mindist2, dist)
 						}
 					}
 				}
-				// each voxel stores the shortest distance to a point in any of
-				// the neighboring cells
-				voxels[z][y][x] = mindist
-
-				// bookkeeping of the biggest smallest distance for the following
-				// normalization step
-				maxdist = cgm.Max32(maxdist, mindist)
-			}
-		}
-	}
-
-	maxdist *= 0.8
-
-	// map distance to 0..255 and save in data slice
-	for z := 0; z < depth; z++ {
-		for y := 0; y < height; y++ {
-			for x := 0; x < width; x++ {
-				val := voxels[z][y][x]
-				val = cgm.Clamp(float32(val), 0, maxdist)
-				val = cgm.Map(val, 0, maxdist, 255, 0)
-				data = append(data, uint8(val))
+				// each voxel stores the shortest distances to the points in
+				// any of the neighboring cells
+				idx := x + y*width + z*width*height
+				f1[idx] = mindist1
+				f2[idx] = mindist2
 			}
 		}
-	}
+	})
 
-	return data
+	return f1, f2
 }
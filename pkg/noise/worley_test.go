@@ -0,0 +1,183 @@
+package noise
+
+import (
+	"math"
+	"testing"
+)
+
+// checkNormalized fails if a value of the field is NaN or outside of 0..1.
+func checkNormalized(t *testing.T, name string, data []float32) {
+	for i, val := range data {
+		if math.IsNaN(float64(val)) || val < 0 || val > 1 {
+			t.Errorf("%v: value %v at %v is outside of 0..1", name, val, i)
+			return
+		}
+	}
+}
+
+func TestWorleyOptionsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(options *WorleyOptions)
+	}{
+		{"zero res", func(options *WorleyOptions) { options.Res = 0 }},
+		{"zero octaves", func(options *WorleyOptions) { options.Octaves = 0 }},
+		{"negative octaves", func(options *WorleyOptions) { options.Octaves = -1 }},
+		{"negative persistence", func(options *WorleyOptions) { options.Persistence = -0.5 }},
+		{"nan persistence", func(options *WorleyOptions) { options.Persistence = float32(math.NaN()) }},
+		{"output", func(options *WorleyOptions) { options.Output = F2MinusF1 + 1 }},
+		{"metric", func(options *WorleyOptions) { options.Metric = -1 }},
+		{"mode", func(options *WorleyOptions) { options.Normalization.Mode = FixedRadius + 1 }},
+		{"zero percentile", func(options *WorleyOptions) { options.Normalization = Normalization{Percentile, 0} }},
+		{"nan percentile", func(options *WorleyOptions) { options.Normalization = Normalization{Percentile, float32(math.NaN())} }},
+		{"zero radius", func(options *WorleyOptions) { options.Normalization = Normalization{FixedRadius, 0} }},
+		{"negative radius", func(options *WorleyOptions) { options.Normalization = Normalization{FixedRadius, -4} }},
+		{"percentile above 1", func(options *WorleyOptions) { options.Normalization = Normalization{Percentile, 1.5} }},
+		{"infinite radius", func(options *WorleyOptions) {
+			options.Normalization = Normalization{FixedRadius, float32(math.Inf(1))}
+		}},
+	}
+
+	for _, test := range tests {
+		options := DefaultWorleyOptions(4)
+		test.modify(&options)
+		if _, _, err := Worley2DField(16, 16, options, 0); err == nil {
+			t.Errorf("Worley2DField with %v: expected an error", test.name)
+		}
+		if _, _, err := Worley3DField(8, 8, 8, options, 0); err == nil {
+			t.Errorf("Worley3DField with %v: expected an error", test.name)
+		}
+	}
+
+	// the convenience functions return nil instead of an error
+	if Worley2D(16, 16, 4, 0, 0) != nil {
+		t.Error("Worley2D with a radius of 0 returned data")
+	}
+	if Worley3D(8, 8, 8, 0, 0) != nil {
+		t.Error("Worley3D with a resolution of 0 returned data")
+	}
+	if _, err := PerlinWorley3DField(8, 8, 8, 2, 2, 2, 0, 0, 1); err == nil {
+		t.Error("PerlinWorley3DField with 0 worley octaves: expected an error")
+	}
+}
+
+func TestWorleyOptionsValid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(options *WorleyOptions)
+	}{
+		{"default", func(options *WorleyOptions) {}},
+		{"octaves", func(options *WorleyOptions) { options.Octaves = 3 }},
+		{"zero persistence", func(options *WorleyOptions) { options.Octaves, options.Persistence = 3, 0 }},
+		{"f2", func(options *WorleyOptions) { options.Output = F2 }},
+		{"f2 minus f1", func(options *WorleyOptions) { options.Output = F2MinusF1 }},
+		{"manhattan", func(options *WorleyOptions) { options.Metric = Manhattan }},
+		{"chebyshev", func(options *WorleyOptions) { options.Metric = Chebyshev }},
+		{"observed max", func(options *WorleyOptions) { options.Normalization = Normalization{ObservedMax, 0} }},
+		{"percentile", func(options *WorleyOptions) { options.Normalization = Normalization{Percentile, 0.9} }},
+		{"fixed radius", func(options *WorleyOptions) {
+			options.Octaves = 2
+			options.Normalization = Normalization{FixedRadius, 3}
+		}},
+	}
+
+	for _, test := range tests {
+		options := DefaultWorleyOptions(4)
+		test.modify(&options)
+		field2D, stats2D, err := Worley2DField(16, 16, options, 0)
+		if err != nil {
+			t.Errorf("Worley2DField with %v: %v", test.name, err)
+			continue
+		}
+		checkNormalized(t, "Worley2DField with "+test.name, field2D.data)
+		field3D, stats3D, err := Worley3DField(8, 8, 8, options, 0)
+		if err != nil {
+			t.Errorf("Worley3DField with %v: %v", test.name, err)
+			continue
+		}
+		checkNormalized(t, "Worley3DField with "+test.name, field3D.data)
+		if len(stats2D) != options.Octaves || len(stats3D) != options.Octaves {
+			t.Errorf("%v: %v and %v range stats for %v octaves", test.name, len(stats2D), len(stats3D), options.Octaves)
+		}
+	}
+}
+
+func TestWorleyRangeStats(t *testing.T) {
+	tests := []struct {
+		name          string
+		normalization Normalization
+		output        WorleyOutput
+		// check fails if the stats of an octave are inconsistent
+		check func(stats RangeStats, octave int) bool
+	}{
+		{"cell diagonal", Normalization{CellDiagonal, 0}, F1, func(stats RangeStats, octave int) bool {
+			// the closest point is never further away than the cell diagonal
+			return stats.Max <= stats.Bound && stats.Clipped == 0
+		}},
+		{"cell diagonal f2", Normalization{CellDiagonal, 0}, F2, func(stats RangeStats, octave int) bool {
+			return stats.Max <= stats.Bound && stats.Clipped == 0
+		}},
+		{"observed max", Normalization{ObservedMax, 0}, F1, func(stats RangeStats, octave int) bool {
+			return stats.Bound == stats.Max && stats.Clipped == 0
+		}},
+		{"percentile", Normalization{Percentile, 0.8}, F1, func(stats RangeStats, octave int) bool {
+			return stats.Bound <= stats.Max && math.Abs(float64(stats.Clipped)-0.2) < 0.02
+		}},
+		{"fixed radius", Normalization{FixedRadius, 2}, F1, func(stats RangeStats, octave int) bool {
+			return stats.Bound == 2/float32(int(1)<<uint(octave)) && stats.Clipped > 0
+		}},
+	}
+
+	for _, test := range tests {
+		options := DefaultWorleyOptions(4)
+		options.Octaves = 2
+		options.Output = test.output
+		options.Normalization = test.normalization
+
+		// the cell diagonal and a fixed radius don't depend on the distances,
+		// thus all seeds report the same bound and a fixed radius is the same
+		// in 2D and 3D
+		var bounds2D, bounds3D []float32
+		for _, seed := range testSeeds {
+			_, stats2D, err := Worley2DField(32, 32, options, seed)
+			if err != nil {
+				t.Fatalf("%v: %v", test.name, err)
+			}
+			_, stats3D, err := Worley3DField(16, 16, 16, options, seed)
+			if err != nil {
+				t.Fatalf("%v: %v", test.name, err)
+			}
+
+			for octave := 0; octave < options.Octaves; octave++ {
+				for _, dim := range []struct {
+					name  string
+					stats RangeStats
+				}{{"Worley2D", stats2D[octave]}, {"Worley3D", stats3D[octave]}} {
+					stats := dim.stats
+					if stats.Min < 0 || stats.Min > stats.Max || stats.Clipped < 0 || stats.Clipped > 1 {
+						t.Errorf("%v %v seed %v octave %v: invalid stats %+v", dim.name, test.name, seed, octave, stats)
+					}
+					if !test.check(stats, octave) {
+						t.Errorf("%v %v seed %v octave %v: inconsistent stats %+v", dim.name, test.name, seed, octave, stats)
+					}
+				}
+			}
+			bounds2D = append(bounds2D, stats2D[0].Bound)
+			bounds3D = append(bounds3D, stats3D[0].Bound)
+		}
+
+		mode := test.normalization.Mode
+		if mode != CellDiagonal && mode != FixedRadius {
+			continue
+		}
+		for i := range bounds2D {
+			if bounds2D[i] != bounds2D[0] || bounds3D[i] != bounds3D[0] {
+				t.Errorf("%v: bounds differ between seeds, 2D %v and 3D %v", test.name, bounds2D, bounds3D)
+				break
+			}
+		}
+		if mode == FixedRadius && bounds2D[0] != bounds3D[0] {
+			t.Errorf("%v: 2D bound %v differs from 3D bound %v", test.name, bounds2D[0], bounds3D[0])
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package image2d
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"io/ioutil"
+)
+
+// pngSignature is at the beginning of each png file.
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// SaveAPNG saves the images as frames of an animated png at the specified path.
+// Delay is the time each frame is shown in 100ths of a second. All images need
+// to have the same size and are stored as 8 bit rgba frames. The animation loops forever.
+func SaveAPNG(filepath string, images []Image2D, delay int) error {
+	if len(images) == 0 {
+		return errors.New("No frames specified")
+	}
+	width, height := images[0].width, images[0].height
+	for _, image := range images {
+		if image.width != width || image.height != height {
+			return errors.New("All frames must have the same size")
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+
+	// header with 8 bit rgba and the number of frames
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], uint32(width))
+	binary.BigEndian.PutUint32(ihdr[4:], uint32(height))
+	ihdr[8], ihdr[9] = 8, 6
+	writePNGChunk(&buf, "IHDR", ihdr)
+	writePNGChunk(&buf, "acTL", []uint32{uint32(len(images)), 0})
+
+	// the first frame is stored as regular image data, all following ones as
+	// frame data, each prefixed with a sequence number
+	var sequence uint32
+	for i, image := range images {
+		data, err := image.encodeRGBA8()
+		if err != nil {
+			return err
+		}
+
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], sequence)
+		binary.BigEndian.PutUint32(fctl[4:], uint32(width))
+		binary.BigEndian.PutUint32(fctl[8:], uint32(height))
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:], 100)
+		writePNGChunk(&buf, "fcTL", fctl)
+		sequence++
+
+		if i == 0 {
+			writePNGChunk(&buf, "IDAT", data)
+			continue
+		}
+		fdat := make([]byte, 4, 4+len(data))
+		binary.BigEndian.PutUint32(fdat, sequence)
+		writePNGChunk(&buf, "fdAT", append(fdat, data...))
+		sequence++
+	}
+	writePNGChunk(&buf, "IEND", nil)
+
+	return ioutil.WriteFile(filepath, buf.Bytes(), 0644)
+}
+
+// encodeRGBA8 returns the zlib compressed scanlines of the image as 8 bit rgba.
+func (img *Image2D) encodeRGBA8() ([]byte, error) {
+	simage, err := img.toImage8()
+	if err != nil {
+		return nil, err
+	}
+
+	// expand gray images to rgba
+	var pix []uint8
+	switch simage := simage.(type) {
+	case *image.RGBA:
+		pix = simage.Pix
+	case *image.Gray:
+		pix = make([]uint8, len(simage.Pix)*4)
+		for i, val := range simage.Pix {
+			pix[i*4], pix[i*4+1], pix[i*4+2], pix[i*4+3] = val, val, val, 255
+		}
+	default:
+		return nil, errors.New("Unsupported image type")
+	}
+
+	// each scanline starts with filter type none
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	stride := img.width * 4
+	for y := 0; y < img.height; y++ {
+		writer.Write([]byte{0})
+		writer.Write(pix[y*stride : (y+1)*stride])
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writePNGChunk writes a chunk of the specified type whose data is value in
+// big endian byte order followed by its checksum.
+func writePNGChunk(buf *bytes.Buffer, typ string, value interface{}) {
+	var data bytes.Buffer
+	if value != nil {
+		binary.Write(&data, binary.BigEndian, value)
+	}
+
+	binary.Write(buf, binary.BigEndian, uint32(data.Len()))
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data.Bytes())
+	buf.WriteString(typ)
+	buf.Write(data.Bytes())
+	binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
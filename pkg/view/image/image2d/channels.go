@@ -0,0 +1,244 @@
+package image2d
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Split returns one single channel image for each channel of the image.
+// The images keep the pixel type of the image.
+func (image *Image2D) Split() ([]Image2D, error) {
+	var images []Image2D
+	for c := 0; c < image.channels; c++ {
+		channel, err := image.GetChannel(c)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, channel)
+	}
+
+	return images, nil
+}
+
+// GetChannel returns a single channel image with the values of channel c.
+// The image keeps the pixel type of the image.
+func (image *Image2D) GetChannel(c int) (Image2D, error) {
+	if err := image.checkChannel(c); err != nil {
+		return Image2D{}, err
+	}
+
+	size := image.getComponentSize()
+	data := make([]uint8, image.width*image.height*size)
+	for i := 0; i < image.width*image.height; i++ {
+		off := (i*image.channels + c) * size
+		copy(data[i*size:(i+1)*size], image.data[off:off+size])
+	}
+
+	return MakeFromRawData(image.width, image.height, 1, image.pixelType, data)
+}
+
+// Pack creates an image with one channel per source, e.g. four sources result
+// in an RGBA image. Each source has to be a single channel image and all
+// sources need to have the same size. Channels of sources that are nil are
+// filled with 0. The image has the pixel type of the first source that isn't nil.
+func Pack(sources ...*Image2D) (Image2D, error) {
+	if len(sources) < 1 || len(sources) > 4 {
+		return Image2D{}, errors.New("Number of sources must be between 1 and 4.")
+	}
+
+	// the first source that isn't nil determines size and pixel type
+	var first *Image2D
+	for _, source := range sources {
+		if source != nil {
+			first = source
+			break
+		}
+	}
+	if first == nil {
+		return Image2D{}, errors.New("At least one source must not be nil.")
+	}
+	for _, source := range sources {
+		if source == nil {
+			continue
+		}
+		if source.channels != 1 {
+			return Image2D{}, errors.New("Sources must have exactly one channel.")
+		}
+		if source.width != first.width || source.height != first.height {
+			return Image2D{}, errors.New("Source dimensions don't match.")
+		}
+	}
+
+	// interleave the values of the sources
+	size := first.getComponentSize()
+	channels := len(sources)
+	data := make([]uint8, first.width*first.height*channels*size)
+	image, err := MakeFromRawData(first.width, first.height, channels, first.pixelType, data)
+	if err != nil {
+		return Image2D{}, err
+	}
+	for c, source := range sources {
+		if source == nil {
+			continue
+		}
+		for i := 0; i < first.width*first.height; i++ {
+			if source.pixelType == image.pixelType {
+				copy(data[(i*channels+c)*size:(i*channels+c+1)*size], source.data[i*size:(i+1)*size])
+			} else {
+				image.setFloat(i*channels+c, source.getFloat(i))
+			}
+		}
+	}
+
+	return image, nil
+}
+
+// Swizzle creates an image whose channels are picked from the channels of the
+// image as specified by order. Each character of order results in one channel,
+// where r, g, b and a pick the first to fourth channel and 0 and 1 result in a
+// channel filled with 0 or 1. For example "bgr1" swaps red and blue and adds an
+// opaque alpha channel.
+func (image *Image2D) Swizzle(order string) (Image2D, error) {
+	if len(order) < 1 || len(order) > 4 {
+		return Image2D{}, errors.New("Swizzle order must have between 1 and 4 channels.")
+	}
+
+	size := image.getComponentSize()
+	channels := len(order)
+	data := make([]uint8, image.width*image.height*channels*size)
+	result, err := MakeFromRawData(image.width, image.height, channels, image.pixelType, data)
+	if err != nil {
+		return Image2D{}, err
+	}
+	for dst, char := range strings.ToLower(order) {
+		switch char {
+		case '0', '1':
+			result.FillChannel(dst, float32(char-'0'))
+		case 'r', 'g', 'b', 'a':
+			src := strings.IndexRune("rgba", char)
+			if err := image.checkChannel(src); err != nil {
+				return Image2D{}, err
+			}
+			for i := 0; i < image.width*image.height; i++ {
+				from := (i*image.channels + src) * size
+				to := (i*channels + dst) * size
+				copy(data[to:to+size], image.data[from:from+size])
+			}
+		default:
+			return Image2D{}, fmt.Errorf("Invalid swizzle channel %q", char)
+		}
+	}
+
+	return result, nil
+}
+
+// FillChannel sets channel c of all pixels to val.
+// For unsigned integers val is clamped to the range 0 to 1.
+func (image *Image2D) FillChannel(c int, val float32) error {
+	return image.mapChannel(c, func(float32) float32 {
+		return val
+	})
+}
+
+// GetChannelRange returns the smallest and biggest value of channel c.
+// Unsigned integers are mapped to the range 0 to 1.
+func (image *Image2D) GetChannelRange(c int) (float32, float32, error) {
+	if err := image.checkChannel(c); err != nil {
+		return 0, 0, err
+	}
+
+	var (
+		min float32 = math.MaxFloat32
+		max float32 = -math.MaxFloat32
+	)
+	for i := 0; i < image.width*image.height; i++ {
+		val := image.getFloat(i*image.channels + c)
+		if val < min {
+			min = val
+		}
+		if val > max {
+			max = val
+		}
+	}
+
+	return min, max, nil
+}
+
+// Remap linearly maps the values of channel c from the range oldMin to oldMax
+// to the range newMin to newMax. Unsigned integers are mapped to the range 0
+// to 1 before remapping and are clamped afterwards.
+func (image *Image2D) Remap(c int, oldMin, oldMax, newMin, newMax float32) error {
+	if oldMin == oldMax {
+		return errors.New("Old range must not be empty.")
+	}
+
+	return image.mapChannel(c, func(val float32) float32 {
+		return newMin + (val-oldMin)/(oldMax-oldMin)*(newMax-newMin)
+	})
+}
+
+// Threshold sets all values of channel c that are smaller than t to 0.
+// Unsigned integers are mapped to the range 0 to 1 before comparing them with t.
+func (image *Image2D) Threshold(c int, t float32) error {
+	return image.mapChannel(c, func(val float32) float32 {
+		if val < t {
+			return 0
+		}
+		return val
+	})
+}
+
+// Levels adjusts channel c like the levels tool of image editors.
+// Values are mapped from the input range inBlack to inWhite to 0..1 and
+// clamped, then gamma corrected and finally mapped to the output range
+// outBlack to outWhite. A gamma bigger than 1 brightens the mid tones.
+// Unsigned integers are mapped to the range 0 to 1 beforehand.
+func (image *Image2D) Levels(c int, inBlack, inWhite, gamma, outBlack, outWhite float32) error {
+	if inBlack == inWhite {
+		return errors.New("Input range must not be empty.")
+	}
+	if gamma <= 0 {
+		return errors.New("Gamma must be bigger than 0.")
+	}
+
+	return image.mapChannel(c, func(val float32) float32 {
+		return levels(val, inBlack, inWhite, gamma, outBlack, outWhite)
+	})
+}
+
+// levels applies the levels adjustment to val.
+func levels(val, inBlack, inWhite, gamma, outBlack, outWhite float32) float32 {
+	val = (val - inBlack) / (inWhite - inBlack)
+	if val < 0 {
+		val = 0
+	}
+	if val > 1 {
+		val = 1
+	}
+	val = float32(math.Pow(float64(val), float64(1/gamma)))
+	return outBlack + val*(outWhite-outBlack)
+}
+
+// mapChannel replaces each value of channel c with the result of fn.
+func (image *Image2D) mapChannel(c int, fn func(float32) float32) error {
+	if err := image.checkChannel(c); err != nil {
+		return err
+	}
+
+	for i := 0; i < image.width*image.height; i++ {
+		idx := i*image.channels + c
+		image.setFloat(idx, fn(image.getFloat(idx)))
+	}
+
+	return nil
+}
+
+// checkChannel returns an error if the image has no channel c.
+func (image *Image2D) checkChannel(c int) error {
+	if c < 0 || c >= image.channels {
+		return fmt.Errorf("Channel %v doesn't exist in an image with %v channels.", c, image.channels)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package image2d
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// makeRGB creates a 2x2 rgb image with 8 bit per channel whose red, green and
+// blue channels hold the values 0..3, 10..13 and 20..23.
+func makeRGB(t *testing.T) Image2D {
+	image, err := MakeFromData(2, 2, []uint8{
+		0, 10, 20, 1, 11, 21,
+		2, 12, 22, 3, 13, 23,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+// makeFloat creates a 2x2 image with one float channel holding the values.
+func makeFloat(t *testing.T, values ...float32) Image2D {
+	image, err := MakeFromFloat32(2, 2, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+// checkFloats fails if channel c of the image differs from expected.
+func checkFloats(t *testing.T, name string, image *Image2D, c int, expected ...float32) {
+	for i, val := range expected {
+		actual := image.GetFloat(i%image.width, i/image.width, c)
+		if math.Abs(float64(actual-val)) > 1e-6 {
+			t.Errorf("%v: pixel %v of channel %v is %v instead of %v", name, i, c, actual, val)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	image := makeRGB(t)
+	channels, err := image.Split()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 3 {
+		t.Fatalf("split into %v images instead of 3", len(channels))
+	}
+	for c, channel := range channels {
+		expected := []uint8{uint8(c * 10), uint8(c*10 + 1), uint8(c*10 + 2), uint8(c*10 + 3)}
+		if channel.GetChannels() != 1 || channel.GetPixelType() != gl.UNSIGNED_BYTE || !bytes.Equal(channel.GetData(), expected) {
+			t.Errorf("channel %v is %v with data %v instead of %v", c, channel, channel.GetData(), expected)
+		}
+	}
+
+	// the pixel type is kept
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	floats, err := float.Split()
+	if err != nil || len(floats) != 1 || floats[0].GetPixelType() != gl.FLOAT {
+		t.Fatalf("split of a float image failed: %v", err)
+	}
+	checkFloats(t, "float split", &floats[0], 0, 0.5, -1, 2, 3)
+
+	if _, err := image.GetChannel(3); err == nil {
+		t.Error("channel 3 of an rgb image: expected an error")
+	}
+	if _, err := image.GetChannel(-1); err == nil {
+		t.Error("channel -1: expected an error")
+	}
+}
+
+func TestPack(t *testing.T) {
+	image := makeRGB(t)
+	channels, err := image.Split()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// packing split channels results in the original image
+	packed, err := Pack(&channels[0], &channels[1], &channels[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(packed.GetData(), image.GetData()) {
+		t.Errorf("packed data is %v instead of %v", packed.GetData(), image.GetData())
+	}
+
+	// nil sources are filled with 0
+	packed, err = Pack(nil, &channels[2], nil, &channels[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{0, 20, 0, 0, 0, 21, 0, 1, 0, 22, 0, 2, 0, 23, 0, 3}
+	if packed.GetChannels() != 4 || !bytes.Equal(packed.GetData(), expected) {
+		t.Errorf("packed data is %v instead of %v", packed.GetData(), expected)
+	}
+
+	// sources of a different pixel type are converted to the first one
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	mixed, err := Pack(&float, &channels[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mixed.GetPixelType() != gl.FLOAT {
+		t.Errorf("pixel type is %v instead of float", mixed.GetPixelType())
+	}
+	checkFloats(t, "mixed float", &mixed, 0, 0.5, -1, 2, 3)
+	checkFloats(t, "mixed bytes", &mixed, 1, 0, 1.0/255, 2.0/255, 3.0/255)
+
+	small, err := Make(1, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := [][]*Image2D{
+		{},
+		{&channels[0], &channels[0], &channels[0], &channels[0], &channels[0]},
+		{nil, nil},
+		{&image},
+		{&channels[0], &small},
+	}
+	for _, sources := range invalid {
+		if _, err := Pack(sources...); err == nil {
+			t.Errorf("packing %v sources: expected an error", len(sources))
+		}
+	}
+}
+
+func TestSwizzle(t *testing.T) {
+	image := makeRGB(t)
+
+	swizzled, err := image.Swizzle("bgr1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{20, 10, 0, 255, 21, 11, 1, 255, 22, 12, 2, 255, 23, 13, 3, 255}
+	if !bytes.Equal(swizzled.GetData(), expected) {
+		t.Errorf("bgr1 is %v instead of %v", swizzled.GetData(), expected)
+	}
+
+	swizzled, err = image.Swizzle("G0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = []uint8{10, 0, 11, 0, 12, 0, 13, 0}
+	if !bytes.Equal(swizzled.GetData(), expected) {
+		t.Errorf("G0 is %v instead of %v", swizzled.GetData(), expected)
+	}
+
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	swizzled, err = float.Swizzle("1r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "float 1", &swizzled, 0, 1, 1, 1, 1)
+	checkFloats(t, "float r", &swizzled, 1, 0.5, -1, 2, 3)
+
+	for _, order := range []string{"", "rgbar", "rgx", "a"} {
+		if _, err := image.Swizzle(order); err == nil {
+			t.Errorf("swizzle %q: expected an error", order)
+		}
+	}
+}
+
+func TestFillChannel(t *testing.T) {
+	image := makeRGB(t)
+	if err := image.FillChannel(1, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	// unsigned integers are clamped
+	if err := image.FillChannel(2, 2); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{0, 128, 255, 1, 128, 255, 2, 128, 255, 3, 128, 255}
+	if !bytes.Equal(image.GetData(), expected) {
+		t.Errorf("filled data is %v instead of %v", image.GetData(), expected)
+	}
+
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	if err := float.FillChannel(0, -7); err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "float fill", &float, 0, -7, -7, -7, -7)
+
+	if err := image.FillChannel(3, 0); err == nil {
+		t.Error("filling channel 3 of an rgb image: expected an error")
+	}
+}
+
+func TestGetChannelRange(t *testing.T) {
+	image := makeRGB(t)
+	min, max, err := image.GetChannelRange(2)
+	if err != nil || min != 20.0/255 || max != 23.0/255 {
+		t.Errorf("range of blue is %v to %v instead of %v to %v: %v", min, max, 20.0/255, 23.0/255, err)
+	}
+
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	min, max, err = float.GetChannelRange(0)
+	if err != nil || min != -1 || max != 3 {
+		t.Errorf("range is %v to %v instead of -1 to 3: %v", min, max, err)
+	}
+
+	if _, _, err := image.GetChannelRange(5); err == nil {
+		t.Error("range of channel 5: expected an error")
+	}
+}
+
+func TestRemap(t *testing.T) {
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	if err := float.Remap(0, -1, 3, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "remap", &float, 0, 0.375, 0, 0.75, 1)
+
+	// unsigned integers are clamped after remapping
+	image := makeRGB(t)
+	if err := image.Remap(0, 0, 2.0/255, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{255, 10, 20, 128, 11, 21, 0, 12, 22, 0, 13, 23}
+	if !bytes.Equal(image.GetData(), expected) {
+		t.Errorf("remapped data is %v instead of %v", image.GetData(), expected)
+	}
+
+	if err := float.Remap(0, 1, 1, 0, 1); err == nil {
+		t.Error("remapping an empty range: expected an error")
+	}
+	if err := float.Remap(1, 0, 1, 0, 1); err == nil {
+		t.Error("remapping channel 1 of a single channel image: expected an error")
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	if err := float.Threshold(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "threshold", &float, 0, 0, 0, 2, 3)
+
+	image := makeRGB(t)
+	if err := image.Threshold(1, 12.0/255); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{0, 0, 20, 1, 0, 21, 2, 12, 22, 3, 13, 23}
+	if !bytes.Equal(image.GetData(), expected) {
+		t.Errorf("threshold data is %v instead of %v", image.GetData(), expected)
+	}
+
+	if err := image.Threshold(-1, 0); err == nil {
+		t.Error("threshold of channel -1: expected an error")
+	}
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		name                                        string
+		inBlack, inWhite, gamma, outBlack, outWhite float32
+		expected                                    []float32
+	}{
+		{"identity", 0, 1, 1, 0, 1, []float32{0, 0.25, 0.5, 1}},
+		{"input range", 0.25, 0.75, 1, 0, 1, []float32{0, 0, 0.5, 1}},
+		{"gamma", 0, 1, 2, 0, 1, []float32{0, 0.5, float32(math.Sqrt(0.5)), 1}},
+		{"output range", 0, 1, 1, 0.2, 0.6, []float32{0.2, 0.3, 0.4, 0.6}},
+		{"inverted", 0, 1, 1, 1, 0, []float32{1, 0.75, 0.5, 0}},
+	}
+	for _, test := range tests {
+		float := makeFloat(t, 0, 0.25, 0.5, 1)
+		if err := float.Levels(0, test.inBlack, test.inWhite, test.gamma, test.outBlack, test.outWhite); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		checkFloats(t, test.name, &float, 0, test.expected...)
+	}
+
+	float := makeFloat(t, 0, 0.25, 0.5, 1)
+	if err := float.Levels(0, 0.5, 0.5, 1, 0, 1); err == nil {
+		t.Error("levels with an empty input range: expected an error")
+	}
+	if err := float.Levels(0, 0, 1, 0, 0, 1); err == nil {
+		t.Error("levels with a gamma of 0: expected an error")
+	}
+	if err := float.Levels(2, 0, 1, 1, 0, 1); err == nil {
+		t.Error("levels of channel 2: expected an error")
+	}
+}
@@ -0,0 +1,116 @@
+package image2d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// pixel types of the OpenEXR format.
+const (
+	exrHalf  int32 = 1
+	exrFloat int32 = 2
+)
+
+// exrChannelNames maps the number of channels of an image to the channel names
+// of the OpenEXR format.
+var exrChannelNames = map[int][]string{
+	1: {"Y"},
+	2: {"R", "G"},
+	3: {"R", "G", "B"},
+	4: {"R", "G", "B", "A"},
+}
+
+// saveEXR writes the image as an uncompressed scanline OpenEXR file.
+// Half float images are stored as half floats, all other images as 32 bit
+// floats, with unsigned integers mapped to the range 0 to 1.
+func (img *Image2D) saveEXR(path string) error {
+	exrType, size := exrFloat, 4
+	if img.pixelType == gl.HALF_FLOAT {
+		exrType, size = exrHalf, 2
+	}
+
+	// the channels have to be stored in alphabetical order
+	names := exrChannelNames[img.channels]
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	for i := 1; i < len(order); i++ {
+		for j := i; j > 0 && names[order[j]] < names[order[j-1]]; j-- {
+			order[j], order[j-1] = order[j-1], order[j]
+		}
+	}
+
+	// magic number and version
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint32(20000630))
+	binary.Write(&buf, binary.LittleEndian, uint32(2))
+
+	// header attributes
+	var channels bytes.Buffer
+	for _, c := range order {
+		channels.WriteString(names[c])
+		channels.WriteByte(0)
+		binary.Write(&channels, binary.LittleEndian, exrType)
+		channels.Write([]byte{0, 0, 0, 0})
+		binary.Write(&channels, binary.LittleEndian, []int32{1, 1})
+	}
+	channels.WriteByte(0)
+	window := []int32{0, 0, int32(img.width - 1), int32(img.height - 1)}
+	writeEXRAttribute(&buf, "channels", "chlist", channels.Bytes())
+	writeEXRAttribute(&buf, "compression", "compression", []byte{0})
+	writeEXRAttribute(&buf, "dataWindow", "box2i", window)
+	writeEXRAttribute(&buf, "displayWindow", "box2i", window)
+	writeEXRAttribute(&buf, "lineOrder", "lineOrder", []byte{0})
+	writeEXRAttribute(&buf, "pixelAspectRatio", "float", float32(1))
+	writeEXRAttribute(&buf, "screenWindowCenter", "v2f", []float32{0, 0})
+	writeEXRAttribute(&buf, "screenWindowWidth", "float", float32(1))
+	buf.WriteByte(0)
+
+	// offset table with one entry per scanline
+	lineSize := img.width * img.channels * size
+	offset := uint64(buf.Len() + img.height*8)
+	for y := 0; y < img.height; y++ {
+		binary.Write(&buf, binary.LittleEndian, offset)
+		offset += uint64(8 + lineSize)
+	}
+
+	// each scanline stores all values of one channel after another
+	line := make([]byte, lineSize)
+	for y := 0; y < img.height; y++ {
+		off := 0
+		for _, c := range order {
+			for x := 0; x < img.width; x++ {
+				idx := img.getIdx(x, y) + c
+				if exrType == exrHalf {
+					copy(line[off:off+2], img.data[idx*2:idx*2+2])
+				} else {
+					binary.LittleEndian.PutUint32(line[off:], math.Float32bits(img.getFloat(idx)))
+				}
+				off += size
+			}
+		}
+		binary.Write(&buf, binary.LittleEndian, int32(y))
+		binary.Write(&buf, binary.LittleEndian, int32(lineSize))
+		buf.Write(line)
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// writeEXRAttribute writes a header attribute with the specified name, type and value.
+func writeEXRAttribute(buf *bytes.Buffer, name, typ string, value interface{}) {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, value)
+
+	buf.WriteString(name)
+	buf.WriteByte(0)
+	buf.WriteString(typ)
+	buf.WriteByte(0)
+	binary.Write(buf, binary.LittleEndian, int32(data.Len()))
+	buf.Write(data.Bytes())
+}
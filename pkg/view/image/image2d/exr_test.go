@@ -0,0 +1,169 @@
+package image2d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// exrFile holds the parts of an uncompressed scanline OpenEXR file that are
+// written by saveEXR.
+type exrFile struct {
+	names  []string
+	types  []int32
+	width  int
+	height int
+	// values holds the values of each channel row by row
+	values [][]float32
+}
+
+// readEXR parses the OpenEXR file at path.
+func readEXR(t *testing.T, path string) exrFile {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.LittleEndian.Uint32(data) != 20000630 || binary.LittleEndian.Uint32(data[4:]) != 2 {
+		t.Fatalf("wrong magic number or version % x", data[:8])
+	}
+
+	// read the header attributes up to the terminating 0
+	readString := func(off int) (string, int) {
+		end := off + bytes.IndexByte(data[off:], 0)
+		return string(data[off:end]), end + 1
+	}
+	var file exrFile
+	attributes := map[string]string{}
+	off := 8
+	for data[off] != 0 {
+		var name, typ string
+		name, off = readString(off)
+		typ, off = readString(off)
+		size := int(binary.LittleEndian.Uint32(data[off:]))
+		value := data[off+4 : off+4+size]
+		off += 4 + size
+		attributes[name] = typ
+
+		switch name {
+		case "channels":
+			for value[0] != 0 {
+				end := bytes.IndexByte(value, 0)
+				file.names = append(file.names, string(value[:end]))
+				file.types = append(file.types, int32(binary.LittleEndian.Uint32(value[end+1:])))
+				value = value[end+17:]
+			}
+		case "dataWindow":
+			var window [4]int32
+			binary.Read(bytes.NewReader(value), binary.LittleEndian, &window)
+			file.width, file.height = int(window[2]-window[0]+1), int(window[3]-window[1]+1)
+		case "compression":
+			if value[0] != 0 {
+				t.Errorf("compression is %v instead of 0", value[0])
+			}
+		}
+	}
+	off++
+	for _, name := range []string{"channels", "compression", "dataWindow", "displayWindow", "lineOrder", "pixelAspectRatio", "screenWindowCenter", "screenWindowWidth"} {
+		if _, ok := attributes[name]; !ok {
+			t.Errorf("required attribute %v is missing", name)
+		}
+	}
+
+	// each scanline stores the values of one channel after another
+	file.values = make([][]float32, len(file.names))
+	for y := 0; y < file.height; y++ {
+		line := int(binary.LittleEndian.Uint64(data[off+y*8:]))
+		if int(binary.LittleEndian.Uint32(data[line:])) != y {
+			t.Fatalf("scanline at offset %v isn't line %v", line, y)
+		}
+		pos := line + 8
+		for c, typ := range file.types {
+			for x := 0; x < file.width; x++ {
+				if typ == exrHalf {
+					file.values[c] = append(file.values[c], HalfToFloat(binary.LittleEndian.Uint16(data[pos:])))
+					pos += 2
+				} else {
+					file.values[c] = append(file.values[c], math.Float32frombits(binary.LittleEndian.Uint32(data[pos:])))
+					pos += 4
+				}
+			}
+		}
+		if size := int(binary.LittleEndian.Uint32(data[line+4:])); size != pos-line-8 {
+			t.Errorf("scanline %v has size %v instead of %v", y, size, pos-line-8)
+		}
+	}
+	if off+file.height*8 > len(data) {
+		t.Fatal("offset table is out of bounds")
+	}
+	return file
+}
+
+func TestSaveEXR(t *testing.T) {
+	dir := t.TempDir()
+
+	// the channels of float and byte images are stored as floats in
+	// alphabetical order
+	float, err := MakeFromFloat32(2, 2, []float32{
+		-1, 2, 0.5, 100, 0, 0.25,
+		3, -4, 5, 0.125, 1e6, 0,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "float.exr")
+	if err := float.SaveToPath(path); err != nil {
+		t.Fatal(err)
+	}
+	file := readEXR(t, path)
+	if file.width != 2 || file.height != 2 {
+		t.Errorf("size is %vx%v instead of 2x2", file.width, file.height)
+	}
+	if !reflect.DeepEqual(file.names, []string{"B", "G", "R"}) || !reflect.DeepEqual(file.types, []int32{exrFloat, exrFloat, exrFloat}) {
+		t.Errorf("channels are %v of type %v", file.names, file.types)
+	}
+	expected := [][]float32{{0.5, 0.25, 5, 0}, {2, 0, -4, 1e6}, {-1, 100, 3, 0.125}}
+	if !reflect.DeepEqual(file.values, expected) {
+		t.Errorf("values are %v instead of %v", file.values, expected)
+	}
+
+	rgba, err := MakeFromData(1, 2, []uint8{0, 255, 51, 255, 255, 0, 102, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(dir, "bytes.exr")
+	if err := rgba.SaveToPath(path); err != nil {
+		t.Fatal(err)
+	}
+	file = readEXR(t, path)
+	if !reflect.DeepEqual(file.names, []string{"A", "B", "G", "R"}) {
+		t.Errorf("channels are %v", file.names)
+	}
+	expected = [][]float32{{1, 0}, {0.2, 0.4}, {1, 0}, {0, 1}}
+	if !reflect.DeepEqual(file.values, expected) {
+		t.Errorf("values are %v instead of %v", file.values, expected)
+	}
+
+	// half float images keep their values
+	half, err := float.Convert(gl.HALF_FLOAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path = filepath.Join(dir, "half.exr")
+	if err := half.SaveToPath(path); err != nil {
+		t.Fatal(err)
+	}
+	file = readEXR(t, path)
+	if !reflect.DeepEqual(file.types, []int32{exrHalf, exrHalf, exrHalf}) {
+		t.Errorf("channel types are %v", file.types)
+	}
+	expected = [][]float32{{0.5, 0.25, 5, 0}, {2, 0, -4, float32(math.Inf(1))}, {-1, 100, 3, 0.125}}
+	if !reflect.DeepEqual(file.values, expected) {
+		t.Errorf("values are %v instead of %v", file.values, expected)
+	}
+}
@@ -1,30 +1,79 @@
 package image2d
 
 import (
+	"errors"
 	"image"
+	"image/color"
 	"image/color/palette"
 	"image/draw"
 	"image/gif"
 	"os"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
 )
 
-func SaveGif(filepath string, images []Image2D) error {
+// SaveGif saves the images as frames of an animated gif at the specified path.
+// Delay is the time each frame is shown in 100ths of a second. Single channel
+// images use a gray palette, all others the Plan 9 palette. The animation loops forever.
+func SaveGif(filepath string, images []Image2D, delay int) error {
+	if len(images) == 0 {
+		return errors.New("No frames specified")
+	}
+
+	// gray images keep all their shades with a gray palette
+	var pal color.Palette = palette.Plan9
+	if isGray(images) {
+		pal = make(color.Palette, 256)
+		for i := range pal {
+			pal[i] = color.Gray{uint8(i)}
+		}
+	}
+
 	outGif := &gif.GIF{}
 	for _, image2D := range images {
-		simage, err := image2D.ToImage()
+		simage, err := image2D.toImage8()
 		if err != nil {
 			return err
 		}
-		palettedImage := image.NewPaletted(simage.Bounds(), palette.Plan9)
-		draw.Draw(palettedImage, palettedImage.Rect, simage, simage.Bounds().Min, draw.Over)
+		palettedImage := image.NewPaletted(simage.Bounds(), pal)
+		draw.Draw(palettedImage, palettedImage.Rect, simage, simage.Bounds().Min, draw.Src)
 
 		outGif.Image = append(outGif.Image, palettedImage)
-		outGif.Delay = append(outGif.Delay, 0)
+		outGif.Delay = append(outGif.Delay, delay)
 	}
 
-	f, _ := os.OpenFile("rgb.gif", os.O_WRONLY|os.O_CREATE, 0600)
-	defer f.Close()
-	gif.EncodeAll(f, outGif)
+	f, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+	err = gif.EncodeAll(f, outGif)
+	if err != nil {
+		f.Close()
+		return err
+	}
 
-	return nil
+	return f.Close()
+}
+
+// isGray returns true if all images have exactly one channel.
+func isGray(images []Image2D) bool {
+	for _, image := range images {
+		if image.channels != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// toImage8 converts the image into the golang image format with 8 bit per channel.
+func (img *Image2D) toImage8() (image.Image, error) {
+	if img.pixelType == gl.UNSIGNED_BYTE {
+		return img.ToImage()
+	}
+
+	converted, err := img.Convert(gl.UNSIGNED_BYTE)
+	if err != nil {
+		return nil, err
+	}
+	return converted.ToImage()
 }
@@ -2,6 +2,7 @@
 package image2d
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"image"
@@ -9,7 +10,10 @@ import (
 	"image/draw"
 	_ "image/jpeg"
 	"image/png"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"unsafe"
 
 	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
@@ -72,6 +76,52 @@ func MakeFromData(width, height int, data []uint8) (Image2D, error) {
 	}, nil
 }
 
+// MakeFromUint16 constructs an image of the specified width and height that stores the
+// specified data with 16 bit per channel.
+func MakeFromUint16(width, height int, data []uint16) (Image2D, error) {
+	raw := make([]uint8, len(data)*2)
+	for i, val := range data {
+		binary.LittleEndian.PutUint16(raw[i*2:], val)
+	}
+	return MakeFromRawData(width, height, len(data)/(width*height), gl.UNSIGNED_SHORT, raw)
+}
+
+// MakeFromFloat32 constructs an image of the specified width and height that stores the
+// specified data with 32 bit floats per channel.
+func MakeFromFloat32(width, height int, data []float32) (Image2D, error) {
+	raw := make([]uint8, len(data)*4)
+	for i, val := range data {
+		binary.LittleEndian.PutUint32(raw[i*4:], math.Float32bits(val))
+	}
+	return MakeFromRawData(width, height, len(data)/(width*height), gl.FLOAT, raw)
+}
+
+// MakeFromRawData constructs an image of the specified width, height and number of channels
+// from the raw bytes of pixels of the specified pixel type. Multi byte values are expected
+// in little endian byte order.
+func MakeFromRawData(width, height, channels int, pixelType uint32, data []uint8) (Image2D, error) {
+	// early return if invalid dimensions had been specified
+	err := checkDimensions(width, height, channels)
+	if err != nil {
+		return Image2D{}, err
+	}
+	size, err := GetComponentSize(pixelType)
+	if err != nil {
+		return Image2D{}, err
+	}
+	if len(data) != width*height*channels*size {
+		return Image2D{}, fmt.Errorf("Expected %v bytes but got %v", width*height*channels*size, len(data))
+	}
+
+	return Image2D{
+		pixelType: pixelType,
+		width:     width,
+		height:    height,
+		channels:  channels,
+		data:      data,
+	}, nil
+}
+
 // MakeFromPath constructs the image data from the specified path.
 // If there is no image at the specified path an error is returned instead.
 func MakeFromPath(path string) (Image2D, error) {
@@ -104,6 +154,14 @@ func MakeFromPath(path string) (Image2D, error) {
 		channels = 1
 	}
 
+	// images with 16 bit per channel keep their precision
+	if colormodel == color.Alpha16Model ||
+		colormodel == color.Gray16Model ||
+		colormodel == color.RGBA64Model ||
+		colormodel == color.NRGBA64Model {
+		return makeFromImage16(img, channels)
+	}
+
 	// early return if invalid dimensions had been specified
 	err = checkDimensions(width, height, channels)
 	if err != nil {
@@ -132,11 +190,62 @@ func MakeFromPath(path string) (Image2D, error) {
 	}, nil
 }
 
+// makeFromImage16 extracts the data of an image with 16 bit per channel.
+func makeFromImage16(img image.Image, channels int) (Image2D, error) {
+	rect := img.Bounds()
+	data := make([]uint16, 0, rect.Dx()*rect.Dy()*channels)
+	switch channels {
+	case 1:
+		gray := image.NewGray16(rect)
+		draw.Draw(gray, rect, img, rect.Min, draw.Src)
+		for i := 0; i < len(gray.Pix); i += 2 {
+			data = append(data, binary.BigEndian.Uint16(gray.Pix[i:]))
+		}
+	case 4:
+		rgba := image.NewNRGBA64(rect)
+		draw.Draw(rgba, rect, img, rect.Min, draw.Src)
+		for i := 0; i < len(rgba.Pix); i += 2 {
+			data = append(data, binary.BigEndian.Uint16(rgba.Pix[i:]))
+		}
+	}
+
+	return MakeFromUint16(rect.Dx(), rect.Dy(), data)
+}
+
+// MakeFromFrameBuffer reads the pixels of the currently bound read frame buffer
+// into an 8 bit image of the specified width, height and number of channels.
 func MakeFromFrameBuffer(width, height, channels int) (Image2D, error) {
-	data := make([]uint8, width*height*channels)
-	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(data))
+	return MakeFromFrameBufferWithType(width, height, channels, gl.UNSIGNED_BYTE)
+}
+
+// MakeFromFrameBufferWithType reads the pixels of the currently bound read frame
+// buffer into an image of the specified width, height, number of channels and
+// pixel type, e.g. gl.FLOAT to keep the full range of floating point attachments.
+func MakeFromFrameBufferWithType(width, height, channels int, pixelType uint32) (Image2D, error) {
+	// early return if invalid dimensions had been specified
+	err := checkDimensions(width, height, channels)
+	if err != nil {
+		return Image2D{}, err
+	}
+	format, err := GetFormat(channels)
+	if err != nil {
+		return Image2D{}, err
+	}
+	size, err := GetComponentSize(pixelType)
+	if err != nil {
+		return Image2D{}, err
+	}
+
+	// rows of the image are tightly packed
+	data := make([]uint8, width*height*channels*size)
+	var previousAlignment int32
+	gl.GetIntegerv(gl.PACK_ALIGNMENT, &previousAlignment)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(width), int32(height), format, pixelType, gl.Ptr(data))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, previousAlignment)
+
 	img := Image2D{
-		pixelType: uint32(gl.UNSIGNED_BYTE),
+		pixelType: pixelType,
 		width:     width,
 		height:    height,
 		channels:  channels,
@@ -146,166 +255,97 @@ func MakeFromFrameBuffer(width, height, channels int) (Image2D, error) {
 	return img, nil
 }
 
-// SaveToPath saves the image at the specified path in the png format.
-// The specified image path has to have the fileextension .png.
+// SaveToPath saves the image at the specified path.
+// Paths with the fileextension .exr are saved in the OpenEXR format with 32 bit floats,
+// or half floats if the image stores half floats, thus keeping the full range of the values.
+// All other paths are saved in the png format. Images with more than 8 bit per channel are
+// saved as 16 bit png.
 // An error is thrown if the path is not valid or any of the specified
 // directories don't exist.
 func (img *Image2D) SaveToPath(path string) error {
-	// create a file at the specified path
-	file, err := os.Create(path)
-	if err != nil {
-		return err
+	if strings.ToLower(filepath.Ext(path)) == ".exr" {
+		return img.saveEXR(path)
 	}
 
 	// write data back into the golang image format
-	rect := image.Rect(0, 0, img.width, img.height)
-	switch img.channels {
-	case 1:
-		// fill image data
-		out := image.NewGray(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idx := img.getIdx(x, y)
-				out.Pix[idx] = img.data[idx]
-			}
-		}
-
-		// write image into file
-		if err := png.Encode(file, out); err != nil {
-			return err
-		}
-	case 2:
-		// fill image data
-		out := image.NewRGBA(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idxsrc := img.getIdx(x, y)
-				idxdst := (x + y*img.width) * 4
-				out.Pix[idxdst] = img.data[idxsrc]
-				out.Pix[idxdst+1] = img.data[idxsrc+1]
-				out.Pix[idxdst+2] = 0
-				out.Pix[idxdst+3] = 255
-			}
-		}
-
-		// write image into file
-		if err := png.Encode(file, out); err != nil {
-			return err
-		}
-	case 3:
-		// fill image data
-		out := image.NewRGBA(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idxsrc := img.getIdx(x, y)
-				idxdst := (x + y*img.width) * 4
-				out.Pix[idxdst] = img.data[idxsrc]
-				out.Pix[idxdst+1] = img.data[idxsrc+1]
-				out.Pix[idxdst+2] = img.data[idxsrc+2]
-				out.Pix[idxdst+3] = 255
-			}
-		}
-
-		// write image into file
-		if err := png.Encode(file, out); err != nil {
-			return err
-		}
-	case 4:
-		// fill image data
-		out := image.NewRGBA(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idx := img.getIdx(x, y)
-				out.Pix[idx] = img.data[idx]
-				out.Pix[idx+1] = img.data[idx+1]
-				out.Pix[idx+2] = img.data[idx+2]
-				out.Pix[idx+3] = img.data[idx+3]
-			}
-		}
+	out, err := img.ToImage()
+	if err != nil {
+		return err
+	}
 
-		// write image into file
-		if err := png.Encode(file, out); err != nil {
-			return err
-		}
+	// create a file at the specified path
+	file, err := os.Create(path)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	return nil
+	// write image into file
+	return png.Encode(file, out)
 }
 
+// ToImage converts the image into the golang image format.
+// Images with one channel become gray images, all others become rgba images
+// with missing channels set to 0 and a missing alpha channel set to opaque.
+// Images with more than 8 bit per channel are converted to 16 bit images.
 func (img *Image2D) ToImage() (image.Image, error) {
-	// write data back into the golang image format
 	rect := image.Rect(0, 0, img.width, img.height)
-	switch img.channels {
-	case 1:
-		// fill image data
-		out := image.NewGray(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idx := img.getIdx(x, y)
-				out.Pix[idx] = img.data[idx]
-			}
-		}
+	if img.channels < 1 || img.channels > 4 {
+		emptyRect := image.Rectangle{image.Point{0, 0}, image.Point{0, 0}}
+		return image.NewRGBA(emptyRect), errors.New("Unsupported number of channels")
+	}
 
-		return out, nil
-	case 2:
-		// fill image data
-		out := image.NewRGBA(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idxsrc := img.getIdx(x, y)
-				idxdst := (x + y*img.width) * 4
-				out.Pix[idxdst] = img.data[idxsrc]
-				out.Pix[idxdst+1] = img.data[idxsrc+1]
-				out.Pix[idxdst+2] = 0
-				out.Pix[idxdst+3] = 255
-			}
+	// 8 bit images
+	if img.pixelType == gl.UNSIGNED_BYTE {
+		if img.channels == 1 {
+			out := image.NewGray(rect)
+			copy(out.Pix, img.data)
+			return out, nil
 		}
 
-		return out, nil
-	case 3:
-		// fill image data
 		out := image.NewRGBA(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idxsrc := img.getIdx(x, y)
-				idxdst := (x + y*img.width) * 4
-				out.Pix[idxdst] = img.data[idxsrc]
-				out.Pix[idxdst+1] = img.data[idxsrc+1]
-				out.Pix[idxdst+2] = img.data[idxsrc+2]
-				out.Pix[idxdst+3] = 255
+		for i := 0; i < img.width*img.height; i++ {
+			idxsrc := i * img.channels
+			idxdst := i * 4
+			out.Pix[idxdst+3] = 255
+			for c := 0; c < img.channels; c++ {
+				out.Pix[idxdst+c] = img.data[idxsrc+c]
 			}
 		}
-
 		return out, nil
-	case 4:
-		// fill image data
-		out := image.NewRGBA(rect)
-		for y := 0; y < img.height; y++ {
-			for x := 0; x < img.width; x++ {
-				idx := img.getIdx(x, y)
-				out.Pix[idx] = img.data[idx]
-				out.Pix[idx+1] = img.data[idx+1]
-				out.Pix[idx+2] = img.data[idx+2]
-				out.Pix[idx+3] = img.data[idx+3]
-			}
+	}
+
+	// 16 bit images store their values in big endian byte order
+	values := img.GetUint16Data()
+	if img.channels == 1 {
+		out := image.NewGray16(rect)
+		for i, val := range values {
+			binary.BigEndian.PutUint16(out.Pix[i*2:], val)
 		}
 		return out, nil
 	}
 
-	emptyRect := image.Rectangle{image.Point{0, 0}, image.Point{0, 0}}
-	return image.NewRGBA(emptyRect), errors.New("Unsupported number of channels")
+	out := image.NewNRGBA64(rect)
+	for i := 0; i < img.width*img.height; i++ {
+		idxsrc := i * img.channels
+		idxdst := i * 8
+		binary.BigEndian.PutUint16(out.Pix[idxdst+6:], 65535)
+		for c := 0; c < img.channels; c++ {
+			binary.BigEndian.PutUint16(out.Pix[idxdst+c*2:], values[idxsrc+c])
+		}
+	}
+	return out, nil
 }
 
 // FlipX changes the order of the columns by swapping the first column of a row with the
 // last column of the same row, the second column of this row with the second last column of this row etc.
 func (image *Image2D) FlipX() {
+	size := image.getPixelSize()
 	var tempdata []uint8
 	for row := 0; row < image.height; row++ {
 		for col := image.width - 1; col >= 0; col-- {
-			idx := image.getIdx(col, row)
-			for c := 0; c < image.channels; c++ {
-				tempdata = append(tempdata, image.data[idx+c])
-			}
+			off := image.getIdx(col, row) * image.getComponentSize()
+			tempdata = append(tempdata, image.data[off:off+size]...)
 		}
 	}
 	image.data = tempdata
@@ -314,14 +354,11 @@ func (image *Image2D) FlipX() {
 // FlipY changes the order of the rows by swapping the first row with the
 // last row, the second row with the second last row etc.
 func (image *Image2D) FlipY() {
+	size := image.getPixelSize() * image.width
 	var tempdata []uint8
 	for row := image.height - 1; row >= 0; row-- {
-		for col := 0; col < image.width; col++ {
-			idx := image.getIdx(col, row)
-			for c := 0; c < image.channels; c++ {
-				tempdata = append(tempdata, image.data[idx+c])
-			}
-		}
+		off := row * size
+		tempdata = append(tempdata, image.data[off:off+size]...)
 	}
 	image.data = tempdata
 }
@@ -351,104 +388,232 @@ func (image *Image2D) GetDataPointer() unsafe.Pointer {
 	return gl.Ptr(image.data)
 }
 
-// GetData returns a copy of the image's data
+// GetData returns a copy of the image's raw data.
+// Multi byte values are stored in little endian byte order.
 func (image *Image2D) GetData() []uint8 {
 	cpy := make([]uint8, len(image.data))
 	copy(cpy, image.data)
 	return cpy
 }
 
+// GetUint16Data returns a copy of the image's data converted to 16 bit per channel.
+// Floats are clamped to the range 0 to 1.
+func (image *Image2D) GetUint16Data() []uint16 {
+	values := make([]uint16, image.width*image.height*image.channels)
+	for i := range values {
+		values[i] = image.getUint16(i)
+	}
+	return values
+}
+
+// GetFloat32Data returns a copy of the image's data converted to 32 bit floats.
+// Unsigned integers are mapped to the range 0 to 1.
+func (image *Image2D) GetFloat32Data() []float32 {
+	values := make([]float32, image.width*image.height*image.channels)
+	for i := range values {
+		values[i] = image.getFloat(i)
+	}
+	return values
+}
+
+// Convert returns a copy of the image that stores its pixels with the specified pixel type.
+// Supported pixel types are gl.UNSIGNED_BYTE, gl.UNSIGNED_SHORT, gl.HALF_FLOAT and gl.FLOAT.
+// Floats are clamped to the range 0 to 1 when being converted to unsigned integers.
+func (image *Image2D) Convert(pixelType uint32) (Image2D, error) {
+	size, err := GetComponentSize(pixelType)
+	if err != nil {
+		return Image2D{}, err
+	}
+
+	count := image.width * image.height * image.channels
+	data := make([]uint8, count*size)
+	for i := 0; i < count; i++ {
+		switch {
+		case pixelType == gl.UNSIGNED_SHORT:
+			binary.LittleEndian.PutUint16(data[i*2:], image.getUint16(i))
+		case pixelType == gl.UNSIGNED_BYTE && image.pixelType == gl.UNSIGNED_BYTE:
+			data[i] = image.data[i]
+		default:
+			setComponent(data, i*size, pixelType, image.getFloat(i))
+		}
+	}
+
+	return MakeFromRawData(image.width, image.height, image.channels, pixelType, data)
+}
+
+// GetFloat returns the value of channel c of the pixel at (x,y) as float.
+// Unsigned integers are mapped to the range 0 to 1.
+func (image *Image2D) GetFloat(x, y, c int) float32 {
+	return image.getFloat(image.getIdx(x, y) + c)
+}
+
+// SetFloat sets the value of channel c of the pixel at (x,y).
+// For unsigned integers val is clamped to the range 0 to 1.
+func (image *Image2D) SetFloat(x, y, c int, val float32) {
+	image.setFloat(image.getIdx(x, y)+c, val)
+}
+
+// GetUint16 returns the value of channel c of the pixel at (x,y) with 16 bit.
+func (image *Image2D) GetUint16(x, y, c int) uint16 {
+	return image.getUint16(image.getIdx(x, y) + c)
+}
+
+// SetUint16 sets the value of channel c of the pixel at (x,y) with 16 bit.
+func (image *Image2D) SetUint16(x, y, c int, val uint16) {
+	idx := image.getIdx(x, y) + c
+	if image.pixelType == gl.UNSIGNED_SHORT {
+		binary.LittleEndian.PutUint16(image.data[idx*2:], val)
+		return
+	}
+	image.setFloat(idx, float32(val)/65535.0)
+}
+
 // GetR returns the red value of the pixel at (x,y).
 func (image *Image2D) GetR(x, y int) uint8 {
 	idx := image.getIdx(x, y)
-	return image.data[idx]
+	return image.getUint8(idx)
 }
 
 // GetG returns the green value of the pixel at (x,y).
 func (image *Image2D) GetG(x, y int) uint8 {
 	idx := image.getIdx(x, y)
-	return image.data[idx+1]
+	return image.getUint8(idx + 1)
 }
 
 // GetB returns the blue value of the pixel at (x,y).
 func (image *Image2D) GetB(x, y int) uint8 {
 	idx := image.getIdx(x, y)
-	return image.data[idx+2]
+	return image.getUint8(idx + 2)
 }
 
 // GetA returns the alpha value of the pixel at (x,y).
 func (image *Image2D) GetA(x, y int) uint8 {
 	idx := image.getIdx(x, y)
-	return image.data[idx+3]
+	return image.getUint8(idx + 3)
 }
 
 // GetRGB returns the RGB values of the pixel at (x,y).
 func (image *Image2D) GetRGB(x, y int) (uint8, uint8, uint8) {
 	idx := image.getIdx(x, y)
-	return image.data[idx],
-		image.data[idx+1],
-		image.data[idx+2]
+	return image.getUint8(idx),
+		image.getUint8(idx + 1),
+		image.getUint8(idx + 2)
 }
 
 // GetRGBA returns the RGBA value of the pixel at (x,y).
 func (image *Image2D) GetRGBA(x, y int) (uint8, uint8, uint8, uint8) {
 	idx := image.getIdx(x, y)
-	return image.data[idx],
-		image.data[idx+1],
-		image.data[idx+2],
-		image.data[idx+3]
+	return image.getUint8(idx),
+		image.getUint8(idx + 1),
+		image.getUint8(idx + 2),
+		image.getUint8(idx + 3)
 }
 
 // SetR sets the red value of the pixel at (x,y).
 func (image *Image2D) SetR(x, y int, r uint8) {
 	idx := image.getIdx(x, y)
-	image.data[idx] = r
+	image.setUint8(idx, r)
 }
 
 // SetG sets the green value of the pixel at (x,y).
 func (image *Image2D) SetG(x, y int, g uint8) {
 	idx := image.getIdx(x, y)
-	image.data[idx+1] = g
+	image.setUint8(idx+1, g)
 }
 
 // SetB sets the blue value of the pixel at (x,y).
 func (image *Image2D) SetB(x, y int, b uint8) {
 	idx := image.getIdx(x, y)
-	image.data[idx+2] = b
+	image.setUint8(idx+2, b)
 }
 
 // SetA sets the alpha value of the pixel at (x,y).
 func (image *Image2D) SetA(x, y int, a uint8) {
 	idx := image.getIdx(x, y)
-	image.data[idx+3] = a
+	image.setUint8(idx+3, a)
 }
 
 // SetRGB sets the RGB values of the pixel at (x,y).
 func (image *Image2D) SetRGB(x, y int, r, g, b uint8) {
 	idx := image.getIdx(x, y)
-	image.data[idx] = r
-	image.data[idx+1] = g
-	image.data[idx+2] = b
+	image.setUint8(idx, r)
+	image.setUint8(idx+1, g)
+	image.setUint8(idx+2, b)
 }
 
 // SetRGBA sets the RGBA values of the pixel at (x,y).
 func (image *Image2D) SetRGBA(x, y int, r, g, b, a uint8) {
 	idx := image.getIdx(x, y)
-	image.data[idx] = r
-	image.data[idx+1] = g
-	image.data[idx+2] = b
-	image.data[idx+3] = a
+	image.setUint8(idx, r)
+	image.setUint8(idx+1, g)
+	image.setUint8(idx+2, b)
+	image.setUint8(idx+3, a)
 }
 
 func (image Image2D) String() string {
 	return fmt.Sprintf("Image2D (%v,%v) %v", image.width, image.height, image.channels)
 }
 
-// getIdx turns the x and y indices into a 1D index.
+// getIdx turns the x and y indices into the 1D index of the first channel.
 func (image *Image2D) getIdx(x, y int) int {
 	return (x + y*image.width) * image.channels
 }
 
+// getComponentSize returns the size of one channel in bytes.
+func (image *Image2D) getComponentSize() int {
+	size, _ := GetComponentSize(image.pixelType)
+	return size
+}
+
+// getPixelSize returns the size of one pixel in bytes.
+func (image *Image2D) getPixelSize() int {
+	return image.getComponentSize() * image.channels
+}
+
+// getFloat returns the channel with the 1D index idx as float.
+func (image *Image2D) getFloat(idx int) float32 {
+	return getComponent(image.data, idx*image.getComponentSize(), image.pixelType)
+}
+
+// setFloat sets the channel with the 1D index idx.
+func (image *Image2D) setFloat(idx int, val float32) {
+	setComponent(image.data, idx*image.getComponentSize(), image.pixelType, val)
+}
+
+// getUint8 returns the channel with the 1D index idx with 8 bit.
+func (image *Image2D) getUint8(idx int) uint8 {
+	switch image.pixelType {
+	case gl.UNSIGNED_BYTE:
+		return image.data[idx]
+	case gl.UNSIGNED_SHORT:
+		return uint8(binary.LittleEndian.Uint16(image.data[idx*2:]) >> 8)
+	}
+	return quantize8(image.getFloat(idx))
+}
+
+// setUint8 sets the channel with the 1D index idx with 8 bit.
+func (image *Image2D) setUint8(idx int, val uint8) {
+	switch image.pixelType {
+	case gl.UNSIGNED_BYTE:
+		image.data[idx] = val
+	case gl.UNSIGNED_SHORT:
+		binary.LittleEndian.PutUint16(image.data[idx*2:], uint16(val)*257)
+	default:
+		image.setFloat(idx, float32(val)/255.0)
+	}
+}
+
+// getUint16 returns the channel with the 1D index idx with 16 bit.
+func (image *Image2D) getUint16(idx int) uint16 {
+	switch image.pixelType {
+	case gl.UNSIGNED_BYTE:
+		return uint16(image.data[idx]) * 257
+	case gl.UNSIGNED_SHORT:
+		return binary.LittleEndian.Uint16(image.data[idx*2:])
+	}
+	return quantize16(image.getFloat(idx))
+}
+
 func checkDimensions(width, height, channels int) error {
 	if width < 1 || height < 1 {
 		return errors.New("Width and height must be bigger than 0.")
@@ -0,0 +1,43 @@
+package image2d
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+func TestSavePNG16(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		data   []uint16
+	}{
+		{"gray", 3, 2, []uint16{0, 1, 255, 256, 65534, 65535}},
+		{"rgba", 2, 1, []uint16{0, 1, 4660, 65535, 65535, 257, 32768, 12345}},
+	}
+	for _, test := range tests {
+		image, err := MakeFromUint16(test.width, test.height, test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, test.name+".png")
+		if err := image.SaveToPath(path); err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+
+		loaded, err := MakeFromPath(path)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if loaded.GetPixelType() != gl.UNSIGNED_SHORT || loaded.GetChannels() != image.GetChannels() {
+			t.Errorf("%v: loaded %v instead of %v", test.name, loaded, image)
+		}
+		if !reflect.DeepEqual(loaded.GetUint16Data(), test.data) {
+			t.Errorf("%v: loaded %v instead of %v", test.name, loaded.GetUint16Data(), test.data)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package image2d
+
+import "strings"
+
+// glyphs is a 3x5 pixel font with the characters needed to label slices and channels.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'G': {"###", "#..", "#.#", "#.#", "###"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	'=': {"...", "###", "...", "###", "..."},
+	' ': {"...", "...", "...", "...", "..."},
+}
+
+// DrawLabel draws the text in white onto a black box whose top left corner is
+// at (x,y). Each pixel of the font covers scale x scale pixels of the image.
+// Supported are digits, the letters R, G, B, A and Z, '=' and spaces, other
+// characters are drawn as spaces. Parts outside of the image are clipped.
+func (image *Image2D) DrawLabel(x, y, scale int, text string) {
+	text = strings.ToUpper(text)
+	width := (len(text)*4 + 1) * scale
+	height := 7 * scale
+
+	for py := 0; py < height; py++ {
+		for px := 0; px < width; px++ {
+			// the font pixel within the box that has a margin of one font pixel
+			fx, fy := px/scale-1, py/scale-1
+			val := float32(0)
+			if fy >= 0 && fy < 5 && fx >= 0 && fx%4 != 3 {
+				glyph, ok := glyphs[rune(text[fx/4])]
+				if ok && glyph[fy][fx%4] == '#' {
+					val = 1
+				}
+			}
+			image.setLabelPixel(x+px, y+py, val)
+		}
+	}
+}
+
+// setLabelPixel sets all color channels of the pixel at (x,y) to val and makes
+// it opaque. Pixels outside the image are ignored.
+func (image *Image2D) setLabelPixel(x, y int, val float32) {
+	if x < 0 || y < 0 || x >= image.width || y >= image.height {
+		return
+	}
+	idx := image.getIdx(x, y)
+	for c := 0; c < image.channels; c++ {
+		if c == 3 {
+			image.setFloat(idx+c, 1)
+		} else {
+			image.setFloat(idx+c, val)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package image2d
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// GetComponentSize returns the size in bytes of one channel of the specified
+// pixel type. Supported pixel types are gl.UNSIGNED_BYTE, gl.UNSIGNED_SHORT,
+// gl.HALF_FLOAT and gl.FLOAT.
+func GetComponentSize(pixelType uint32) (int, error) {
+	switch pixelType {
+	case gl.UNSIGNED_BYTE:
+		return 1, nil
+	case gl.UNSIGNED_SHORT, gl.HALF_FLOAT:
+		return 2, nil
+	case gl.FLOAT:
+		return 4, nil
+	}
+	return 0, fmt.Errorf("Unsupported pixel type %v", pixelType)
+}
+
+// GetFormat returns the pixel format of an image with the specified number of
+// channels, which is gl.RED, gl.RG, gl.RGB or gl.RGBA.
+func GetFormat(channels int) (uint32, error) {
+	switch channels {
+	case 1:
+		return gl.RED, nil
+	case 2:
+		return gl.RG, nil
+	case 3:
+		return gl.RGB, nil
+	case 4:
+		return gl.RGBA, nil
+	}
+	return 0, fmt.Errorf("Unsupported number of channels %v", channels)
+}
+
+// FloatToHalf converts a 32 bit float to a 16 bit half float.
+// Values that are too big for a half float become infinity and values that
+// are too small become 0. The mantissa is rounded to nearest even.
+func FloatToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int32(bits>>23) & 0xff
+	mantissa := bits & 0x7fffff
+
+	switch {
+	case exp == 0xff:
+		// infinity and nan
+		if mantissa != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp-127 > 15:
+		// overflow
+		return sign | 0x7c00
+	case exp-127 < -25:
+		// underflow
+		return sign
+	case exp-127 < -14:
+		// subnormal half float
+		mantissa |= 0x800000
+		shift := uint32(-1 - (exp - 127))
+		half := mantissa >> shift
+		rest := mantissa & (1<<shift - 1)
+		halfway := uint32(1) << (shift - 1)
+		if rest > halfway || (rest == halfway && half&1 != 0) {
+			half++
+		}
+		return sign | uint16(half)
+	}
+
+	// normal half float, a carry of the mantissa rounding increments the exponent
+	half := uint32(exp-127+15)<<10 | mantissa>>13
+	rest := mantissa & 0x1fff
+	if rest > 0x1000 || (rest == 0x1000 && half&1 != 0) {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+// HalfToFloat converts a 16 bit half float to a 32 bit float.
+func HalfToFloat(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mantissa := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0:
+		// zero and subnormal half floats
+		if mantissa == 0 {
+			return math.Float32frombits(sign)
+		}
+		val := float32(mantissa) / (1 << 24)
+		if sign != 0 {
+			return -val
+		}
+		return val
+	case 0x1f:
+		// infinity and nan
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	}
+
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mantissa<<13)
+}
+
+// getComponent returns the component starting at byte offset off of data
+// with the specified pixel type as a float. Unsigned integers are mapped to
+// the range 0 to 1.
+func getComponent(data []uint8, off int, pixelType uint32) float32 {
+	switch pixelType {
+	case gl.UNSIGNED_SHORT:
+		return float32(binary.LittleEndian.Uint16(data[off:])) / 65535.0
+	case gl.HALF_FLOAT:
+		return HalfToFloat(binary.LittleEndian.Uint16(data[off:]))
+	case gl.FLOAT:
+		return math.Float32frombits(binary.LittleEndian.Uint32(data[off:]))
+	}
+	return float32(data[off]) / 255.0
+}
+
+// setComponent sets the component starting at byte offset off of data with
+// the specified pixel type. For unsigned integers val is clamped to the range
+// 0 to 1 before it is quantized.
+func setComponent(data []uint8, off int, pixelType uint32, val float32) {
+	switch pixelType {
+	case gl.UNSIGNED_SHORT:
+		binary.LittleEndian.PutUint16(data[off:], quantize16(val))
+	case gl.HALF_FLOAT:
+		binary.LittleEndian.PutUint16(data[off:], FloatToHalf(val))
+	case gl.FLOAT:
+		binary.LittleEndian.PutUint32(data[off:], math.Float32bits(val))
+	default:
+		data[off] = quantize8(val)
+	}
+}
+
+// quantize8 maps val between 0 and 1 to 0..255.
+func quantize8(val float32) uint8 {
+	if val <= 0 {
+		return 0
+	}
+	if val >= 1 {
+		return 255
+	}
+	return uint8(val*255 + 0.5)
+}
+
+// quantize16 maps val between 0 and 1 to 0..65535.
+func quantize16(val float32) uint16 {
+	if val <= 0 {
+		return 0
+	}
+	if val >= 1 {
+		return 65535
+	}
+	return uint16(val*65535 + 0.5)
+}
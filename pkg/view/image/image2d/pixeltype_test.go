@@ -0,0 +1,92 @@
+package image2d
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloatToHalf(t *testing.T) {
+	tests := []struct {
+		name string
+		f    float32
+		h    uint16
+	}{
+		{"zero", 0, 0x0000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"one", 1, 0x3c00},
+		{"minus two", -2, 0xc000},
+		{"max half", 65504, 0x7bff},
+		{"below overflow", 65519, 0x7bff},
+		{"rounds to overflow", 65520, 0x7c00},
+		{"overflow", 1e6, 0x7c00},
+		{"negative overflow", -1e6, 0xfc00},
+		{"infinity", float32(math.Inf(1)), 0x7c00},
+		{"negative infinity", float32(math.Inf(-1)), 0xfc00},
+		{"smallest normal", 1.0 / (1 << 14), 0x0400},
+		{"largest subnormal", 1023.0 / (1 << 24), 0x03ff},
+		{"smallest subnormal", 1.0 / (1 << 24), 0x0001},
+		{"negative subnormal", -3.0 / (1 << 24), 0x8003},
+		{"half of smallest subnormal rounds to even", 1.0 / (1 << 25), 0x0000},
+		{"above half of smallest subnormal", 1.5 / (1 << 25), 0x0001},
+		{"underflow", 1.0 / (1 << 26), 0x0000},
+		{"negative underflow", -1.0 / (1 << 26), 0x8000},
+		{"tie rounds down to even", 1 + 1.0/(1<<11), 0x3c00},
+		{"tie rounds up to even", 1 + 3.0/(1<<11), 0x3c02},
+		{"above tie rounds up", 1 + 1.0/(1<<11) + 1.0/(1<<20), 0x3c01},
+		{"rounding carries into the exponent", 2 - 1.0/(1<<11), 0x4000},
+		{"subnormal tie rounds up to even", 3.0 / (1 << 25), 0x0002},
+	}
+	for _, test := range tests {
+		if h := FloatToHalf(test.f); h != test.h {
+			t.Errorf("%v: %v is %#04x instead of %#04x", test.name, test.f, h, test.h)
+		}
+	}
+
+	// nan stays nan
+	if h := FloatToHalf(float32(math.NaN())); h&0x7c00 != 0x7c00 || h&0x3ff == 0 {
+		t.Errorf("nan is %#04x", h)
+	}
+}
+
+func TestHalfToFloat(t *testing.T) {
+	tests := []struct {
+		h uint16
+		f float32
+	}{
+		{0x0000, 0},
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x7bff, 65504},
+		{0x0400, 1.0 / (1 << 14)},
+		{0x03ff, 1023.0 / (1 << 24)},
+		{0x0001, 1.0 / (1 << 24)},
+		{0x8001, -1.0 / (1 << 24)},
+		{0x7c00, float32(math.Inf(1))},
+		{0xfc00, float32(math.Inf(-1))},
+	}
+	for _, test := range tests {
+		if f := HalfToFloat(test.h); f != test.f {
+			t.Errorf("%#04x is %v instead of %v", test.h, f, test.f)
+		}
+	}
+
+	if f := HalfToFloat(0x8000); f != 0 || !math.Signbit(float64(f)) {
+		t.Errorf("0x8000 is %v instead of -0", f)
+	}
+	if f := HalfToFloat(0x7e00); !math.IsNaN(float64(f)) {
+		t.Errorf("0x7e00 is %v instead of nan", f)
+	}
+}
+
+func TestHalfRoundTrip(t *testing.T) {
+	// every half float except nan survives the conversion to float and back
+	for i := 0; i <= 0xffff; i++ {
+		h := uint16(i)
+		if h&0x7c00 == 0x7c00 && h&0x3ff != 0 {
+			continue
+		}
+		if back := FloatToHalf(HalfToFloat(h)); back != h {
+			t.Errorf("%#04x becomes %#04x", h, back)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package image2d
+
+import (
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/internal/sampling"
+)
+
+// Wrap specifies how coordinates outside of the range 0 to 1 are mapped back
+// into the image, like the wrap modes of OpenGL samplers.
+type Wrap = sampling.Wrap
+
+// Filter specifies how the pixels around a sample position are interpolated.
+type Filter = sampling.Filter
+
+const (
+	// Repeat tiles the image like gl.REPEAT.
+	Repeat = sampling.Repeat
+	// Clamp repeats the border pixels like gl.CLAMP_TO_EDGE.
+	Clamp = sampling.Clamp
+	// Mirror tiles the image with every other tile mirrored like gl.MIRRORED_REPEAT.
+	Mirror = sampling.Mirror
+
+	// Nearest returns the pixel that contains the sample position like gl.NEAREST.
+	Nearest = sampling.Nearest
+	// Linear interpolates the 2 closest pixels along each axis like gl.LINEAR.
+	Linear = sampling.Linear
+	// Cubic interpolates the 4 closest pixels along each axis with a Catmull-Rom spline.
+	Cubic = sampling.Cubic
+)
+
+// SampleChannel returns the value of channel c at the normalized coordinates
+// (u,v), where (0,0) is the top left and (1,1) the bottom right corner of the image.
+// Unsigned integers are mapped to the range 0 to 1. If channel c doesn't exist 0 is returned.
+func (image *Image2D) SampleChannel(u, v float32, c int, wrap Wrap, filter Filter) float32 {
+	tx := sampling.MakeTaps(u, image.width, wrap, filter)
+	ty := sampling.MakeTaps(v, image.height, wrap, filter)
+	return image.sampleTaps(&tx, &ty, c)
+}
+
+// Sample returns the values of all channels at the normalized coordinates (u,v)
+// as described in SampleChannel.
+func (image *Image2D) Sample(u, v float32, wrap Wrap, filter Filter) []float32 {
+	tx := sampling.MakeTaps(u, image.width, wrap, filter)
+	ty := sampling.MakeTaps(v, image.height, wrap, filter)
+
+	result := make([]float32, image.channels)
+	for c := range result {
+		result[c] = image.sampleTaps(&tx, &ty, c)
+	}
+	return result
+}
+
+// sampleTaps returns the weighted sum of channel c of the pixels specified by
+// the taps along the x and y axis. If channel c doesn't exist 0 is returned.
+func (image *Image2D) sampleTaps(tx, ty *sampling.Taps, c int) float32 {
+	// reading another channel or past the end of the data otherwise
+	if c < 0 || c >= image.channels {
+		return 0
+	}
+
+	var val float32
+	for j := 0; j < ty.Count; j++ {
+		for i := 0; i < tx.Count; i++ {
+			idx := image.getIdx(tx.Index[i], ty.Index[j]) + c
+			val += tx.Weight[i] * ty.Weight[j] * image.getFloat(idx)
+		}
+	}
+	return val
+}
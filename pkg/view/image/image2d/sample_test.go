@@ -0,0 +1,126 @@
+package image2d
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSampleChannel(t *testing.T) {
+	// 4x1 image with two channels, the second one is the negated first one
+	image, err := MakeFromFloat32(4, 1, []float32{0, 0, 1, -1, 2, -2, 3, -3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		u        float32
+		wrap     Wrap
+		filter   Filter
+		expected float32
+	}{
+		{0.375, Repeat, Nearest, 1},
+		{0.5, Repeat, Linear, 1.5},
+		{0, Repeat, Linear, 1.5},
+		{0, Clamp, Linear, 0},
+		{0, Mirror, Linear, 0},
+		{0.375, Clamp, Cubic, 1},
+		{0.5, Clamp, Cubic, 1.5},
+	}
+	for _, test := range tests {
+		for c, sign := range []float32{1, -1} {
+			actual := image.SampleChannel(test.u, 0.5, c, test.wrap, test.filter)
+			if math.Abs(float64(actual-sign*test.expected)) > 1e-6 {
+				t.Errorf("%v %v at %v channel %v is %v instead of %v", test.wrap, test.filter, test.u, c, actual, sign*test.expected)
+			}
+		}
+	}
+}
+
+func TestSampleBorders(t *testing.T) {
+	image, err := MakeFromFloat32(4, 1, []float32{0, 1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		u        float32
+		wrap     Wrap
+		filter   Filter
+		expected float32
+	}{
+		// the centers of the pixels left and right of the image
+		{-0.125, Repeat, Nearest, 3},
+		{1.125, Repeat, Nearest, 0},
+		{-0.125, Clamp, Nearest, 0},
+		{1.125, Clamp, Nearest, 3},
+		{-0.125, Mirror, Nearest, 0},
+		{1.125, Mirror, Nearest, 3},
+		{-0.375, Mirror, Nearest, 1},
+		{1.375, Mirror, Nearest, 2},
+		// the borders of the image
+		{0, Repeat, Linear, 1.5},
+		{1, Repeat, Linear, 1.5},
+		{0, Mirror, Linear, 0},
+		{1, Mirror, Linear, 3},
+		{-0.25, Mirror, Linear, 0.5},
+		{1.25, Mirror, Linear, 2.5},
+		{0, Mirror, Cubic, 2 * -1.0 / 16},
+		{1, Mirror, Cubic, 2*-1.0/16 + 3*9.0/16 + 3*9.0/16 + 2*-1.0/16},
+	}
+	for _, test := range tests {
+		actual := image.SampleChannel(test.u, 0.5, 0, test.wrap, test.filter)
+		if math.Abs(float64(actual-test.expected)) > 1e-6 {
+			t.Errorf("%v %v at %v is %v instead of %v", test.wrap, test.filter, test.u, actual, test.expected)
+		}
+	}
+}
+
+func TestSampleBicubic(t *testing.T) {
+	// f(x,y) = x² + 4y at the pixel centers
+	var data []float32
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			data = append(data, float32(x*x+4*y))
+		}
+	}
+	image, err := MakeFromFloat32(4, 4, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Catmull-Rom splines reproduce linear functions and, with all four taps inside, x² at these positions
+	tests := []struct {
+		x, y     float32
+		expected float32
+	}{
+		{1, 2, 1 + 8},
+		{1.5, 1.5, 2.25 + 6},
+		{1.25, 1.75, 1.5625 + 7},
+	}
+	for _, test := range tests {
+		u, v := (test.x+0.5)/4, (test.y+0.5)/4
+		actual := image.SampleChannel(u, v, 0, Clamp, Cubic)
+		if math.Abs(float64(actual-test.expected)) > 1e-5 {
+			t.Errorf("bicubic at (%v,%v) is %v instead of %v", test.x, test.y, actual, test.expected)
+		}
+	}
+}
+
+func TestSampleInvalidChannel(t *testing.T) {
+	image, err := MakeFromFloat32(2, 1, []float32{1, 2, 3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// channel 2 of the last pixel would be past the end of the data
+	for _, c := range []int{-1, 2, 5} {
+		for _, filter := range []Filter{Nearest, Linear, Cubic} {
+			if val := image.SampleChannel(0.9, 0.5, c, Clamp, filter); val != 0 {
+				t.Errorf("channel %v with %v is %v instead of 0", c, filter, val)
+			}
+		}
+	}
+	if values := image.Sample(0.25, 0.5, Clamp, Nearest); len(values) != 2 || values[0] != 1 || values[1] != 2 {
+		t.Errorf("sample is %v instead of [1 2]", values)
+	}
+}
@@ -0,0 +1,135 @@
+package image3d
+
+import (
+	"errors"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+)
+
+// Split returns one single channel image for each channel of the image.
+// The images keep the pixel type of the image.
+func (image *Image3D) Split() ([]Image3D, error) {
+	var images []Image3D
+	for c := 0; c < image.channels; c++ {
+		channel, err := image.GetChannel(c)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, channel)
+	}
+
+	return images, nil
+}
+
+// GetChannel returns a single channel image with the values of channel c.
+// The image keeps the pixel type of the image.
+func (image *Image3D) GetChannel(c int) (Image3D, error) {
+	return makeFromSlices(image.slices, func(i int) (image2d.Image2D, error) {
+		return image.data[i].GetChannel(c)
+	})
+}
+
+// Pack creates an image with one channel per source, e.g. four sources result
+// in an RGBA image. Each source has to be a single channel image and all
+// sources need to have the same size. Channels of sources that are nil are
+// filled with 0. The image has the pixel type of the first source that isn't nil.
+func Pack(sources ...*Image3D) (Image3D, error) {
+	slices := -1
+	for _, source := range sources {
+		if source == nil {
+			continue
+		}
+		if slices == -1 {
+			slices = source.slices
+		}
+		if source.slices != slices {
+			return Image3D{}, errors.New("Source dimensions don't match.")
+		}
+	}
+	if slices == -1 {
+		return Image3D{}, errors.New("At least one source must not be nil.")
+	}
+
+	return makeFromSlices(slices, func(i int) (image2d.Image2D, error) {
+		sliceSources := make([]*image2d.Image2D, len(sources))
+		for j, source := range sources {
+			if source != nil {
+				sliceSources[j] = &source.data[i]
+			}
+		}
+		return image2d.Pack(sliceSources...)
+	})
+}
+
+// Swizzle creates an image whose channels are picked from the channels of the
+// image as specified by order. Each character of order results in one channel,
+// where r, g, b and a pick the first to fourth channel and 0 and 1 result in a
+// channel filled with 0 or 1.
+func (image *Image3D) Swizzle(order string) (Image3D, error) {
+	return makeFromSlices(image.slices, func(i int) (image2d.Image2D, error) {
+		return image.data[i].Swizzle(order)
+	})
+}
+
+// FillChannel sets channel c of all pixels to val.
+// For unsigned integers val is clamped to the range 0 to 1.
+func (image *Image3D) FillChannel(c int, val float32) error {
+	return image.forEachSlice(func(slice *image2d.Image2D) error {
+		return slice.FillChannel(c, val)
+	})
+}
+
+// GetChannelRange returns the smallest and biggest value of channel c.
+// Unsigned integers are mapped to the range 0 to 1.
+func (image *Image3D) GetChannelRange(c int) (float32, float32, error) {
+	var min, max float32
+	for i := range image.data {
+		sliceMin, sliceMax, err := image.data[i].GetChannelRange(c)
+		if err != nil {
+			return 0, 0, err
+		}
+		if i == 0 || sliceMin < min {
+			min = sliceMin
+		}
+		if i == 0 || sliceMax > max {
+			max = sliceMax
+		}
+	}
+
+	return min, max, nil
+}
+
+// Remap linearly maps the values of channel c from the range oldMin to oldMax
+// to the range newMin to newMax. Unsigned integers are mapped to the range 0
+// to 1 before remapping and are clamped afterwards.
+func (image *Image3D) Remap(c int, oldMin, oldMax, newMin, newMax float32) error {
+	return image.forEachSlice(func(slice *image2d.Image2D) error {
+		return slice.Remap(c, oldMin, oldMax, newMin, newMax)
+	})
+}
+
+// Threshold sets all values of channel c that are smaller than t to 0.
+// Unsigned integers are mapped to the range 0 to 1 before comparing them with t.
+func (image *Image3D) Threshold(c int, t float32) error {
+	return image.forEachSlice(func(slice *image2d.Image2D) error {
+		return slice.Threshold(c, t)
+	})
+}
+
+// Levels adjusts channel c like the levels tool of image editors.
+// See image2d.Image2D.Levels for a description of the parameters.
+func (image *Image3D) Levels(c int, inBlack, inWhite, gamma, outBlack, outWhite float32) error {
+	return image.forEachSlice(func(slice *image2d.Image2D) error {
+		return slice.Levels(c, inBlack, inWhite, gamma, outBlack, outWhite)
+	})
+}
+
+// forEachSlice calls fn for each slice and stops at the first error.
+func (image *Image3D) forEachSlice(fn func(slice *image2d.Image2D) error) error {
+	for i := range image.data {
+		if err := fn(&image.data[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
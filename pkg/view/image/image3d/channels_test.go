@@ -0,0 +1,215 @@
+package image3d
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// makeRGB creates a 2x1x2 rgb image with 8 bit per channel whose red, green
+// and blue channels hold the values 0..3, 10..13 and 20..23.
+func makeRGB(t *testing.T) Image3D {
+	image, err := MakeFromData(2, 1, 2, []uint8{
+		0, 10, 20, 1, 11, 21,
+		2, 12, 22, 3, 13, 23,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+// makeFloat creates a 2x1x2 image with one float channel holding the values.
+func makeFloat(t *testing.T, values ...float32) Image3D {
+	image, err := MakeFromFloat32(2, 1, 2, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+// checkFloats fails if channel c of the image differs from expected.
+func checkFloats(t *testing.T, name string, image *Image3D, c int, expected ...float32) {
+	for i, val := range expected {
+		actual := image.GetFloat(i%image.width, 0, i/image.width, c)
+		if math.Abs(float64(actual-val)) > 1e-6 {
+			t.Errorf("%v: pixel %v of channel %v is %v instead of %v", name, i, c, actual, val)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	image := makeRGB(t)
+	channels, err := image.Split()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channels) != 3 {
+		t.Fatalf("split into %v images instead of 3", len(channels))
+	}
+	for c, channel := range channels {
+		expected := []uint8{uint8(c * 10), uint8(c*10 + 1), uint8(c*10 + 2), uint8(c*10 + 3)}
+		if channel.GetSlices() != 2 || channel.GetChannels() != 1 || channel.GetPixelType() != gl.UNSIGNED_BYTE ||
+			!bytes.Equal(channel.GetData(), expected) {
+			t.Errorf("channel %v is %v with data %v instead of %v", c, channel, channel.GetData(), expected)
+		}
+	}
+
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	floats, err := float.Split()
+	if err != nil || len(floats) != 1 || floats[0].GetPixelType() != gl.FLOAT {
+		t.Fatalf("split of a float image failed: %v", err)
+	}
+	checkFloats(t, "float split", &floats[0], 0, 0.5, -1, 2, 3)
+
+	if _, err := image.GetChannel(3); err == nil {
+		t.Error("channel 3 of an rgb image: expected an error")
+	}
+}
+
+func TestPack(t *testing.T) {
+	image := makeRGB(t)
+	channels, err := image.Split()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	packed, err := Pack(&channels[0], &channels[1], &channels[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packed.GetSlices() != 2 || !bytes.Equal(packed.GetData(), image.GetData()) {
+		t.Errorf("packed data is %v instead of %v", packed.GetData(), image.GetData())
+	}
+
+	// nil sources are filled with 0
+	packed, err = Pack(&channels[1], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{10, 0, 11, 0, 12, 0, 13, 0}
+	if !bytes.Equal(packed.GetData(), expected) {
+		t.Errorf("packed data is %v instead of %v", packed.GetData(), expected)
+	}
+
+	single, err := MakeFromData(2, 1, 1, []uint8{0, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := [][]*Image3D{
+		{},
+		{nil},
+		{&image},
+		{&channels[0], &single},
+	}
+	for _, sources := range invalid {
+		if _, err := Pack(sources...); err == nil {
+			t.Errorf("packing %v sources: expected an error", len(sources))
+		}
+	}
+}
+
+func TestSwizzle(t *testing.T) {
+	image := makeRGB(t)
+	swizzled, err := image.Swizzle("b0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{20, 0, 21, 0, 22, 0, 23, 0}
+	if swizzled.GetSlices() != 2 || swizzled.GetChannels() != 2 || !bytes.Equal(swizzled.GetData(), expected) {
+		t.Errorf("b0 is %v instead of %v", swizzled.GetData(), expected)
+	}
+
+	for _, order := range []string{"", "rgx", "a"} {
+		if _, err := image.Swizzle(order); err == nil {
+			t.Errorf("swizzle %q: expected an error", order)
+		}
+	}
+}
+
+func TestFillChannel(t *testing.T) {
+	image := makeRGB(t)
+	if err := image.FillChannel(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	expected := []uint8{255, 10, 20, 255, 11, 21, 255, 12, 22, 255, 13, 23}
+	if !bytes.Equal(image.GetData(), expected) {
+		t.Errorf("filled data is %v instead of %v", image.GetData(), expected)
+	}
+
+	if err := image.FillChannel(3, 0); err == nil {
+		t.Error("filling channel 3 of an rgb image: expected an error")
+	}
+}
+
+func TestGetChannelRange(t *testing.T) {
+	// the smallest and biggest values are in different slices
+	float := makeFloat(t, 0.5, 3, -1, 2)
+	min, max, err := float.GetChannelRange(0)
+	if err != nil || min != -1 || max != 3 {
+		t.Errorf("range is %v to %v instead of -1 to 3: %v", min, max, err)
+	}
+
+	image := makeRGB(t)
+	min, max, err = image.GetChannelRange(1)
+	if err != nil || min != 10.0/255 || max != 13.0/255 {
+		t.Errorf("range of green is %v to %v instead of %v to %v: %v", min, max, 10.0/255, 13.0/255, err)
+	}
+
+	if _, _, err := image.GetChannelRange(3); err == nil {
+		t.Error("range of channel 3: expected an error")
+	}
+}
+
+func TestRemap(t *testing.T) {
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	if err := float.Remap(0, -1, 3, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "remap", &float, 0, 0.375, 0, 0.75, 1)
+
+	if err := float.Remap(0, 1, 1, 0, 1); err == nil {
+		t.Error("remapping an empty range: expected an error")
+	}
+	if err := float.Remap(1, 0, 1, 0, 1); err == nil {
+		t.Error("remapping channel 1 of a single channel image: expected an error")
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	float := makeFloat(t, 0.5, -1, 2, 3)
+	if err := float.Threshold(0, 2); err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "threshold", &float, 0, 0, 0, 2, 3)
+
+	if err := float.Threshold(1, 0); err == nil {
+		t.Error("threshold of channel 1: expected an error")
+	}
+}
+
+func TestLevels(t *testing.T) {
+	float := makeFloat(t, 0, 0.25, 0.5, 1)
+	if err := float.Levels(0, 0.25, 0.75, 1, 0.2, 0.6); err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "levels", &float, 0, 0.2, 0.2, 0.4, 0.6)
+
+	float = makeFloat(t, 0, 0.25, 0.5, 1)
+	if err := float.Levels(0, 0, 1, 2, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	checkFloats(t, "gamma", &float, 0, 0, 0.5, float32(math.Sqrt(0.5)), 1)
+
+	if err := float.Levels(0, 0.5, 0.5, 1, 0, 1); err == nil {
+		t.Error("levels with an empty input range: expected an error")
+	}
+	if err := float.Levels(0, 0, 1, -1, 0, 1); err == nil {
+		t.Error("levels with a negative gamma: expected an error")
+	}
+	if err := float.Levels(1, 0, 1, 1, 0, 1); err == nil {
+		t.Error("levels of channel 1: expected an error")
+	}
+}
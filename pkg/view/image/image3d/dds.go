@@ -0,0 +1,203 @@
+package image3d
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// flags and capabilities of the DDS header.
+const (
+	ddsMagic uint32 = 0x20534444 // "DDS "
+
+	ddsdCaps        uint32 = 0x1
+	ddsdHeight      uint32 = 0x2
+	ddsdWidth       uint32 = 0x4
+	ddsdPitch       uint32 = 0x8
+	ddsdPixelFormat uint32 = 0x1000
+	ddsdMipMapCount uint32 = 0x20000
+	ddsdDepth       uint32 = 0x800000
+
+	ddpfFourCC uint32 = 0x4
+	ddsFourCC  uint32 = 0x30315844 // "DX10"
+
+	ddsCapsComplex uint32 = 0x8
+	ddsCapsTexture uint32 = 0x1000
+	ddsCapsMipMap  uint32 = 0x400000
+	ddsCaps2Volume uint32 = 0x200000
+
+	d3d10ResourceDimensionTexture3D uint32 = 4
+)
+
+// ddsPixelFormat is the pixel format block of the DDS header.
+type ddsPixelFormat struct {
+	Size        uint32
+	Flags       uint32
+	FourCC      uint32
+	RGBBitCount uint32
+	RBitMask    uint32
+	GBitMask    uint32
+	BBitMask    uint32
+	ABitMask    uint32
+}
+
+// ddsHeader is the header that follows the magic number of a DDS file.
+type ddsHeader struct {
+	Size              uint32
+	Flags             uint32
+	Height            uint32
+	Width             uint32
+	PitchOrLinearSize uint32
+	Depth             uint32
+	MipMapCount       uint32
+	Reserved1         [11]uint32
+	PixelFormat       ddsPixelFormat
+	Caps              uint32
+	Caps2             uint32
+	Caps3             uint32
+	Caps4             uint32
+	Reserved2         uint32
+}
+
+// ddsHeaderDX10 is the extended header that follows the DDS header if the
+// four character code of the pixel format is DX10.
+type ddsHeaderDX10 struct {
+	DXGIFormat        uint32
+	ResourceDimension uint32
+	MiscFlag          uint32
+	ArraySize         uint32
+	MiscFlags2        uint32
+}
+
+// LoadDDS constructs a mip chain from the DDS file at the specified path.
+// Only uncompressed 3D textures with a DX10 header are supported.
+func LoadDDS(path string) (MipChain, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return MipChain{}, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+
+	// read and validate the headers
+	var (
+		magic  uint32
+		header ddsHeader
+		dx10   ddsHeaderDX10
+	)
+	if err := binary.Read(reader, binary.LittleEndian, &magic); err != nil {
+		return MipChain{}, fmt.Errorf("%v: %v", path, err)
+	}
+	if magic != ddsMagic {
+		return MipChain{}, fmt.Errorf("%v: not a DDS file", path)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return MipChain{}, fmt.Errorf("%v: can't read DDS header: %v", path, err)
+	}
+	if header.PixelFormat.Flags&ddpfFourCC == 0 || header.PixelFormat.FourCC != ddsFourCC {
+		return MipChain{}, fmt.Errorf("%v: only DDS files with a DX10 header are supported", path)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &dx10); err != nil {
+		return MipChain{}, fmt.Errorf("%v: can't read DX10 header: %v", path, err)
+	}
+	if dx10.ResourceDimension != d3d10ResourceDimensionTexture3D {
+		return MipChain{}, fmt.Errorf("%v: not a 3D texture", path)
+	}
+	format, err := formatFromDXGI(dx10.DXGIFormat)
+	if err != nil {
+		return MipChain{}, fmt.Errorf("%v: %v", path, err)
+	}
+
+	// the number of mip maps is only valid if the flag is set
+	levels := 1
+	if header.Flags&ddsdMipMapCount != 0 && header.MipMapCount > 1 {
+		levels = int(header.MipMapCount)
+	}
+	depth := 1
+	if header.Flags&ddsdDepth != 0 && header.Depth > 0 {
+		depth = int(header.Depth)
+	}
+
+	// read each level, the levels are stored from largest to smallest
+	chain := MipChain{format: format}
+	width, height := int(header.Width), int(header.Height)
+	for i := 0; i < levels; i++ {
+		data := make([]uint8, width*height*depth*format.GetPixelSize())
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return MipChain{}, fmt.Errorf("%v: can't read level %v: %v", path, i, err)
+		}
+		if err := chain.AddLevel(width, height, depth, data); err != nil {
+			return MipChain{}, fmt.Errorf("%v: %v", path, err)
+		}
+		width, height, depth = halve(width), halve(height), halve(depth)
+	}
+
+	return chain, nil
+}
+
+// SaveDDS writes all levels of the mip chain as a 3D texture with a DX10
+// header into a DDS file at the specified path.
+// DDS files have no room for the parameters of the chain, which get lost.
+func (chain *MipChain) SaveDDS(path string) error {
+	if len(chain.levels) == 0 {
+		return fmt.Errorf("Mip chain has no levels")
+	}
+	first := chain.levels[0]
+
+	flags := ddsdCaps | ddsdHeight | ddsdWidth | ddsdPitch | ddsdPixelFormat | ddsdDepth
+	caps := ddsCapsTexture | ddsCapsComplex
+	if len(chain.levels) > 1 {
+		flags |= ddsdMipMapCount
+		caps |= ddsCapsMipMap
+	}
+	header := ddsHeader{
+		Size:              124,
+		Flags:             flags,
+		Height:            uint32(first.height),
+		Width:             uint32(first.width),
+		PitchOrLinearSize: uint32(first.width * chain.format.GetPixelSize()),
+		Depth:             uint32(first.depth),
+		MipMapCount:       uint32(len(chain.levels)),
+		PixelFormat: ddsPixelFormat{
+			Size:   32,
+			Flags:  ddpfFourCC,
+			FourCC: ddsFourCC,
+		},
+		Caps:  caps,
+		Caps2: ddsCaps2Volume,
+	}
+	dx10 := ddsHeaderDX10{
+		DXGIFormat:        formatInfos[chain.format].dxgiFormat,
+		ResourceDimension: d3d10ResourceDimensionTexture3D,
+		ArraySize:         1,
+	}
+
+	// write the headers followed by the levels from largest to smallest
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, ddsMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, &dx10); err != nil {
+		return err
+	}
+	for _, level := range chain.levels {
+		buf.Write(level.data)
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// halve returns the size of the next smaller mip level.
+func halve(size int) int {
+	if size > 1 {
+		return size / 2
+	}
+	return 1
+}
@@ -22,6 +22,7 @@ type Image3D struct {
 	channels  int
 	pixelType uint32
 	data      []image2d.Image2D
+	params    map[string]string
 }
 
 // Make constructs an image of the specified length x width x height and with all pixels
@@ -74,6 +75,60 @@ func MakeFromData(width, height, slices int, data []uint8) (Image3D, error) {
 	}, nil
 }
 
+// MakeFromUint16 constructs an image of the specified width, height and slices that stores the
+// specified data with 16 bit per channel.
+func MakeFromUint16(width, height, slices int, data []uint16) (Image3D, error) {
+	size := len(data) / slices
+	return makeFromSlices(slices, func(i int) (image2d.Image2D, error) {
+		return image2d.MakeFromUint16(width, height, data[i*size:(i+1)*size])
+	})
+}
+
+// MakeFromFloat32 constructs an image of the specified width, height and slices that stores the
+// specified data with 32 bit floats per channel.
+func MakeFromFloat32(width, height, slices int, data []float32) (Image3D, error) {
+	size := len(data) / slices
+	return makeFromSlices(slices, func(i int) (image2d.Image2D, error) {
+		return image2d.MakeFromFloat32(width, height, data[i*size:(i+1)*size])
+	})
+}
+
+// MakeFromRawData constructs an image of the specified width, height, slices and number of
+// channels from the raw bytes of pixels of the specified pixel type. Multi byte values are
+// expected in little endian byte order.
+func MakeFromRawData(width, height, slices, channels int, pixelType uint32, data []uint8) (Image3D, error) {
+	size := len(data) / slices
+	return makeFromSlices(slices, func(i int) (image2d.Image2D, error) {
+		return image2d.MakeFromRawData(width, height, channels, pixelType, data[i*size:(i+1)*size])
+	})
+}
+
+// makeFromSlices constructs an image from the slices created by makeSlice.
+func makeFromSlices(slices int, makeSlice func(i int) (image2d.Image2D, error)) (Image3D, error) {
+	if slices < 1 {
+		return Image3D{}, errors.New("Number of slices must be bigger than 0.")
+	}
+
+	var images []image2d.Image2D
+	for i := 0; i < slices; i++ {
+		image, err := makeSlice(i)
+		if err != nil {
+			return Image3D{}, err
+		}
+		images = append(images, image)
+	}
+
+	first := images[0]
+	return Image3D{
+		width:     first.GetWidth(),
+		height:    first.GetHeight(),
+		slices:    slices,
+		channels:  first.GetChannels(),
+		pixelType: first.GetPixelType(),
+		data:      images,
+	}, nil
+}
+
 // MakeImageFromPath constructs the image data from the specified paths.
 // If there is no image at the specified path an error is returned instead.
 // The dimensions of all images must match.
@@ -182,7 +237,8 @@ func (image *Image3D) GetDataPointer() unsafe.Pointer {
 	return gl.Ptr(image.data)
 }
 
-// GetData returns a copy of the images data
+// GetData returns a copy of the images raw data.
+// Multi byte values are stored in little endian byte order.
 func (image *Image3D) GetData() []uint8 {
 	// collect data of all slices
 	var data []uint8
@@ -193,6 +249,83 @@ func (image *Image3D) GetData() []uint8 {
 	return data
 }
 
+// GetUint16Data returns a copy of the images data converted to 16 bit per channel.
+// Floats are clamped to the range 0 to 1.
+func (image *Image3D) GetUint16Data() []uint16 {
+	var data []uint16
+	for _, slice := range image.data {
+		data = append(data, slice.GetUint16Data()...)
+	}
+
+	return data
+}
+
+// GetFloat32Data returns a copy of the images data converted to 32 bit floats.
+// Unsigned integers are mapped to the range 0 to 1.
+func (image *Image3D) GetFloat32Data() []float32 {
+	var data []float32
+	for _, slice := range image.data {
+		data = append(data, slice.GetFloat32Data()...)
+	}
+
+	return data
+}
+
+// Convert returns a copy of the image that stores its pixels with the specified pixel type.
+// Supported pixel types are gl.UNSIGNED_BYTE, gl.UNSIGNED_SHORT, gl.HALF_FLOAT and gl.FLOAT.
+// Floats are clamped to the range 0 to 1 when being converted to unsigned integers.
+func (image *Image3D) Convert(pixelType uint32) (Image3D, error) {
+	converted, err := makeFromSlices(image.slices, func(i int) (image2d.Image2D, error) {
+		return image.data[i].Convert(pixelType)
+	})
+	if err != nil {
+		return Image3D{}, err
+	}
+	converted.params = image.GetParams()
+
+	return converted, nil
+}
+
+// GetFloat returns the value of channel c of the pixel at (x,y) in slice z as float.
+// Unsigned integers are mapped to the range 0 to 1.
+func (image *Image3D) GetFloat(x, y, z, c int) float32 {
+	return image.data[z].GetFloat(x, y, c)
+}
+
+// SetFloat sets the value of channel c of the pixel at (x,y) in slice z.
+// For unsigned integers val is clamped to the range 0 to 1.
+func (image *Image3D) SetFloat(x, y, z, c int, val float32) {
+	image.data[z].SetFloat(x, y, c, val)
+}
+
+// GetUint16 returns the value of channel c of the pixel at (x,y) in slice z with 16 bit.
+func (image *Image3D) GetUint16(x, y, z, c int) uint16 {
+	return image.data[z].GetUint16(x, y, c)
+}
+
+// SetUint16 sets the value of channel c of the pixel at (x,y) in slice z with 16 bit.
+func (image *Image3D) SetUint16(x, y, z, c int, val uint16) {
+	image.data[z].SetUint16(x, y, c, val)
+}
+
+// GetParams returns a copy of the parameters the image had been generated with.
+func (image *Image3D) GetParams() map[string]string {
+	params := make(map[string]string, len(image.params))
+	for key, val := range image.params {
+		params[key] = val
+	}
+	return params
+}
+
+// SetParam records a parameter the image had been generated with.
+// The parameters are stored along with the pixel data by Save.
+func (image *Image3D) SetParam(key string, val interface{}) {
+	if image.params == nil {
+		image.params = make(map[string]string)
+	}
+	image.params[key] = fmt.Sprint(val)
+}
+
 // GetR returns the red value of the pixel at (x,y) in slice z.
 func (image *Image3D) GetR(x, y, z int) uint8 {
 	return image.data[z].GetR(x, y)
@@ -0,0 +1,294 @@
+package image3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// ktx2Identifier is the file identifier at the beginning of each KTX2 file.
+var ktx2Identifier = [12]byte{0xAB, 'K', 'T', 'X', ' ', '2', '0', 0xBB, '\r', '\n', 0x1A, '\n'}
+
+// ktx2Header is the header and index that follow the identifier of a KTX2 file.
+type ktx2Header struct {
+	Identifier             [12]byte
+	VkFormat               uint32
+	TypeSize               uint32
+	PixelWidth             uint32
+	PixelHeight            uint32
+	PixelDepth             uint32
+	LayerCount             uint32
+	FaceCount              uint32
+	LevelCount             uint32
+	SupercompressionScheme uint32
+	DfdByteOffset          uint32
+	DfdByteLength          uint32
+	KvdByteOffset          uint32
+	KvdByteLength          uint32
+	SgdByteOffset          uint64
+	SgdByteLength          uint64
+}
+
+// ktx2Level is an entry of the level index of a KTX2 file.
+type ktx2Level struct {
+	ByteOffset             uint64
+	ByteLength             uint64
+	UncompressedByteLength uint64
+}
+
+// sizes of the parts of a KTX2 file.
+const (
+	ktx2HeaderSize = 80
+	ktx2LevelSize  = 24
+)
+
+// LoadKTX2 constructs a mip chain from the KTX2 file at the specified path.
+// Only 3D textures without supercompression are supported.
+// The key/value data is read into the parameters of the chain.
+func LoadKTX2(path string) (MipChain, error) {
+	file, err := ioutil.ReadFile(path)
+	if err != nil {
+		return MipChain{}, err
+	}
+
+	// read and validate the header
+	var header ktx2Header
+	if err := binary.Read(bytes.NewReader(file), binary.LittleEndian, &header); err != nil {
+		return MipChain{}, fmt.Errorf("%v: can't read KTX2 header: %v", path, err)
+	}
+	if header.Identifier != ktx2Identifier {
+		return MipChain{}, fmt.Errorf("%v: not a KTX2 file", path)
+	}
+	if header.SupercompressionScheme != 0 {
+		return MipChain{}, fmt.Errorf("%v: supercompression is not supported", path)
+	}
+	if header.PixelDepth == 0 || header.LayerCount > 1 || header.FaceCount > 1 {
+		return MipChain{}, fmt.Errorf("%v: not a 3D texture", path)
+	}
+	format, err := formatFromVk(header.VkFormat)
+	if err != nil {
+		return MipChain{}, fmt.Errorf("%v: %v", path, err)
+	}
+
+	// a level count of 0 requests the mip maps to be generated at load time
+	levels := int(header.LevelCount)
+	if levels == 0 {
+		levels = 1
+	}
+
+	// read the level index followed by each level
+	index := make([]ktx2Level, levels)
+	reader := bytes.NewReader(file[ktx2HeaderSize:])
+	if err := binary.Read(reader, binary.LittleEndian, index); err != nil {
+		return MipChain{}, fmt.Errorf("%v: can't read level index: %v", path, err)
+	}
+	chain := MipChain{format: format}
+	width, height, depth := int(header.PixelWidth), int(header.PixelHeight), int(header.PixelDepth)
+	for i, entry := range index {
+		start, end := entry.ByteOffset, entry.ByteOffset+entry.ByteLength
+		if end > uint64(len(file)) || start > end {
+			return MipChain{}, fmt.Errorf("%v: level %v is out of bounds", path, i)
+		}
+		data := make([]uint8, entry.ByteLength)
+		copy(data, file[start:end])
+		if err := chain.AddLevel(width, height, depth, data); err != nil {
+			return MipChain{}, fmt.Errorf("%v: %v", path, err)
+		}
+		width, height, depth = halve(width), halve(height), halve(depth)
+	}
+
+	// read the key/value data
+	start, end := uint64(header.KvdByteOffset), uint64(header.KvdByteOffset)+uint64(header.KvdByteLength)
+	if end > uint64(len(file)) {
+		return MipChain{}, fmt.Errorf("%v: key/value data is out of bounds", path)
+	}
+	chain.params, err = parseKeyValueData(file[start:end])
+	if err != nil {
+		return MipChain{}, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return chain, nil
+}
+
+// SaveKTX2 writes all levels of the mip chain as a 3D texture into a KTX2
+// file at the specified path. The parameters of the chain are stored as
+// key/value data.
+func (chain *MipChain) SaveKTX2(path string) error {
+	if len(chain.levels) == 0 {
+		return fmt.Errorf("Mip chain has no levels")
+	}
+	first := chain.levels[0]
+	levels := len(chain.levels)
+
+	// the data format descriptor follows the level index
+	dfd, err := chain.dataFormatDescriptor()
+	if err != nil {
+		return err
+	}
+	dfdOffset := ktx2HeaderSize + levels*ktx2LevelSize
+
+	// the key/value data follows the data format descriptor
+	kvd := chain.keyValueData()
+	kvdOffset := 0
+	if len(kvd) > 0 {
+		kvdOffset = dfdOffset + len(dfd)
+	}
+
+	// the levels are stored from smallest to largest, each level aligned to
+	// the least common multiple of the pixel size and 4
+	alignment := 4
+	if chain.format.GetPixelSize()%4 == 0 {
+		alignment = chain.format.GetPixelSize()
+	}
+	index := make([]ktx2Level, levels)
+	offset := dfdOffset + len(dfd) + len(kvd)
+	for i := levels - 1; i >= 0; i-- {
+		offset = align(offset, alignment)
+		size := uint64(len(chain.levels[i].data))
+		index[i] = ktx2Level{
+			ByteOffset:             uint64(offset),
+			ByteLength:             size,
+			UncompressedByteLength: size,
+		}
+		offset += int(size)
+	}
+
+	header := ktx2Header{
+		Identifier:    ktx2Identifier,
+		VkFormat:      formatInfos[chain.format].vkFormat,
+		TypeSize:      uint32(chain.format.GetComponentSize()),
+		PixelWidth:    uint32(first.width),
+		PixelHeight:   uint32(first.height),
+		PixelDepth:    uint32(first.depth),
+		FaceCount:     1,
+		LevelCount:    uint32(levels),
+		DfdByteOffset: uint32(dfdOffset),
+		DfdByteLength: uint32(len(dfd)),
+		KvdByteOffset: uint32(kvdOffset),
+		KvdByteLength: uint32(len(kvd)),
+	}
+
+	// write header, level index, data format descriptor, key/value data and levels
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, index); err != nil {
+		return err
+	}
+	buf.Write(dfd)
+	buf.Write(kvd)
+	for i := levels - 1; i >= 0; i-- {
+		buf.Write(make([]byte, int(index[i].ByteOffset)-buf.Len()))
+		buf.Write(chain.levels[i].data)
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// dataFormatDescriptor creates the basic data format descriptor of the
+// format, which describes the position and type of each channel.
+func (chain *MipChain) dataFormatDescriptor() ([]byte, error) {
+	const (
+		modelRGBSDA     = 1
+		primariesBT709  = 1
+		transferLinear  = 1
+		qualifierFloat  = 0x80
+		qualifierSigned = 0x40
+		channelAlpha    = 15
+	)
+	channels := chain.format.GetChannels()
+	componentSize := chain.format.GetComponentSize()
+	blockSize := 24 + 16*channels
+
+	words := []uint32{
+		uint32(4 + blockSize),
+		0,
+		2 | uint32(blockSize)<<16,
+		modelRGBSDA | primariesBT709<<8 | transferLinear<<16,
+		0,
+		uint32(chain.format.GetPixelSize()),
+		0,
+	}
+	for c := 0; c < channels; c++ {
+		id := uint32(c)
+		if channels == 4 && c == 3 {
+			id = channelAlpha
+		}
+		bitOffset := uint32(c * componentSize * 8)
+		bitLength := uint32(componentSize*8 - 1)
+
+		// float channels range from -1 to 1, normalized ones from 0 to 255
+		lower, upper := uint32(0), uint32(255)
+		if componentSize == 2 {
+			id |= qualifierFloat | qualifierSigned
+			lower, upper = 0xBF800000, 0x3F800000
+		}
+		words = append(words, bitOffset|bitLength<<16|id<<24, 0, lower, upper)
+	}
+
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, words); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// keyValueData encodes the parameters sorted by key. Each entry consists of
+// its length, the key and the value both terminated by 0 and is padded to 4
+// bytes.
+func (chain *MipChain) keyValueData() []byte {
+	keys := make([]string, 0, len(chain.params))
+	for key := range chain.params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		entry := key + "\x00" + chain.params[key] + "\x00"
+		binary.Write(&buf, binary.LittleEndian, uint32(len(entry)))
+		buf.WriteString(entry)
+		buf.Write(make([]byte, align(len(entry), 4)-len(entry)))
+	}
+	return buf.Bytes()
+}
+
+// parseKeyValueData decodes key/value data written by keyValueData.
+func parseKeyValueData(data []byte) (map[string]string, error) {
+	var params map[string]string
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return nil, fmt.Errorf("Truncated key/value data")
+		}
+		length := int(binary.LittleEndian.Uint32(data))
+		data = data[4:]
+		if length > len(data) {
+			return nil, fmt.Errorf("Key/value entry of %v bytes is out of bounds", length)
+		}
+		entry := data[:length]
+		separator := bytes.IndexByte(entry, 0)
+		if separator < 0 {
+			return nil, fmt.Errorf("Key/value entry without key")
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		params[string(entry[:separator])] = string(bytes.TrimSuffix(entry[separator+1:], []byte{0}))
+
+		// the padding of the last entry may be missing
+		padded := align(length, 4)
+		if padded > len(data) {
+			padded = len(data)
+		}
+		data = data[padded:]
+	}
+	return params, nil
+}
+
+// align rounds offset up to the next multiple of alignment.
+func align(offset, alignment int) int {
+	return (offset + alignment - 1) / alignment * alignment
+}
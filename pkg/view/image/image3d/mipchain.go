@@ -0,0 +1,287 @@
+package image3d
+
+import (
+	"fmt"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// Format specifies the pixel format of a mip chain.
+type Format int
+
+const (
+	// R8 stores one unsigned normalized byte per pixel.
+	R8 Format = iota
+	// RG8 stores two unsigned normalized bytes per pixel.
+	RG8
+	// RGBA8 stores four unsigned normalized bytes per pixel.
+	RGBA8
+	// R16F stores one half float per pixel.
+	R16F
+	// RGBA16F stores four half floats per pixel.
+	RGBA16F
+)
+
+// formatInfo describes how a format is stored in memory, in DDS and KTX2
+// files and how it is uploaded to OpenGL.
+type formatInfo struct {
+	name           string
+	channels       int
+	componentSize  int
+	dxgiFormat     uint32
+	vkFormat       uint32
+	internalFormat int32
+	pixelFormat    uint32
+	pixelType      uint32
+}
+
+var formatInfos = map[Format]formatInfo{
+	R8:      {"R8", 1, 1, 61, 9, gl.R8, gl.RED, gl.UNSIGNED_BYTE},
+	RG8:     {"RG8", 2, 1, 49, 16, gl.RG8, gl.RG, gl.UNSIGNED_BYTE},
+	RGBA8:   {"RGBA8", 4, 1, 28, 37, gl.RGBA8, gl.RGBA, gl.UNSIGNED_BYTE},
+	R16F:    {"R16F", 1, 2, 54, 76, gl.R16F, gl.RED, gl.HALF_FLOAT},
+	RGBA16F: {"RGBA16F", 4, 2, 10, 97, gl.RGBA16F, gl.RGBA, gl.HALF_FLOAT},
+}
+
+// GetChannels returns the number of channels of the format.
+func (format Format) GetChannels() int {
+	return formatInfos[format].channels
+}
+
+// GetComponentSize returns the size of one channel in bytes.
+func (format Format) GetComponentSize() int {
+	return formatInfos[format].componentSize
+}
+
+// GetPixelSize returns the size of one pixel in bytes.
+func (format Format) GetPixelSize() int {
+	info := formatInfos[format]
+	return info.channels * info.componentSize
+}
+
+// GetInternalFormat returns the OpenGL internal format, e.g. gl.RGBA16F.
+func (format Format) GetInternalFormat() int32 {
+	return formatInfos[format].internalFormat
+}
+
+// GetPixelFormat returns the OpenGL format of the pixel data, e.g. gl.RGBA.
+func (format Format) GetPixelFormat() uint32 {
+	return formatInfos[format].pixelFormat
+}
+
+// GetPixelType returns the OpenGL type of the pixel data, e.g. gl.HALF_FLOAT.
+func (format Format) GetPixelType() uint32 {
+	return formatInfos[format].pixelType
+}
+
+// String returns the name of the format.
+func (format Format) String() string {
+	if info, ok := formatInfos[format]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("Format(%d)", int(format))
+}
+
+// formatFromDXGI returns the format with the specified DXGI format code.
+func formatFromDXGI(code uint32) (Format, error) {
+	for format, info := range formatInfos {
+		if info.dxgiFormat == code {
+			return format, nil
+		}
+	}
+	return 0, fmt.Errorf("Unsupported DXGI format %v", code)
+}
+
+// formatFromVk returns the format with the specified Vulkan format code.
+func formatFromVk(code uint32) (Format, error) {
+	for format, info := range formatInfos {
+		if info.vkFormat == code {
+			return format, nil
+		}
+	}
+	return 0, fmt.Errorf("Unsupported Vulkan format %v", code)
+}
+
+// mipLevel stores the dimensions and the raw pixel data of one mip level.
+type mipLevel struct {
+	width  int
+	height int
+	depth  int
+	data   []uint8
+}
+
+// MipChain stores the raw pixel data of all mip levels of a 3D texture.
+// The first level has the full resolution, each following level halves each
+// dimension. The pixel data of each level is stored slice by slice, each
+// slice row by row, and multi byte components are stored in little endian
+// byte order.
+type MipChain struct {
+	format Format
+	levels []mipLevel
+	params map[string]string
+}
+
+// MakeMipChain constructs a mip chain of the specified format whose first
+// level has the size width x height x depth and holds the provided raw data.
+func MakeMipChain(format Format, width, height, depth int, data []uint8) (MipChain, error) {
+	if _, ok := formatInfos[format]; !ok {
+		return MipChain{}, fmt.Errorf("Unsupported format %v", format)
+	}
+
+	chain := MipChain{format: format}
+	err := chain.AddLevel(width, height, depth, data)
+	if err != nil {
+		return MipChain{}, err
+	}
+
+	return chain, nil
+}
+
+// MakeMipChainFromImage constructs a mip chain with one level that holds
+// the data of the image. One, two and four channel images are stored as R8,
+// RG8 and RGBA8. Three channel images are stored as RGBA8 with an alpha of 255.
+// One and four channel half float images are stored as R16F and RGBA16F.
+// Other pixel types like gl.FLOAT and gl.UNSIGNED_SHORT are not supported and
+// have to be converted to gl.HALF_FLOAT or gl.UNSIGNED_BYTE first.
+// The parameters of the image are copied to the chain.
+func MakeMipChainFromImage(image *Image3D) (MipChain, error) {
+	data := image.GetData()
+
+	var format Format
+	switch image.GetPixelType() {
+	case gl.HALF_FLOAT:
+		switch image.GetChannels() {
+		case 1:
+			format = R16F
+		case 4:
+			format = RGBA16F
+		default:
+			return MipChain{}, fmt.Errorf("Unsupported number of half float channels %v", image.GetChannels())
+		}
+	case gl.UNSIGNED_BYTE:
+		switch image.GetChannels() {
+		case 1:
+			format = R8
+		case 2:
+			format = RG8
+		case 3:
+			format = RGBA8
+			rgba := make([]uint8, 0, len(data)/3*4)
+			for i := 0; i < len(data); i += 3 {
+				rgba = append(rgba, data[i], data[i+1], data[i+2], 255)
+			}
+			data = rgba
+		case 4:
+			format = RGBA8
+		default:
+			return MipChain{}, fmt.Errorf("Unsupported number of channels %v", image.GetChannels())
+		}
+	default:
+		return MipChain{}, fmt.Errorf("Unsupported pixel type %v, convert the image to half floats or bytes first", image.GetPixelType())
+	}
+
+	chain, err := MakeMipChain(format, image.GetWidth(), image.GetHeight(), image.GetSlices(), data)
+	if err != nil {
+		return MipChain{}, err
+	}
+	chain.params = image.GetParams()
+
+	return chain, nil
+}
+
+// AddLevel appends a level of the specified size holding the provided raw data.
+// Each dimension of the level has to be half of the previous level rounded
+// down but at least 1. The size of the data has to match the size of the
+// level and the format.
+func (chain *MipChain) AddLevel(width, height, depth int, data []uint8) error {
+	if width <= 0 || height <= 0 || depth <= 0 {
+		return fmt.Errorf("Invalid level size (%v,%v,%v)", width, height, depth)
+	}
+	if n := len(chain.levels); n > 0 {
+		prev := chain.levels[n-1]
+		if width != halve(prev.width) || height != halve(prev.height) || depth != halve(prev.depth) {
+			return fmt.Errorf("Level (%v,%v,%v) doesn't follow level (%v,%v,%v)", width, height, depth, prev.width, prev.height, prev.depth)
+		}
+	}
+	size := width * height * depth * chain.format.GetPixelSize()
+	if len(data) != size {
+		return fmt.Errorf("Level (%v,%v,%v) %v needs %v bytes but got %v", width, height, depth, chain.format, size, len(data))
+	}
+
+	chain.levels = append(chain.levels, mipLevel{
+		width:  width,
+		height: height,
+		depth:  depth,
+		data:   data,
+	})
+
+	return nil
+}
+
+// ToImage converts the first level to an image.
+// Half float formats result in half float images. The parameters of the chain
+// are copied to the image.
+func (chain *MipChain) ToImage() (Image3D, error) {
+	if len(chain.levels) == 0 {
+		return Image3D{}, fmt.Errorf("Mip chain has no levels")
+	}
+
+	level := chain.levels[0]
+	data := make([]uint8, len(level.data))
+	copy(data, level.data)
+	image, err := MakeFromRawData(level.width, level.height, level.depth, chain.format.GetChannels(), chain.format.GetPixelType(), data)
+	if err != nil {
+		return Image3D{}, err
+	}
+	image.params = chain.GetParams()
+
+	return image, nil
+}
+
+// GetFormat returns the pixel format of all levels.
+func (chain *MipChain) GetFormat() Format {
+	return chain.format
+}
+
+// GetLevelCount returns the number of levels.
+func (chain *MipChain) GetLevelCount() int {
+	return len(chain.levels)
+}
+
+// GetLevelSize returns the width, height and depth of the specified level.
+func (chain *MipChain) GetLevelSize(level int) (int, int, int) {
+	l := chain.levels[level]
+	return l.width, l.height, l.depth
+}
+
+// GetLevelData returns the raw pixel data of the specified level.
+func (chain *MipChain) GetLevelData(level int) []uint8 {
+	return chain.levels[level].data
+}
+
+// GetParams returns a copy of the parameters the chain had been generated with.
+func (chain *MipChain) GetParams() map[string]string {
+	params := make(map[string]string, len(chain.params))
+	for key, val := range chain.params {
+		params[key] = val
+	}
+	return params
+}
+
+// SetParam records a parameter the chain had been generated with.
+// The parameters are stored as key/value data by SaveKTX2.
+func (chain *MipChain) SetParam(key string, val interface{}) {
+	if chain.params == nil {
+		chain.params = make(map[string]string)
+	}
+	chain.params[key] = fmt.Sprint(val)
+}
+
+// String pretty prints information about the mip chain.
+func (chain MipChain) String() string {
+	if len(chain.levels) == 0 {
+		return fmt.Sprintf("MipChain %v empty", chain.format)
+	}
+	l := chain.levels[0]
+	return fmt.Sprintf("MipChain (%v,%v,%v) %v %v levels", l.width, l.height, l.depth, chain.format, len(chain.levels))
+}
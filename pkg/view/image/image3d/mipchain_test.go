@@ -0,0 +1,209 @@
+package image3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// formats are all formats of a mip chain
+var formats = []Format{R8, RG8, RGBA8, R16F, RGBA16F}
+
+// makeTestMipChain creates a full mip chain of the specified format whose
+// first level has the size 8x4x2 and whose bytes differ between levels.
+func makeTestMipChain(t *testing.T, format Format) MipChain {
+	chain := MipChain{format: format}
+	width, height, depth := 8, 4, 2
+	for level := 0; ; level++ {
+		data := make([]uint8, width*height*depth*format.GetPixelSize())
+		for i := range data {
+			data[i] = uint8(i*5 + level*31)
+		}
+		if err := chain.AddLevel(width, height, depth, data); err != nil {
+			t.Fatal(err)
+		}
+		if width == 1 && height == 1 && depth == 1 {
+			break
+		}
+		width, height, depth = halve(width), halve(height), halve(depth)
+	}
+	return chain
+}
+
+// checkMipChainsEqual fails if the format, the number of levels or any level differs.
+func checkMipChainsEqual(t *testing.T, name string, actual, expected MipChain) {
+	if actual.GetFormat() != expected.GetFormat() {
+		t.Errorf("%v: format is %v instead of %v", name, actual.GetFormat(), expected.GetFormat())
+	}
+	if actual.GetLevelCount() != expected.GetLevelCount() {
+		t.Fatalf("%v: %v levels instead of %v", name, actual.GetLevelCount(), expected.GetLevelCount())
+	}
+	for level := 0; level < expected.GetLevelCount(); level++ {
+		aw, ah, ad := actual.GetLevelSize(level)
+		ew, eh, ed := expected.GetLevelSize(level)
+		if aw != ew || ah != eh || ad != ed {
+			t.Errorf("%v: level %v has size (%v,%v,%v) instead of (%v,%v,%v)", name, level, aw, ah, ad, ew, eh, ed)
+		}
+		if !bytes.Equal(actual.GetLevelData(level), expected.GetLevelData(level)) {
+			t.Errorf("%v: data of level %v differs", name, level)
+		}
+	}
+}
+
+func TestMipChainLevels(t *testing.T) {
+	chain := makeTestMipChain(t, R8)
+	if chain.GetLevelCount() != 4 {
+		t.Errorf("8x4x2 has %v levels instead of 4", chain.GetLevelCount())
+	}
+	if err := chain.AddLevel(1, 1, 1, make([]uint8, 1)); err != nil {
+		t.Errorf("1x1x1 can't follow 1x1x1: %v", err)
+	}
+
+	invalid := MipChain{format: RGBA16F}
+	if err := invalid.AddLevel(4, 4, 4, make([]uint8, 4*4*4*8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := invalid.AddLevel(2, 2, 1, make([]uint8, 2*2*1*8)); err == nil {
+		t.Error("2x2x1 mustn't follow 4x4x4")
+	}
+	if err := invalid.AddLevel(2, 2, 2, make([]uint8, 2*2*2*4)); err == nil {
+		t.Error("level with half of the data mustn't be added")
+	}
+}
+
+func TestDDSRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range formats {
+		chain := makeTestMipChain(t, format)
+		path := filepath.Join(dir, format.String()+".dds")
+		if err := chain.SaveDDS(path); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		loaded, err := LoadDDS(path)
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		checkMipChainsEqual(t, format.String()+" dds", loaded, chain)
+
+		// the file consists of the magic number, both headers and all levels
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := 4 + binary.Size(ddsHeader{}) + binary.Size(ddsHeaderDX10{})
+		for level := 0; level < chain.GetLevelCount(); level++ {
+			size += len(chain.GetLevelData(level))
+		}
+		if len(file) != size {
+			t.Errorf("%v: dds file has %v bytes instead of %v", format, len(file), size)
+		}
+		if binary.Size(ddsHeader{}) != 124 {
+			t.Errorf("dds header has %v bytes instead of 124", binary.Size(ddsHeader{}))
+		}
+	}
+}
+
+func TestKTX2RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range formats {
+		chain := makeTestMipChain(t, format)
+		path := filepath.Join(dir, format.String()+".ktx2")
+		if err := chain.SaveKTX2(path); err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		loaded, err := LoadKTX2(path)
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		checkMipChainsEqual(t, format.String()+" ktx2", loaded, chain)
+
+		// the levels are stored from smallest to largest and aligned
+		file, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		index := make([]ktx2Level, chain.GetLevelCount())
+		if err := binary.Read(bytes.NewReader(file[ktx2HeaderSize:]), binary.LittleEndian, index); err != nil {
+			t.Fatal(err)
+		}
+		alignment := uint64(4)
+		if format.GetPixelSize()%4 == 0 {
+			alignment = uint64(format.GetPixelSize())
+		}
+		for level, entry := range index {
+			if entry.ByteOffset%alignment != 0 {
+				t.Errorf("%v: level %v at offset %v isn't aligned to %v", format, level, entry.ByteOffset, alignment)
+			}
+			if level > 0 && entry.ByteOffset >= index[level-1].ByteOffset {
+				t.Errorf("%v: level %v is stored after level %v", format, level, level-1)
+			}
+		}
+		if binary.Size(ktx2Header{}) != ktx2HeaderSize || binary.Size(ktx2Level{}) != ktx2LevelSize {
+			t.Errorf("ktx2 header has %v bytes and level %v bytes", binary.Size(ktx2Header{}), binary.Size(ktx2Level{}))
+		}
+	}
+}
+
+func TestMipChainImageRoundTrip(t *testing.T) {
+	for _, format := range formats {
+		chain := makeTestMipChain(t, format)
+		image, err := chain.ToImage()
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		converted, err := MakeMipChainFromImage(&image)
+		if err != nil {
+			t.Fatalf("%v: %v", format, err)
+		}
+		if converted.GetFormat() != format || !bytes.Equal(converted.GetLevelData(0), chain.GetLevelData(0)) {
+			t.Errorf("%v: first level differs after converting to an image and back", format)
+		}
+	}
+}
+
+func TestKTX2Params(t *testing.T) {
+	chain := makeTestMipChain(t, RGBA8)
+	chain.SetParam("seed", 42)
+	chain.SetParam("r", "perlin-worley 4")
+	chain.SetParam("a", "")
+
+	path := filepath.Join(t.TempDir(), "params.ktx2")
+	if err := chain.SaveKTX2(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadKTX2(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMipChainsEqual(t, "params", loaded, chain)
+	if !reflect.DeepEqual(loaded.GetParams(), chain.GetParams()) {
+		t.Errorf("params are %v instead of %v", loaded.GetParams(), chain.GetParams())
+	}
+
+	// the parameters are passed on to and from images
+	image, err := loaded.ToImage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, err := MakeMipChainFromImage(&image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(converted.GetParams(), chain.GetParams()) {
+		t.Errorf("params are %v instead of %v after converting to an image and back", converted.GetParams(), chain.GetParams())
+	}
+
+	invalid := [][]byte{
+		{1, 0},
+		{8, 0, 0, 0, 'k', 0},
+		{2, 0, 0, 0, 'k', 'v', 0, 0},
+	}
+	for _, data := range invalid {
+		if _, err := parseKeyValueData(data); err == nil {
+			t.Errorf("key/value data %v: expected an error", data)
+		}
+	}
+}
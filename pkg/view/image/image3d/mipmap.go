@@ -0,0 +1,264 @@
+package image3d
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/internal/sampling"
+)
+
+// MipFilter specifies the filter that is used to downsample the levels of a mip chain.
+type MipFilter int
+
+const (
+	// BoxFilter averages the 2x2x2 pixels that are covered by a pixel of the next level.
+	BoxFilter MipFilter = iota
+	// KaiserFilter uses a Kaiser windowed sinc, which keeps more detail than
+	// the box filter with barely any ringing.
+	KaiserFilter
+	// LanczosFilter uses a three lobed Lanczos windowed sinc, which is the
+	// sharpest filter but may ring at hard edges.
+	LanczosFilter
+)
+
+// mipFilterInfo describes the kernel of a filter. The radius is specified in
+// pixels of the downsampled level.
+type mipFilterInfo struct {
+	name   string
+	radius float64
+	weight func(x float64) float64
+}
+
+var mipFilterInfos = map[MipFilter]mipFilterInfo{
+	BoxFilter:     {"box", 0.5, boxWeight},
+	KaiserFilter:  {"kaiser", 3, kaiserWeight},
+	LanczosFilter: {"lanczos", 3, lanczosWeight},
+}
+
+// String returns the name of the filter.
+func (filter MipFilter) String() string {
+	if info, ok := mipFilterInfos[filter]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("MipFilter(%d)", int(filter))
+}
+
+// ParseMipFilter returns the filter with the specified name, which is one of
+// box, kaiser and lanczos.
+func ParseMipFilter(name string) (MipFilter, error) {
+	for filter, info := range mipFilterInfos {
+		if info.name == name {
+			return filter, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown mip filter %v", name)
+}
+
+// GenerateMipChain constructs a mip chain with all levels down to 1x1x1 from
+// the image. Each level is calculated from the previous one by downsampling it
+// with the specified filter. If tileable is true the filter wraps around the
+// borders of the image, so that each level tiles seamlessly like the image,
+// otherwise the border pixels are repeated. The format of the chain is chosen
+// as described in MakeMipChainFromImage, so float and 16 bit images have to be
+// converted to half floats or bytes first. The parameters of the image are
+// copied to the chain.
+func (image *Image3D) GenerateMipChain(filter MipFilter, tileable bool) (MipChain, error) {
+	info, ok := mipFilterInfos[filter]
+	if !ok {
+		return MipChain{}, fmt.Errorf("Unsupported mip filter %v", filter)
+	}
+
+	chain, err := MakeMipChainFromImage(image)
+	if err != nil {
+		return MipChain{}, err
+	}
+
+	level := volumeFromImage(image)
+	for level.width > 1 || level.height > 1 || level.depth > 1 {
+		// downsample each axis separately
+		level = level.downsample(0, info, tileable)
+		level = level.downsample(1, info, tileable)
+		level = level.downsample(2, info, tileable)
+
+		// convert the level with the same rules as the first level
+		levelImage, err := MakeFromFloat32(level.width, level.height, level.depth, level.data)
+		if err != nil {
+			return MipChain{}, err
+		}
+		levelImage, err = levelImage.Convert(image.pixelType)
+		if err != nil {
+			return MipChain{}, err
+		}
+		levelChain, err := MakeMipChainFromImage(&levelImage)
+		if err != nil {
+			return MipChain{}, err
+		}
+		err = chain.AddLevel(level.width, level.height, level.depth, levelChain.GetLevelData(0))
+		if err != nil {
+			return MipChain{}, err
+		}
+	}
+
+	return chain, nil
+}
+
+// mipVolume stores the values of a level as floats.
+type mipVolume struct {
+	width    int
+	height   int
+	depth    int
+	channels int
+	data     []float32
+}
+
+// volumeFromImage converts the image to a float volume.
+func volumeFromImage(image *Image3D) mipVolume {
+	return mipVolume{
+		width:    image.width,
+		height:   image.height,
+		depth:    image.slices,
+		channels: image.channels,
+		data:     image.GetFloat32Data(),
+	}
+}
+
+// downsample halves the volume along the specified axis, with 0 being x, 1
+// being y and 2 being z. Axes of size 1 are kept as they are.
+func (vol mipVolume) downsample(axis int, info mipFilterInfo, tileable bool) mipVolume {
+	size := [3]int{vol.width, vol.height, vol.depth}
+	if size[axis] == 1 {
+		return vol
+	}
+	taps := makeMipTaps(size[axis], halve(size[axis]), info, tileable)
+
+	// the stride is the distance of neighboring pixels along the axis
+	strides := [3]int{vol.channels, vol.width * vol.channels, vol.width * vol.height * vol.channels}
+	dstSize := size
+	dstSize[axis] = halve(size[axis])
+	dstStrides := [3]int{vol.channels, dstSize[0] * vol.channels, dstSize[0] * dstSize[1] * vol.channels}
+
+	result := mipVolume{
+		width:    dstSize[0],
+		height:   dstSize[1],
+		depth:    dstSize[2],
+		channels: vol.channels,
+		data:     make([]float32, dstSize[0]*dstSize[1]*dstSize[2]*vol.channels),
+	}
+	for z := 0; z < dstSize[2]; z++ {
+		for y := 0; y < dstSize[1]; y++ {
+			for x := 0; x < dstSize[0]; x++ {
+				pos := [3]int{x, y, z}
+				dst := x*dstStrides[0] + y*dstStrides[1] + z*dstStrides[2]
+
+				// start of the source line along the axis
+				pos[axis] = 0
+				src := pos[0]*strides[0] + pos[1]*strides[1] + pos[2]*strides[2]
+
+				i := [3]int{x, y, z}[axis]
+				for _, tap := range taps[i] {
+					off := src + tap.index*strides[axis]
+					for c := 0; c < vol.channels; c++ {
+						result.data[dst+c] += tap.weight * vol.data[off+c]
+					}
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// mipTap is a source pixel and its weight that contributes to a pixel of the
+// downsampled level.
+type mipTap struct {
+	index  int
+	weight float32
+}
+
+// makeMipTaps calculates the source pixels and normalized weights of each pixel
+// when downsampling from srcSize to dstSize pixels.
+func makeMipTaps(srcSize, dstSize int, info mipFilterInfo, tileable bool) [][]mipTap {
+	scale := float64(srcSize) / float64(dstSize)
+	radius := info.radius * scale
+	wrap := Clamp
+	if tileable {
+		wrap = Repeat
+	}
+
+	taps := make([][]mipTap, dstSize)
+	for i := 0; i < dstSize; i++ {
+		center := (float64(i) + 0.5) * scale
+		first := int(math.Floor(center - radius))
+		last := int(math.Ceil(center + radius))
+
+		// accumulate the weights of source pixels that wrap to the same pixel
+		weights := map[int]float64{}
+		var order []int
+		sum := 0.0
+		for j := first; j <= last; j++ {
+			w := info.weight((float64(j) + 0.5 - center) / scale)
+			if w == 0 {
+				continue
+			}
+			idx := sampling.WrapIndex(j, srcSize, wrap)
+			if _, ok := weights[idx]; !ok {
+				order = append(order, idx)
+			}
+			weights[idx] += w
+			sum += w
+		}
+
+		for _, idx := range order {
+			taps[i] = append(taps[i], mipTap{idx, float32(weights[idx] / sum)})
+		}
+	}
+
+	return taps
+}
+
+// boxWeight is 1 within half a pixel of the center.
+func boxWeight(x float64) float64 {
+	if math.Abs(x) <= 0.5 {
+		return 1
+	}
+	return 0
+}
+
+// kaiserWeight is a sinc windowed with a Kaiser window of alpha 4 and width 3.
+func kaiserWeight(x float64) float64 {
+	const (
+		width = 3.0
+		alpha = 4.0
+	)
+	t := x / width
+	if t*t >= 1 {
+		return 0
+	}
+	return sinc(x) * bessel0(alpha*math.Sqrt(1-t*t)) / bessel0(alpha)
+}
+
+// lanczosWeight is a sinc windowed with a sinc of three lobes.
+func lanczosWeight(x float64) float64 {
+	if math.Abs(x) >= 3 {
+		return 0
+	}
+	return sinc(x) * sinc(x/3)
+}
+
+// sinc is the normalized sinc function.
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// bessel0 is the zeroth order modified Bessel function of the first kind.
+func bessel0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > 1e-12*sum; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
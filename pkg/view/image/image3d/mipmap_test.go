@@ -0,0 +1,177 @@
+package image3d
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+)
+
+// filters are all filters to downsample a mip chain with
+var filters = []MipFilter{BoxFilter, KaiserFilter, LanczosFilter}
+
+func TestGenerateMipChainBox(t *testing.T) {
+	image, err := MakeFromData(2, 2, 2, []uint8{0, 2, 4, 6, 8, 10, 12, 14})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tileable := range []bool{true, false} {
+		chain, err := image.GenerateMipChain(BoxFilter, tileable)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chain.GetLevelCount() != 2 {
+			t.Fatalf("tileable %v: %v levels instead of 2", tileable, chain.GetLevelCount())
+		}
+		if mean := chain.GetLevelData(1)[0]; mean != 7 {
+			t.Errorf("tileable %v: mean is %v instead of 7", tileable, mean)
+		}
+	}
+}
+
+func TestGenerateMipChainConstant(t *testing.T) {
+	tests := []struct {
+		width, height, depth int
+		sizes                [][3]int
+	}{
+		{5, 3, 2, [][3]int{{5, 3, 2}, {2, 1, 1}, {1, 1, 1}}},
+		{7, 1, 4, [][3]int{{7, 1, 4}, {3, 1, 2}, {1, 1, 1}}},
+		{4, 4, 4, [][3]int{{4, 4, 4}, {2, 2, 2}, {1, 1, 1}}},
+	}
+	for _, test := range tests {
+		data := make([]uint8, test.width*test.height*test.depth)
+		for i := range data {
+			data[i] = 100
+		}
+		image, err := MakeFromData(test.width, test.height, test.depth, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, filter := range filters {
+			for _, tileable := range []bool{true, false} {
+				name := fmt.Sprintf("%vx%vx%v %v tileable %v", test.width, test.height, test.depth, filter, tileable)
+				chain, err := image.GenerateMipChain(filter, tileable)
+				if err != nil {
+					t.Fatalf("%v: %v", name, err)
+				}
+				if chain.GetLevelCount() != len(test.sizes) {
+					t.Fatalf("%v: %v levels instead of %v", name, chain.GetLevelCount(), len(test.sizes))
+				}
+				for level, size := range test.sizes {
+					w, h, d := chain.GetLevelSize(level)
+					if w != size[0] || h != size[1] || d != size[2] {
+						t.Errorf("%v: level %v has size (%v,%v,%v) instead of %v", name, level, w, h, d, size)
+					}
+					for i, val := range chain.GetLevelData(level) {
+						if val != 100 {
+							t.Errorf("%v: pixel %v of level %v is %v instead of 100", name, i, level, val)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateMipChainTileable(t *testing.T) {
+	// the values along x repeat every 4 pixels
+	period := []float32{0, 1, 0.5, 0.25}
+	values := make([]float32, 8*2*2)
+	for i := range values {
+		values[i] = period[i%4]
+	}
+	image, err := MakeFromFloat32(8, 2, 2, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image, err = image.Convert(gl.HALF_FLOAT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first level repeats every 2 pixels
+	for _, filter := range filters {
+		chain, err := image.GenerateMipChain(filter, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		level, err := MakeFromRawData(4, 1, 1, 1, gl.HALF_FLOAT, chain.GetLevelData(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x := 0; x < 2; x++ {
+			if a, b := level.GetFloat(x, 0, 0, 0), level.GetFloat(x+2, 0, 0, 0); a != b {
+				t.Errorf("%v: pixel %v is %v but pixel %v is %v", filter, x, a, x+2, b)
+			}
+		}
+	}
+}
+
+func TestGenerateMipChainUnsupported(t *testing.T) {
+	image, err := MakeFromFloat32(2, 2, 2, make([]float32, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := image.GenerateMipChain(BoxFilter, true); err == nil {
+		t.Error("float image: expected an error")
+	}
+	if _, err := image.GenerateMipChain(MipFilter(7), true); err == nil {
+		t.Error("unknown filter: expected an error")
+	}
+}
+
+func TestMakeMipTaps(t *testing.T) {
+	for _, filter := range filters {
+		info := mipFilterInfos[filter]
+		for _, size := range [][2]int{{8, 4}, {5, 2}, {7, 3}, {2, 1}} {
+			for _, tileable := range []bool{true, false} {
+				for i, taps := range makeMipTaps(size[0], size[1], info, tileable) {
+					sum := float32(0)
+					for _, tap := range taps {
+						if tap.index < 0 || tap.index >= size[0] {
+							t.Errorf("%v %v->%v: tap of pixel %v at %v is out of range", filter, size[0], size[1], i, tap.index)
+						}
+						sum += tap.weight
+					}
+					if math.Abs(float64(sum-1)) > 1e-6 {
+						t.Errorf("%v %v->%v tileable %v: weights of pixel %v sum up to %v", filter, size[0], size[1], tileable, i, sum)
+					}
+				}
+			}
+		}
+	}
+
+	// the first pixel wraps around to the last source pixel only if tileable
+	info := mipFilterInfos[KaiserFilter]
+	contains := func(taps []mipTap, index int) bool {
+		for _, tap := range taps {
+			if tap.index == index {
+				return true
+			}
+		}
+		return false
+	}
+	if !contains(makeMipTaps(8, 4, info, true)[0], 7) {
+		t.Error("tileable taps of pixel 0 don't contain pixel 7")
+	}
+	if contains(makeMipTaps(8, 4, info, false)[0], 7) {
+		t.Error("clamped taps of pixel 0 contain pixel 7")
+	}
+}
+
+func TestMipFilterWeights(t *testing.T) {
+	for _, filter := range []MipFilter{KaiserFilter, LanczosFilter} {
+		weight := mipFilterInfos[filter].weight
+		if weight(0) != 1 {
+			t.Errorf("%v: weight at 0 is %v instead of 1", filter, weight(0))
+		}
+		for _, x := range []float64{-2, -1, 1, 2, 3} {
+			if math.Abs(weight(x)) > 1e-12 {
+				t.Errorf("%v: weight at %v is %v instead of 0", filter, x, weight(x))
+			}
+		}
+	}
+}
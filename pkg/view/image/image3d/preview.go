@@ -0,0 +1,194 @@
+package image3d
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+)
+
+// PreviewOptions specifies how the slices of an image are laid out in a
+// contact sheet or in the frames of a fly-through.
+type PreviewOptions struct {
+	// Columns is the number of slices per row of a contact sheet.
+	// With 0 the slices are arranged in a square grid.
+	Columns int
+	// Step selects every Step-th slice, thus 1 selects all slices.
+	Step int
+	// PerChannel shows each channel of a slice as a separate gray image,
+	// with the slices of each channel in their own rows.
+	PerChannel bool
+	// Labels draws the slice number and in case of PerChannel the channel
+	// name onto each slice.
+	Labels bool
+	// Spacing is the number of pixels between neighboring slices.
+	Spacing int
+}
+
+// DefaultPreviewOptions shows every slice with labels in a square grid.
+func DefaultPreviewOptions() PreviewOptions {
+	return PreviewOptions{
+		Columns:    0,
+		Step:       1,
+		PerChannel: false,
+		Labels:     true,
+		Spacing:    2,
+	}
+}
+
+// channelNames are used to label the channels of a per channel preview.
+var channelNames = []string{"R", "G", "B", "A"}
+
+// MakeContactSheet arranges the slices of the image in a grid.
+// The sheet has 8 bit per channel and is gray for single channel images and
+// per channel previews, otherwise it is rgb and the alpha channel is ignored.
+func (image *Image3D) MakeContactSheet(options PreviewOptions) (image2d.Image2D, error) {
+	slices, err := image.selectSlices(options)
+	if err != nil {
+		return image2d.Image2D{}, err
+	}
+
+	columns := options.Columns
+	if columns <= 0 {
+		columns = int(math.Ceil(math.Sqrt(float64(len(slices)))))
+	}
+	if columns > len(slices) {
+		columns = len(slices)
+	}
+
+	return image.makeSheet(slices, columns, options)
+}
+
+// SaveGif saves a fly-through of the slices as animated gif at the specified path.
+// Delay is the time each slice is shown in 100ths of a second. Each frame shows
+// one slice or in case of PerChannel all channels of a slice below each other.
+func (image *Image3D) SaveGif(path string, delay int, options PreviewOptions) error {
+	frames, err := image.makeFrames(options)
+	if err != nil {
+		return err
+	}
+	return image2d.SaveGif(path, frames, delay)
+}
+
+// SaveAPNG saves a fly-through of the slices as animated png at the specified path.
+// Delay is the time each slice is shown in 100ths of a second. Each frame shows
+// one slice or in case of PerChannel all channels of a slice below each other.
+func (image *Image3D) SaveAPNG(path string, delay int, options PreviewOptions) error {
+	frames, err := image.makeFrames(options)
+	if err != nil {
+		return err
+	}
+	return image2d.SaveAPNG(path, frames, delay)
+}
+
+// makeFrames creates one frame for each selected slice.
+func (image *Image3D) makeFrames(options PreviewOptions) ([]image2d.Image2D, error) {
+	slices, err := image.selectSlices(options)
+	if err != nil {
+		return nil, err
+	}
+
+	// frames have no border
+	options.Spacing = 0
+	var frames []image2d.Image2D
+	for _, z := range slices {
+		frame, err := image.makeSheet([]int{z}, 1, options)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// selectSlices returns the indices of the slices selected by the options.
+// An error is returned if no slice is selected.
+func (image *Image3D) selectSlices(options PreviewOptions) ([]int, error) {
+	if options.Step < 1 {
+		return nil, errors.New("Step must be at least 1.")
+	}
+	if options.Spacing < 0 {
+		return nil, errors.New("Spacing must not be negative.")
+	}
+
+	var slices []int
+	for z := 0; z < image.slices; z += options.Step {
+		slices = append(slices, z)
+	}
+	if len(slices) == 0 {
+		return nil, errors.New("Image has no slices to preview.")
+	}
+	return slices, nil
+}
+
+// makeSheet arranges the specified slices in a grid with the specified number
+// of columns. In case of PerChannel each channel gets its own rows.
+func (image *Image3D) makeSheet(slices []int, columns int, options PreviewOptions) (image2d.Image2D, error) {
+	bands, channels := 1, 3
+	if options.PerChannel {
+		bands = image.channels
+	}
+	if options.PerChannel || image.channels == 1 {
+		channels = 1
+	}
+
+	// calc sheet size
+	rows := (len(slices) + columns - 1) / columns
+	spacing := options.Spacing
+	width := columns*image.width + (columns+1)*spacing
+	height := bands*rows*image.height + (bands*rows+1)*spacing
+	sheet, err := image2d.Make(width, height, channels)
+	if err != nil {
+		return image2d.Image2D{}, err
+	}
+	for c := 0; c < channels; c++ {
+		sheet.FillChannel(c, 0.25)
+	}
+
+	labelScale := image.width / 64
+	if labelScale < 1 {
+		labelScale = 1
+	}
+	for band := 0; band < bands; band++ {
+		for i, z := range slices {
+			col, row := i%columns, band*rows+i/columns
+			x0 := spacing + col*(image.width+spacing)
+			y0 := spacing + row*(image.height+spacing)
+			image.copySlice(&sheet, x0, y0, z, band, options.PerChannel)
+
+			if options.Labels {
+				label := fmt.Sprint(z)
+				if options.PerChannel {
+					label = channelNames[band] + " " + label
+				}
+				sheet.DrawLabel(x0, y0, labelScale, label)
+			}
+		}
+	}
+
+	return sheet, nil
+}
+
+// copySlice copies slice z into the sheet with its top left corner at (x0,y0).
+// In case of perChannel only the specified channel is copied, otherwise up to
+// three channels with missing channels set to 0.
+func (image *Image3D) copySlice(sheet *image2d.Image2D, x0, y0, z, channel int, perChannel bool) {
+	slice := &image.data[z]
+	for y := 0; y < image.height; y++ {
+		for x := 0; x < image.width; x++ {
+			if perChannel {
+				sheet.SetFloat(x0+x, y0+y, 0, slice.GetFloat(x, y, channel))
+				continue
+			}
+			for c := 0; c < sheet.GetChannels(); c++ {
+				var val float32
+				if c < image.channels {
+					val = slice.GetFloat(x, y, c)
+				}
+				sheet.SetFloat(x0+x, y0+y, c, val)
+			}
+		}
+	}
+}
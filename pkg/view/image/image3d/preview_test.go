@@ -0,0 +1,180 @@
+package image3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makePreviewImage creates a 4x2x5 rgb image whose value of channel c at
+// (x,y) in slice z is z*40 + c*10 + x + y*4.
+func makePreviewImage(t *testing.T) Image3D {
+	data := make([]uint8, 0, 4*2*5*3)
+	for z := 0; z < 5; z++ {
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				for c := 0; c < 3; c++ {
+					data = append(data, uint8(z*40+c*10+x+y*4))
+				}
+			}
+		}
+	}
+	image, err := MakeFromData(4, 2, 5, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+func TestMakeContactSheet(t *testing.T) {
+	image := makePreviewImage(t)
+	options := PreviewOptions{Step: 1, Spacing: 2}
+	tests := []struct {
+		name          string
+		columns, step int
+		perChannel    bool
+		width, height int
+		channels      int
+		// slice z is expected with channel c at its top left corner (x,y)
+		x, y, z, c int
+	}{
+		// 5 slices in a 3x2 grid
+		{"square", 0, 1, false, 3*4 + 4*2, 2*2 + 3*2, 3, 2 + 6, 2 + 4, 4, 0},
+		{"columns", 5, 1, false, 5*4 + 6*2, 2 + 2*2, 3, 2 + 4*6, 2, 4, 0},
+		{"too many columns", 9, 1, false, 5*4 + 6*2, 2 + 2*2, 3, 2 + 4*6, 2, 4, 0},
+		{"step", 0, 2, false, 2*4 + 3*2, 2*2 + 3*2, 3, 2, 2 + 4, 4, 0},
+		// each of the 3 channels has 2 rows
+		{"per channel", 0, 1, true, 3*4 + 4*2, 3*2*2 + 7*2, 1, 2 + 6, 2 + 3*4, 4, 1},
+		{"per channel last band", 2, 2, true, 2*4 + 3*2, 3*2*2 + 7*2, 1, 2, 2 + 5*4, 4, 2},
+	}
+	for _, test := range tests {
+		options.Columns, options.Step, options.PerChannel = test.columns, test.step, test.perChannel
+		sheet, err := image.MakeContactSheet(options)
+		if err != nil {
+			t.Fatalf("%v: %v", test.name, err)
+		}
+		if sheet.GetWidth() != test.width || sheet.GetHeight() != test.height || sheet.GetChannels() != test.channels {
+			t.Errorf("%v: sheet is %v instead of %vx%v with %v channels", test.name, sheet, test.width, test.height, test.channels)
+			continue
+		}
+
+		// the spacing is filled with gray
+		if val := sheet.GetFloat(0, 0, 0); val != 64.0/255 {
+			t.Errorf("%v: spacing is %v", test.name, val)
+		}
+		for y := 0; y < 2; y++ {
+			for x := 0; x < 4; x++ {
+				expected := uint8(test.z*40 + test.c*10 + x + y*4)
+				for c := 0; c < test.channels; c++ {
+					if val := sheet.GetFloat(test.x+x, test.y+y, c); val != float32(expected+uint8(c*10))/255 {
+						t.Errorf("%v: channel %v at (%v,%v) is %v instead of %v", test.name, c, x, y, val*255, expected+uint8(c*10))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestPreviewInvalid(t *testing.T) {
+	image := makePreviewImage(t)
+	invalid := []PreviewOptions{
+		{Step: 0},
+		{Step: 1, Spacing: -1},
+	}
+	for _, options := range invalid {
+		if _, err := image.MakeContactSheet(options); err == nil {
+			t.Errorf("%+v: expected an error", options)
+		}
+	}
+
+	// an image without slices has nothing to preview
+	var empty Image3D
+	if _, err := empty.MakeContactSheet(DefaultPreviewOptions()); err == nil {
+		t.Error("contact sheet of an empty image: expected an error")
+	}
+	if err := empty.SaveGif(filepath.Join(t.TempDir(), "empty.gif"), 10, DefaultPreviewOptions()); err == nil {
+		t.Error("gif of an empty image: expected an error")
+	}
+}
+
+func TestSaveGif(t *testing.T) {
+	image := makePreviewImage(t)
+	path := filepath.Join(t.TempDir(), "slices.gif")
+	options := DefaultPreviewOptions()
+	options.PerChannel = true
+	if err := image.SaveGif(path, 7, options); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	animation, err := gif.DecodeAll(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one frame per slice with the channels below each other
+	if len(animation.Image) != 5 {
+		t.Fatalf("%v frames instead of 5", len(animation.Image))
+	}
+	for i, frame := range animation.Image {
+		if size := frame.Bounds().Size(); size.X != 4 || size.Y != 3*2 {
+			t.Errorf("frame %v has size %v instead of 4x6", i, size)
+		}
+		if animation.Delay[i] != 7 {
+			t.Errorf("frame %v has a delay of %v instead of 7", i, animation.Delay[i])
+		}
+	}
+}
+
+func TestSaveAPNG(t *testing.T) {
+	image := makePreviewImage(t)
+	path := filepath.Join(t.TempDir(), "slices.png")
+	options := DefaultPreviewOptions()
+	options.Step = 2
+	if err := image.SaveAPNG(path, 5, options); err != nil {
+		t.Fatal(err)
+	}
+
+	// decoders without animation support show the first frame
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := first.Bounds().Size(); size.X != 4 || size.Y != 2 {
+		t.Errorf("first frame has size %v instead of 4x2", size)
+	}
+
+	// the animation control chunk holds the number of frames and each frame
+	// control chunk the delay
+	frames, controls := -1, 0
+	for off := 8; off+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[off:]))
+		chunk := data[off+8 : off+8+length]
+		switch string(data[off+4 : off+8]) {
+		case "acTL":
+			frames = int(binary.BigEndian.Uint32(chunk))
+		case "fcTL":
+			controls++
+			if num, den := binary.BigEndian.Uint16(chunk[20:]), binary.BigEndian.Uint16(chunk[22:]); num != 5 || den != 100 {
+				t.Errorf("frame delay is %v/%v instead of 5/100", num, den)
+			}
+		}
+		off += 12 + length
+	}
+	if frames != 3 || controls != 3 {
+		t.Errorf("acTL has %v frames and there are %v fcTL chunks instead of 3", frames, controls)
+	}
+}
@@ -0,0 +1,73 @@
+package image3d
+
+import (
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/internal/sampling"
+)
+
+// Wrap specifies how coordinates outside of the range 0 to 1 are mapped back
+// into the image, see image2d.Wrap.
+type Wrap = sampling.Wrap
+
+// Filter specifies how the pixels around a sample position are interpolated,
+// see image2d.Filter.
+type Filter = sampling.Filter
+
+const (
+	// Repeat tiles the image like gl.REPEAT.
+	Repeat = sampling.Repeat
+	// Clamp repeats the border pixels like gl.CLAMP_TO_EDGE.
+	Clamp = sampling.Clamp
+	// Mirror tiles the image with every other tile mirrored like gl.MIRRORED_REPEAT.
+	Mirror = sampling.Mirror
+
+	// Nearest returns the pixel that contains the sample position like gl.NEAREST.
+	Nearest = sampling.Nearest
+	// Linear interpolates the 2 closest pixels along each axis like gl.LINEAR.
+	Linear = sampling.Linear
+	// Cubic interpolates the 4 closest pixels along each axis with a Catmull-Rom spline.
+	Cubic = sampling.Cubic
+)
+
+// SampleChannel returns the value of channel c at the normalized coordinates
+// (u,v,w), where w selects the slice. Like with OpenGL the center of slice i
+// is located at (i+0.5)/slices. Unsigned integers are mapped to the range 0 to 1.
+// If channel c doesn't exist 0 is returned.
+func (image *Image3D) SampleChannel(u, v, w float32, c int, wrap Wrap, filter Filter) float32 {
+	tx := sampling.MakeTaps(u, image.width, wrap, filter)
+	ty := sampling.MakeTaps(v, image.height, wrap, filter)
+	tz := sampling.MakeTaps(w, image.slices, wrap, filter)
+	return image.sampleTaps(&tx, &ty, &tz, c)
+}
+
+// Sample returns the values of all channels at the normalized coordinates (u,v,w)
+// as described in SampleChannel.
+func (image *Image3D) Sample(u, v, w float32, wrap Wrap, filter Filter) []float32 {
+	tx := sampling.MakeTaps(u, image.width, wrap, filter)
+	ty := sampling.MakeTaps(v, image.height, wrap, filter)
+	tz := sampling.MakeTaps(w, image.slices, wrap, filter)
+
+	result := make([]float32, image.channels)
+	for c := range result {
+		result[c] = image.sampleTaps(&tx, &ty, &tz, c)
+	}
+	return result
+}
+
+// sampleTaps returns the weighted sum of channel c of the pixels specified by
+// the taps along the x, y and z axis. If channel c doesn't exist 0 is returned.
+func (image *Image3D) sampleTaps(tx, ty, tz *sampling.Taps, c int) float32 {
+	if c < 0 || c >= image.channels {
+		return 0
+	}
+
+	var val float32
+	for k := 0; k < tz.Count; k++ {
+		slice := &image.data[tz.Index[k]]
+		for j := 0; j < ty.Count; j++ {
+			for i := 0; i < tx.Count; i++ {
+				val += tx.Weight[i] * ty.Weight[j] * tz.Weight[k] * slice.GetFloat(tx.Index[i], ty.Index[j], c)
+			}
+		}
+	}
+	return val
+}
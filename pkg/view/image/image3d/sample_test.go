@@ -0,0 +1,108 @@
+package image3d
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSampleChannel(t *testing.T) {
+	// 1x1x2 image with two channels, the second one is the negated first one
+	image, err := MakeFromFloat32(1, 1, 2, []float32{1, -1, 3, -3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for c, sign := range []float32{1, -1} {
+		if val := image.SampleChannel(0.5, 0.5, 0.5, c, Clamp, Linear); val != sign*2 {
+			t.Errorf("channel %v between the slices is %v instead of %v", c, val, sign*2)
+		}
+		if val := image.SampleChannel(0.5, 0.5, 0.75, c, Clamp, Nearest); val != sign*3 {
+			t.Errorf("channel %v of the second slice is %v instead of %v", c, val, sign*3)
+		}
+	}
+
+	for _, c := range []int{-1, 2} {
+		if val := image.SampleChannel(0.5, 0.5, 0.5, c, Clamp, Linear); val != 0 {
+			t.Errorf("channel %v is %v instead of 0", c, val)
+		}
+	}
+	if values := image.Sample(0.5, 0.5, 0.25, Repeat, Nearest); len(values) != 2 || values[0] != 1 || values[1] != -1 {
+		t.Errorf("sample is %v instead of [1 -1]", values)
+	}
+}
+
+func TestSampleWrap(t *testing.T) {
+	// 1x1x4 image whose slices hold 0 to 3
+	image, err := MakeFromFloat32(1, 1, 4, []float32{0, 1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		w        float32
+		wrap     Wrap
+		filter   Filter
+		expected float32
+	}{
+		{0, Repeat, Linear, 1.5},
+		{1, Repeat, Linear, 1.5},
+		{0, Clamp, Linear, 0},
+		{1, Clamp, Linear, 3},
+		{1.125, Repeat, Nearest, 0},
+		{-0.125, Repeat, Nearest, 3},
+		{1.125, Clamp, Nearest, 3},
+		{-0.125, Clamp, Nearest, 0},
+		{-0.125, Mirror, Nearest, 0},
+		{1.375, Mirror, Nearest, 2},
+	}
+	for _, test := range tests {
+		actual := image.SampleChannel(0.5, 0.5, test.w, 0, test.wrap, test.filter)
+		if math.Abs(float64(actual-test.expected)) > 1e-6 {
+			t.Errorf("%v %v at w %v is %v instead of %v", test.wrap, test.filter, test.w, actual, test.expected)
+		}
+	}
+
+	// the x and y axis wrap within each slice
+	image, err = MakeFromFloat32(2, 2, 1, []float32{0, 1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := image.SampleChannel(0, 0, 0.5, 0, Repeat, Linear); actual != 1.5 {
+		t.Errorf("repeat at the corner is %v instead of 1.5", actual)
+	}
+	if actual := image.SampleChannel(0, 0, 0.5, 0, Clamp, Linear); actual != 0 {
+		t.Errorf("clamp at the corner is %v instead of 0", actual)
+	}
+}
+
+func TestSampleTricubic(t *testing.T) {
+	// f(x,y,z) = x² + 4y + 16z at the pixel centers
+	var data []float32
+	for z := 0; z < 4; z++ {
+		for y := 0; y < 4; y++ {
+			for x := 0; x < 4; x++ {
+				data = append(data, float32(x*x+4*y+16*z))
+			}
+		}
+	}
+	image, err := MakeFromFloat32(4, 4, 4, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		x, y, z  float32
+		expected float32
+	}{
+		{1, 2, 1, 1 + 8 + 16},
+		{1.5, 1.5, 1.5, 2.25 + 6 + 24},
+		{1.25, 1.5, 1.75, 1.5625 + 6 + 28},
+	}
+	for _, test := range tests {
+		u, v, w := (test.x+0.5)/4, (test.y+0.5)/4, (test.z+0.5)/4
+		actual := image.SampleChannel(u, v, w, 0, Clamp, Cubic)
+		if math.Abs(float64(actual-test.expected)) > 1e-4 {
+			t.Errorf("tricubic at (%v,%v,%v) is %v instead of %v", test.x, test.y, test.z, actual, test.expected)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package image3d
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+)
+
+// Compression specifies how the pixel data of a volume file is stored.
+type Compression uint32
+
+const (
+	// Raw stores the pixel data uncompressed.
+	Raw Compression = iota
+	// Zlib stores the pixel data compressed with zlib.
+	Zlib
+)
+
+// volumeMagic identifies a volume file.
+var volumeMagic = [4]byte{'R', 'C', 'V', 'L'}
+
+// volumeVersion is the version of the volume file format.
+const volumeVersion uint32 = 1
+
+const (
+	// maxVolumeDimension is the biggest width, height and number of slices
+	// of a volume file, which is far beyond the 3D texture size of any GPU.
+	maxVolumeDimension = 1 << 14
+	// maxVolumeSize is the biggest size in bytes of the pixel data of a volume file.
+	maxVolumeSize = 1 << 32
+	// maxZlibRatio is the theoretical maximum compression ratio of zlib,
+	// which bounds the size of compressed pixel data by the file size.
+	maxZlibRatio = 1032
+)
+
+// volumeHeader is the fixed size header at the beginning of a volume file.
+// It is followed by ParamsLength bytes of json encoded generation parameters
+// and the pixel data, which is stored slice by slice, each slice row by row.
+// All values are stored in little endian byte order.
+type volumeHeader struct {
+	Magic        [4]byte
+	Version      uint32
+	Width        uint32
+	Height       uint32
+	Slices       uint32
+	Channels     uint32
+	PixelType    uint32
+	Compression  Compression
+	ParamsLength uint32
+}
+
+// Load constructs the image from a single volume file that had been written
+// with Save. If the file doesn't exist or is no valid volume file an error is
+// returned instead.
+func Load(path string) (Image3D, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Image3D{}, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return Image3D{}, err
+	}
+	reader := bufio.NewReader(file)
+
+	// read and validate the header
+	var header volumeHeader
+	if err := binary.Read(reader, binary.LittleEndian, &header); err != nil {
+		return Image3D{}, fmt.Errorf("%v: can't read volume header: %v", path, err)
+	}
+	if header.Magic != volumeMagic {
+		return Image3D{}, fmt.Errorf("%v: not a volume file", path)
+	}
+	if header.Version != volumeVersion {
+		return Image3D{}, fmt.Errorf("%v: unsupported volume version %v", path, header.Version)
+	}
+	componentSize, err := image2d.GetComponentSize(header.PixelType)
+	if err != nil {
+		return Image3D{}, fmt.Errorf("%v: %v", path, err)
+	}
+	size, err := header.payloadSize(componentSize, info.Size())
+	if err != nil {
+		return Image3D{}, fmt.Errorf("%v: %v", path, err)
+	}
+
+	// read the generation parameters
+	var params map[string]string
+	if header.ParamsLength > 0 {
+		buf := make([]byte, header.ParamsLength)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return Image3D{}, fmt.Errorf("%v: can't read parameters: %v", path, err)
+		}
+		if err := json.Unmarshal(buf, &params); err != nil {
+			return Image3D{}, fmt.Errorf("%v: invalid parameters: %v", path, err)
+		}
+	}
+
+	// read the pixel data
+	var payload io.Reader = reader
+	switch header.Compression {
+	case Raw:
+	case Zlib:
+		zreader, err := zlib.NewReader(reader)
+		if err != nil {
+			return Image3D{}, fmt.Errorf("%v: %v", path, err)
+		}
+		defer zreader.Close()
+		payload = zreader
+	default:
+		return Image3D{}, fmt.Errorf("%v: unsupported compression %v", path, header.Compression)
+	}
+	data := make([]uint8, size)
+	if _, err := io.ReadFull(payload, data); err != nil {
+		return Image3D{}, fmt.Errorf("%v: can't read pixel data: %v", path, err)
+	}
+
+	image, err := MakeFromRawData(int(header.Width), int(header.Height), int(header.Slices), int(header.Channels), header.PixelType, data)
+	if err != nil {
+		return Image3D{}, err
+	}
+	image.params = params
+
+	return image, nil
+}
+
+// payloadSize returns the size in bytes of the pixel data described by the
+// header. It validates the header against the size of the file before anything
+// is allocated, thus corrupt files can't request huge amounts of memory.
+func (header *volumeHeader) payloadSize(componentSize int, fileSize int64) (int, error) {
+	dims := []struct {
+		name string
+		val  uint32
+	}{{"width", header.Width}, {"height", header.Height}, {"slices", header.Slices}}
+	for _, dim := range dims {
+		if dim.val < 1 || dim.val > maxVolumeDimension {
+			return 0, fmt.Errorf("%v %v out of range 1..%v", dim.name, dim.val, maxVolumeDimension)
+		}
+	}
+	if header.Channels < 1 || header.Channels > 4 {
+		return 0, fmt.Errorf("channels %v out of range 1..4", header.Channels)
+	}
+
+	// the parameters and the pixel data have to fit into the file
+	remaining := fileSize - int64(binary.Size(header)) - int64(header.ParamsLength)
+	if remaining < 0 {
+		return 0, fmt.Errorf("parameters of %v bytes exceed the file size", header.ParamsLength)
+	}
+	size := int64(header.Width) * int64(header.Height) * int64(header.Slices) * int64(header.Channels) * int64(componentSize)
+	if size > maxVolumeSize {
+		return 0, fmt.Errorf("pixel data of %v bytes exceeds the maximum of %v bytes", size, int64(maxVolumeSize))
+	}
+	switch header.Compression {
+	case Raw:
+		if size != remaining {
+			return 0, fmt.Errorf("pixel data of %v bytes doesn't match the remaining %v bytes of the file", size, remaining)
+		}
+	case Zlib:
+		if size > remaining*maxZlibRatio {
+			return 0, fmt.Errorf("pixel data of %v bytes can't be compressed into %v bytes", size, remaining)
+		}
+	}
+
+	return int(size), nil
+}
+
+// Save writes the image including its parameters into a single volume file
+// at the specified path. The pixel data is compressed as specified.
+func (image *Image3D) Save(path string, compression Compression) error {
+	if compression != Raw && compression != Zlib {
+		return errors.New("Unsupported compression")
+	}
+
+	// encode the generation parameters
+	var params []byte
+	if len(image.params) > 0 {
+		var err error
+		params, err = json.Marshal(image.params)
+		if err != nil {
+			return err
+		}
+	}
+
+	// write header and parameters
+	var buf bytes.Buffer
+	header := volumeHeader{
+		Magic:        volumeMagic,
+		Version:      volumeVersion,
+		Width:        uint32(image.width),
+		Height:       uint32(image.height),
+		Slices:       uint32(image.slices),
+		Channels:     uint32(image.channels),
+		PixelType:    image.pixelType,
+		Compression:  compression,
+		ParamsLength: uint32(len(params)),
+	}
+	if err := binary.Write(&buf, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	buf.Write(params)
+
+	// write the pixel data slice by slice
+	switch compression {
+	case Raw:
+		for _, slice := range image.data {
+			buf.Write(slice.GetData())
+		}
+	case Zlib:
+		zwriter := zlib.NewWriter(&buf)
+		for _, slice := range image.data {
+			if _, err := zwriter.Write(slice.GetData()); err != nil {
+				return err
+			}
+		}
+		if err := zwriter.Close(); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
@@ -0,0 +1,136 @@
+package image3d
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+)
+
+// pixelTypes are all pixel types that can be stored in a volume file
+var pixelTypes = []uint32{gl.UNSIGNED_BYTE, gl.UNSIGNED_SHORT, gl.HALF_FLOAT, gl.FLOAT}
+
+// makeTestVolume creates a volume whose bytes all differ from their neighbors.
+func makeTestVolume(t *testing.T, width, height, slices, channels int, pixelType uint32) Image3D {
+	componentSize, err := image2d.GetComponentSize(pixelType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]uint8, width*height*slices*channels*componentSize)
+	for i := range data {
+		data[i] = uint8(i*7 + i/13)
+	}
+	image, err := MakeFromRawData(width, height, slices, channels, pixelType, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return image
+}
+
+func TestVolumeRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	for _, compression := range []Compression{Raw, Zlib} {
+		for _, pixelType := range pixelTypes {
+			for _, channels := range []int{1, 2, 3, 4} {
+				image := makeTestVolume(t, 5, 3, 4, channels, pixelType)
+				image.SetParam("seed", 42)
+				image.SetParam("name", "test")
+
+				path := filepath.Join(dir, "test.vol")
+				if err := image.Save(path, compression); err != nil {
+					t.Fatalf("compression %v, pixel type %v, channels %v: %v", compression, pixelType, channels, err)
+				}
+				loaded, err := Load(path)
+				if err != nil {
+					t.Fatalf("compression %v, pixel type %v, channels %v: %v", compression, pixelType, channels, err)
+				}
+
+				if loaded.GetWidth() != 5 || loaded.GetHeight() != 3 || loaded.GetSlices() != 4 ||
+					loaded.GetChannels() != channels || loaded.GetPixelType() != pixelType {
+					t.Errorf("compression %v, pixel type %v, channels %v: loaded %v", compression, pixelType, channels, loaded)
+				}
+				if !bytes.Equal(loaded.GetData(), image.GetData()) {
+					t.Errorf("compression %v, pixel type %v, channels %v: pixel data differs", compression, pixelType, channels)
+				}
+				params := loaded.GetParams()
+				if params["seed"] != "42" || params["name"] != "test" || len(params) != 2 {
+					t.Errorf("compression %v, pixel type %v, channels %v: params are %v", compression, pixelType, channels, params)
+				}
+			}
+		}
+	}
+}
+
+func TestVolumeSaveUnsupportedCompression(t *testing.T) {
+	image := makeTestVolume(t, 2, 2, 2, 1, gl.UNSIGNED_BYTE)
+	if err := image.Save(filepath.Join(t.TempDir(), "test.vol"), Compression(7)); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestVolumeLoadInvalid(t *testing.T) {
+	// header of a valid raw 2x2x2 volume with one 8 bit channel
+	valid := volumeHeader{
+		Magic:     volumeMagic,
+		Version:   volumeVersion,
+		Width:     2,
+		Height:    2,
+		Slices:    2,
+		Channels:  1,
+		PixelType: gl.UNSIGNED_BYTE,
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(header *volumeHeader)
+		payload int
+		err     string
+	}{
+		{"valid", func(header *volumeHeader) {}, 8, ""},
+		{"magic", func(header *volumeHeader) { header.Magic = [4]byte{'R', 'I', 'F', 'F'} }, 8, "not a volume file"},
+		{"version", func(header *volumeHeader) { header.Version = 2 }, 8, "unsupported volume version"},
+		{"pixel type", func(header *volumeHeader) { header.PixelType = gl.INT }, 8, "Unsupported pixel type"},
+		{"zero width", func(header *volumeHeader) { header.Width = 0 }, 8, "width 0 out of range"},
+		{"huge height", func(header *volumeHeader) { header.Height = 1 << 30 }, 8, "height 1073741824 out of range"},
+		{"huge volume", func(header *volumeHeader) {
+			header.Width, header.Height, header.Slices, header.Channels = 1<<14, 1<<14, 1<<14, 4
+		}, 8, "exceeds the maximum"},
+		{"channels", func(header *volumeHeader) { header.Channels = 5 }, 8, "channels 5 out of range"},
+		{"params", func(header *volumeHeader) { header.ParamsLength = 1 << 31 }, 8, "exceed the file size"},
+		{"truncated", func(header *volumeHeader) {}, 7, "doesn't match"},
+		{"trailing", func(header *volumeHeader) {}, 9, "doesn't match"},
+		{"big raw", func(header *volumeHeader) { header.Width = 1 << 10 }, 8, "doesn't match"},
+		{"big zlib", func(header *volumeHeader) {
+			header.Width, header.Height, header.Slices, header.Compression = 1<<10, 1<<10, 1<<10, Zlib
+		}, 8, "can't be compressed"},
+		{"compression", func(header *volumeHeader) { header.Compression = 7 }, 8, "unsupported compression"},
+	}
+
+	dir := t.TempDir()
+	for _, test := range tests {
+		header := valid
+		test.modify(&header)
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.LittleEndian, &header)
+		buf.Write(make([]byte, test.payload))
+
+		path := filepath.Join(dir, "test.vol")
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(path)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%v: unexpected error %v", test.name, err)
+		case test.err != "" && err == nil:
+			t.Errorf("%v: expected error containing %q", test.name, test.err)
+		case test.err != "" && !strings.Contains(err.Error(), test.err):
+			t.Errorf("%v: error %q doesn't contain %q", test.name, err, test.err)
+		}
+	}
+}
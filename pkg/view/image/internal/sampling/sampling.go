@@ -0,0 +1,125 @@
+// Package sampling calculates which pixels contribute to a sample of an image
+// and how much, which is shared by the 2D and 3D images.
+package sampling
+
+import (
+	"fmt"
+	"math"
+)
+
+// Wrap specifies how coordinates outside of the range 0 to 1 are mapped back
+// into the image, like the wrap modes of OpenGL samplers.
+type Wrap int
+
+const (
+	// Repeat tiles the image like gl.REPEAT.
+	Repeat Wrap = iota
+	// Clamp repeats the border pixels like gl.CLAMP_TO_EDGE.
+	Clamp
+	// Mirror tiles the image with every other tile mirrored like gl.MIRRORED_REPEAT.
+	Mirror
+)
+
+// String returns the name of the wrap mode.
+func (wrap Wrap) String() string {
+	switch wrap {
+	case Repeat:
+		return "repeat"
+	case Clamp:
+		return "clamp"
+	case Mirror:
+		return "mirror"
+	}
+	return fmt.Sprintf("Wrap(%d)", int(wrap))
+}
+
+// Filter specifies how the pixels around a sample position are interpolated.
+type Filter int
+
+const (
+	// Nearest returns the pixel that contains the sample position like gl.NEAREST.
+	Nearest Filter = iota
+	// Linear interpolates the 2 closest pixels along each axis like gl.LINEAR.
+	Linear
+	// Cubic interpolates the 4 closest pixels along each axis with a Catmull-Rom spline.
+	Cubic
+)
+
+// String returns the name of the filter.
+func (filter Filter) String() string {
+	switch filter {
+	case Nearest:
+		return "nearest"
+	case Linear:
+		return "linear"
+	case Cubic:
+		return "cubic"
+	}
+	return fmt.Sprintf("Filter(%d)", int(filter))
+}
+
+// Taps are the pixels along one axis and their weights that contribute to a sample.
+type Taps struct {
+	Index  [4]int
+	Weight [4]float32
+	Count  int
+}
+
+// MakeTaps calculates the pixels and weights along an axis of the specified size
+// for the normalized coordinate coord. Like with OpenGL the center of pixel i is
+// located at (i+0.5)/size.
+func MakeTaps(coord float32, size int, wrap Wrap, filter Filter) Taps {
+	var taps Taps
+	x := float64(coord)*float64(size) - 0.5
+
+	switch filter {
+	case Linear:
+		i := math.Floor(x)
+		f := float32(x - i)
+		taps.Count = 2
+		taps.Index = [4]int{int(i), int(i) + 1}
+		taps.Weight = [4]float32{1 - f, f}
+	case Cubic:
+		i := math.Floor(x)
+		t := float32(x - i)
+		t2, t3 := t*t, t*t*t
+		taps.Count = 4
+		taps.Index = [4]int{int(i) - 1, int(i), int(i) + 1, int(i) + 2}
+		taps.Weight = [4]float32{
+			0.5 * (-t3 + 2*t2 - t),
+			0.5 * (3*t3 - 5*t2 + 2),
+			0.5 * (-3*t3 + 4*t2 + t),
+			0.5 * (t3 - t2),
+		}
+	default:
+		taps.Count = 1
+		taps.Index[0] = int(math.Floor(x + 0.5))
+		taps.Weight[0] = 1
+	}
+
+	for i := 0; i < taps.Count; i++ {
+		taps.Index[i] = WrapIndex(taps.Index[i], size, wrap)
+	}
+	return taps
+}
+
+// WrapIndex maps the pixel index i into the range 0 to size-1 with the specified wrap mode.
+func WrapIndex(i, size int, wrap Wrap) int {
+	switch wrap {
+	case Clamp:
+		if i < 0 {
+			return 0
+		}
+		if i >= size {
+			return size - 1
+		}
+		return i
+	case Mirror:
+		i = ((i % (2 * size)) + 2*size) % (2 * size)
+		if i >= size {
+			return 2*size - 1 - i
+		}
+		return i
+	}
+	return ((i % size) + size) % size
+}
@@ -0,0 +1,65 @@
+package sampling
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWrapIndex(t *testing.T) {
+	tests := []struct {
+		wrap     Wrap
+		indices  []int
+		expected []int
+	}{
+		{Repeat, []int{-5, -1, 0, 3, 4, 9}, []int{3, 3, 0, 3, 0, 1}},
+		{Clamp, []int{-5, -1, 0, 3, 4, 9}, []int{0, 0, 0, 3, 3, 3}},
+		// every other tile is mirrored, thus the border pixels repeat once
+		{Mirror, []int{-8, -5, -2, -1, 0, 3, 4, 5, 7, 8, 12}, []int{0, 3, 1, 0, 0, 3, 3, 2, 0, 0, 3}},
+	}
+	for _, test := range tests {
+		for i, idx := range test.indices {
+			if actual := WrapIndex(idx, 4, test.wrap); actual != test.expected[i] {
+				t.Errorf("%v of %v is %v instead of %v", test.wrap, idx, actual, test.expected[i])
+			}
+		}
+	}
+}
+
+func TestMakeTaps(t *testing.T) {
+	tests := []struct {
+		coord   float32
+		wrap    Wrap
+		filter  Filter
+		indices []int
+		weights []float32
+	}{
+		{0.3, Repeat, Nearest, []int{1}, []float32{1}},
+		{0, Repeat, Nearest, []int{0}, []float32{1}},
+		{0.5, Repeat, Linear, []int{1, 2}, []float32{0.5, 0.5}},
+		{0, Repeat, Linear, []int{3, 0}, []float32{0.5, 0.5}},
+		{0, Mirror, Linear, []int{0, 0}, []float32{0.5, 0.5}},
+		{1, Clamp, Linear, []int{3, 3}, []float32{0.5, 0.5}},
+		// Catmull-Rom weights halfway between two pixels
+		{0.5, Clamp, Cubic, []int{0, 1, 2, 3}, []float32{-1.0 / 16, 9.0 / 16, 9.0 / 16, -1.0 / 16}},
+		{0.4375, Repeat, Cubic, []int{0, 1, 2, 3}, []float32{-0.0703125, 0.8671875, 0.2265625, -0.0234375}},
+		{0, Mirror, Cubic, []int{1, 0, 0, 1}, []float32{-1.0 / 16, 9.0 / 16, 9.0 / 16, -1.0 / 16}},
+	}
+	for _, test := range tests {
+		taps := MakeTaps(test.coord, 4, test.wrap, test.filter)
+		if taps.Count != len(test.indices) {
+			t.Errorf("%v %v at %v: %v taps instead of %v", test.wrap, test.filter, test.coord, taps.Count, len(test.indices))
+			continue
+		}
+		sum := float32(0)
+		for i := 0; i < taps.Count; i++ {
+			sum += taps.Weight[i]
+			if taps.Index[i] != test.indices[i] || math.Abs(float64(taps.Weight[i]-test.weights[i])) > 1e-6 {
+				t.Errorf("%v %v at %v: tap %v is pixel %v with weight %v instead of pixel %v with weight %v",
+					test.wrap, test.filter, test.coord, i, taps.Index[i], taps.Weight[i], test.indices[i], test.weights[i])
+			}
+		}
+		if math.Abs(float64(sum-1)) > 1e-6 {
+			t.Errorf("%v %v at %v: weights sum up to %v", test.wrap, test.filter, test.coord, sum)
+		}
+	}
+}
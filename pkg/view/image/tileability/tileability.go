@@ -0,0 +1,198 @@
+// Package tileability checks if images wrap seamlessly.
+package tileability
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image3d"
+)
+
+// DefaultThreshold is the default upper bound of the ratio between the mean
+// seam difference and the mean difference next to the seam of a tileable axis.
+// It is also the number of interior standard deviations the mean seam
+// difference may exceed the mean interior difference.
+const DefaultThreshold float64 = 1.5
+
+// AxisReport holds the seam statistics of one axis.
+// SeamMean is the mean absolute difference between the first and the last
+// pixel along the axis. InteriorMean and InteriorStd are the mean and the
+// standard deviation of the mean absolute differences between neighboring
+// pixels at each other position along the axis. Ratio is SeamMean divided by the mean difference between
+// the pixels next to the seam.
+type AxisReport struct {
+	Axis         string
+	SeamMean     float64
+	InteriorMean float64
+	InteriorStd  float64
+	Ratio        float64
+	Tileable     bool
+}
+
+// Report holds the seam statistics of all axes of an image.
+type Report struct {
+	Axes []AxisReport
+}
+
+// Tileable returns true if all axes are tileable.
+func (report *Report) Tileable() bool {
+	for _, axis := range report.Axes {
+		if !axis.Tileable {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns an error listing all axes if any axis is not tileable,
+// otherwise it returns nil.
+func (report *Report) Err() error {
+	if report.Tileable() {
+		return nil
+	}
+	return fmt.Errorf("image is not tileable\n%v", report)
+}
+
+// String returns one line per axis.
+func (report Report) String() string {
+	var lines []string
+	for _, axis := range report.Axes {
+		status := "ok"
+		if !axis.Tileable {
+			status = "SEAM"
+		}
+		lines = append(lines, fmt.Sprintf("%v: %v seam %.4f interior %.4f (std %.4f) ratio %.2f",
+			axis.Axis, status, axis.SeamMean, axis.InteriorMean, axis.InteriorStd, axis.Ratio))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Check2D measures the seams of the image along the x and y axis.
+// An axis is tileable if the ratio between the mean seam difference and the
+// mean difference next to the seam is not bigger than threshold, or if the
+// mean seam difference is within threshold standard deviations of the mean
+// interior difference.
+func Check2D(image *image2d.Image2D, threshold float64) Report {
+	v := volume{
+		width:    image.GetWidth(),
+		height:   image.GetHeight(),
+		depth:    1,
+		channels: image.GetChannels(),
+		data:     image.GetFloat32Data(),
+	}
+
+	return Report{
+		Axes: []AxisReport{
+			v.checkAxis("x", 1, 0, 0, threshold),
+			v.checkAxis("y", 0, 1, 0, threshold),
+		},
+	}
+}
+
+// Check3D measures the seams of the image along the x, y and z axis.
+// An axis is tileable if the ratio between the mean seam difference and the
+// mean difference next to the seam is not bigger than threshold, or if the
+// mean seam difference is within threshold standard deviations of the mean
+// interior difference.
+func Check3D(image *image3d.Image3D, threshold float64) Report {
+	v := volume{
+		width:    image.GetWidth(),
+		height:   image.GetHeight(),
+		depth:    image.GetSlices(),
+		channels: image.GetChannels(),
+		data:     image.GetFloat32Data(),
+	}
+
+	return Report{
+		Axes: []AxisReport{
+			v.checkAxis("x", 1, 0, 0, threshold),
+			v.checkAxis("y", 0, 1, 0, threshold),
+			v.checkAxis("z", 0, 0, 1, threshold),
+		},
+	}
+}
+
+// volume provides access to the interleaved pixel data of 2D and 3D images.
+// The data is converted with GetFloat32Data, which maps unsigned integers to
+// the range 0 to 1.
+type volume struct {
+	width    int
+	height   int
+	depth    int
+	channels int
+	data     []float32
+}
+
+// get returns the value of channel c at (x,y,z).
+func (v *volume) get(x, y, z, c int) float64 {
+	idx := (x+y*v.width+z*v.width*v.height)*v.channels + c
+	return float64(v.data[idx])
+}
+
+// checkAxis compares the differences across the seam of the axis specified
+// by the step (dx,dy,dz) with the differences between neighboring pixels.
+// Since the gradient of smooth noise varies along the axis, the seam is
+// compared to the differences right next to it on both sides. A seam that
+// stands out from the differences next to it is still accepted if it stays
+// within threshold standard deviations of the interior mean, as then the
+// differences next to the seam are just unusually small.
+func (v *volume) checkAxis(name string, dx, dy, dz int, threshold float64) AxisReport {
+	report := AxisReport{Axis: name, Tileable: true}
+
+	// length of the axis
+	length := dx*v.width + dy*v.height + dz*v.depth
+	if length < 3 {
+		return report
+	}
+
+	// sum up the differences at each position along the axis, the last
+	// position holds the differences across the seam
+	sums := make([]float64, length)
+	var count int = 0
+	for z := 0; z < v.depth; z++ {
+		for y := 0; y < v.height; y++ {
+			for x := 0; x < v.width; x++ {
+				// position along the axis and of the next pixel
+				pos := dx*x + dy*y + dz*z
+				xn := (x + dx) % v.width
+				yn := (y + dy) % v.height
+				zn := (z + dz) % v.depth
+
+				for c := 0; c < v.channels; c++ {
+					sums[pos] += math.Abs(v.get(x, y, z, c) - v.get(xn, yn, zn, c))
+				}
+				if pos == 0 {
+					count += v.channels
+				}
+			}
+		}
+	}
+
+	// calculate the statistics of the mean differences at the interior
+	// positions with Welford's method
+	var mean, m2 float64 = 0, 0
+	for pos := 0; pos < length-1; pos++ {
+		diff := sums[pos] / float64(count)
+		delta := diff - mean
+		mean += delta / float64(pos+1)
+		m2 += delta * (diff - mean)
+	}
+	report.InteriorMean = mean
+	report.InteriorStd = math.Sqrt(m2 / float64(length-1))
+
+	// compare the seam with the differences on both sides of it and with
+	// the interior differences
+	report.SeamMean = sums[length-1] / float64(count)
+	local := (sums[0] + sums[length-2]) / float64(2*count)
+	if local > 0 {
+		report.Ratio = report.SeamMean / local
+	} else if report.SeamMean > 0 {
+		report.Ratio = math.Inf(1)
+	}
+	withinInterior := report.SeamMean <= report.InteriorMean+threshold*report.InteriorStd
+	report.Tileable = report.Ratio <= threshold || withinInterior
+
+	return report
+}
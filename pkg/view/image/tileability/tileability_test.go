@@ -0,0 +1,112 @@
+package tileability
+
+import (
+	"math"
+	"testing"
+
+	"github.com/adrianderstroff/realtime-clouds/pkg/noise"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image2d"
+	"github.com/adrianderstroff/realtime-clouds/pkg/view/image/image3d"
+)
+
+// crop2D returns the top left size x size pixels of the one channel image
+// data of the specified width.
+func crop2D(data []uint8, width, size int) []uint8 {
+	var cropped []uint8
+	for y := 0; y < size; y++ {
+		cropped = append(cropped, data[y*width:y*width+size]...)
+	}
+	return cropped
+}
+
+// crop3D returns the front top left size x size x size pixels of the one
+// channel volume data of the specified width, height and depth.
+func crop3D(data []uint8, width, height, size int) []uint8 {
+	var cropped []uint8
+	for z := 0; z < size; z++ {
+		cropped = append(cropped, crop2D(data[z*width*height:], width, size)...)
+	}
+	return cropped
+}
+
+// checkReport fails if any axis of the report differs from tileable.
+func checkReport(t *testing.T, name string, report Report, tileable bool) {
+	for _, axis := range report.Axes {
+		if axis.Tileable != tileable {
+			t.Errorf("%v: axis %v is tileable %v instead of %v\n%v", name, axis.Axis, axis.Tileable, tileable, report)
+		}
+	}
+	if report.Tileable() != tileable || (report.Err() == nil) != tileable {
+		t.Errorf("%v: report is tileable %v with error %v", name, report.Tileable(), report.Err())
+	}
+}
+
+func TestCheck2D(t *testing.T) {
+	const size = 64
+	fields := map[string][]uint8{
+		"perlin":  noise.Perlin2D(size, size, 4, 0.5, 3),
+		"worley":  noise.Worley2D(size, size, 4, 16, 3),
+		"perlin1": noise.Perlin2D(size, size, 1, 0.5, 5),
+	}
+	for name, data := range fields {
+		image, err := image2d.MakeFromData(size, size, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReport(t, name, Check2D(&image, DefaultThreshold), true)
+
+		// cutting off a part of the field introduces seams
+		cropped, err := image2d.MakeFromData(size*3/4, size*3/4, crop2D(data, size, size*3/4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReport(t, name+" cropped", Check2D(&cropped, DefaultThreshold), false)
+	}
+}
+
+func TestCheck3D(t *testing.T) {
+	const size = 32
+	fields := map[string][]uint8{
+		"perlin":       noise.Perlin3D(size, size, size, 3, 1, 3),
+		"worley":       noise.Worley3D(size, size, size, 4, 3),
+		"perlinworley": noise.PerlinWorley3D(size, size, size, 4, 3, 4, 2, 3),
+	}
+	for name, data := range fields {
+		image, err := image3d.MakeFromData(size, size, size, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReport(t, name, Check3D(&image, DefaultThreshold), true)
+
+		cropped, err := image3d.MakeFromData(size*3/4, size*3/4, size*3/4, crop3D(data, size, size, size*3/4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkReport(t, name+" cropped", Check3D(&cropped, DefaultThreshold), false)
+	}
+}
+
+func TestCheckConstant(t *testing.T) {
+	// a constant image is tileable, a ramp isn't
+	data := make([]uint8, 4*4)
+	image, err := image2d.MakeFromData(4, 4, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkReport(t, "constant", Check2D(&image, DefaultThreshold), true)
+
+	for i := range data {
+		data[i] = uint8(i%4*40 + i/4*40)
+	}
+	image, err = image2d.MakeFromData(4, 4, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Check2D(&image, DefaultThreshold)
+	checkReport(t, "ramp", report, false)
+	for _, axis := range report.Axes {
+		if math.Abs(axis.SeamMean-3*axis.InteriorMean) > 1e-6 || axis.InteriorStd > 1e-6 {
+			t.Errorf("axis %v has seam %v and interior %v (std %v)", axis.Axis, axis.SeamMean, axis.InteriorMean, axis.InteriorStd)
+		}
+	}
+}
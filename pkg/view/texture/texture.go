@@ -2,6 +2,9 @@
 package texture
 
 import (
+	"errors"
+	"path/filepath"
+	"strings"
 	"unsafe"
 
 	gl "github.com/adrianderstroff/realtime-clouds/pkg/core/gl"
@@ -235,6 +238,80 @@ func Make3DFromImage(image3d *image3d.Image3D, internalformat int32, format uint
 		image3d.GetPixelType(), gl.Ptr(data), gl.NEAREST, gl.NEAREST, gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE), nil
 }
 
+// Make3DFromFile creates a 3D texture with the data of the file at the specified path.
+// DDS and KTX2 files are uploaded with all their mip levels and their own format,
+// thus internalformat and format are only used for volume files.
+func Make3DFromFile(path string, internalformat int32, format uint32) (Texture, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dds":
+		chain, err := image3d.LoadDDS(path)
+		if err != nil {
+			return Texture{}, err
+		}
+		return Make3DFromMipChain(&chain)
+	case ".ktx2":
+		chain, err := image3d.LoadKTX2(path)
+		if err != nil {
+			return Texture{}, err
+		}
+		return Make3DFromMipChain(&chain)
+	}
+
+	image, err := image3d.Load(path)
+	if err != nil {
+		return Texture{}, err
+	}
+
+	return Make3DFromImage(&image, internalformat, format)
+}
+
+// Make3DWithLevels constructs a 3D texture like Make3D but with explicitly specified mip levels.
+// Levels points to the data of each level, starting with the full resolution level of size
+// width x height x depth. Each following level halves each dimension but is at least 1 pixel.
+// The rows of the levels have to be tightly packed.
+func Make3DWithLevels(width, height, depth, internalformat int32, format, pixelType uint32, levels []unsafe.Pointer, min, mag, s, t, r int32) Texture {
+	texture := Make3D(width, height, depth, internalformat, format, pixelType, nil, min, mag, s, t, r)
+
+	// specify each level
+	texture.Bind(0)
+	var previousAlignment int32
+	gl.GetIntegerv(gl.UNPACK_ALIGNMENT, &previousAlignment)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	for level, data := range levels {
+		gl.TexImage3D(gl.TEXTURE_3D, int32(level), internalformat, width, height, depth, 0, format, pixelType, data)
+		width, height, depth = halveSize(width), halveSize(height), halveSize(depth)
+	}
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, previousAlignment)
+	gl.TexParameteri(gl.TEXTURE_3D, gl.TEXTURE_BASE_LEVEL, 0)
+	gl.TexParameteri(gl.TEXTURE_3D, gl.TEXTURE_MAX_LEVEL, int32(len(levels)-1))
+	texture.Unbind()
+
+	return texture
+}
+
+// Make3DFromMipChain creates a 3D texture with all levels of the mip chain.
+// The internal format and the pixel format are derived from the format of the mip chain.
+// If the chain has more than one level the texture uses trilinear filtering.
+func Make3DFromMipChain(chain *image3d.MipChain) (Texture, error) {
+	if chain.GetLevelCount() == 0 {
+		return Texture{}, errors.New("Mip chain has no levels")
+	}
+
+	format := chain.GetFormat()
+	width, height, depth := chain.GetLevelSize(0)
+	var min int32 = gl.LINEAR
+	if chain.GetLevelCount() > 1 {
+		min = gl.LINEAR_MIPMAP_LINEAR
+	}
+	var levels []unsafe.Pointer
+	for level := 0; level < chain.GetLevelCount(); level++ {
+		levels = append(levels, gl.Ptr(chain.GetLevelData(level)))
+	}
+
+	return Make3DWithLevels(int32(width), int32(height), int32(depth), format.GetInternalFormat(), format.GetPixelFormat(),
+		format.GetPixelType(), levels, min, gl.LINEAR, gl.REPEAT, gl.REPEAT, gl.REPEAT), nil
+}
+
 // Make3DFromImage creates a 3D texture with the data of the 3D image.
 func Make3DFromData(data []uint8, width, height, slices int, internalformat int32, format uint32) (Texture, error) {
 	return Make3D(int32(width), int32(height), int32(slices), internalformat, format,
@@ -252,6 +329,92 @@ func (tex *Texture) Replace(width, height int, internalformat int32, format, pix
 	tex.Unbind()
 }
 
+// ReadImage2D reads the first level of the 2D texture of the specified size back into an image
+// with the specified number of channels and pixel type, e.g. gl.FLOAT to keep the full range of
+// floating point textures.
+func (tex *Texture) ReadImage2D(width, height, channels int, pixelType uint32) (image2d.Image2D, error) {
+	formats := []uint32{gl.RED, gl.RG, gl.RGB, gl.RGBA}
+	if channels < 1 || channels > len(formats) {
+		return image2d.Image2D{}, errors.New("Number of channels must be between 1 and 4.")
+	}
+	size, err := image2d.GetComponentSize(pixelType)
+	if err != nil {
+		return image2d.Image2D{}, err
+	}
+
+	// rows of the image are tightly packed
+	data := make([]uint8, width*height*channels*size)
+	tex.Bind(0)
+	var previousAlignment int32
+	gl.GetIntegerv(gl.PACK_ALIGNMENT, &previousAlignment)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.GetTexImage(tex.target, 0, formats[channels-1], pixelType, gl.Ptr(data))
+	gl.PixelStorei(gl.PACK_ALIGNMENT, previousAlignment)
+	tex.Unbind()
+
+	return image2d.MakeFromRawData(width, height, channels, pixelType, data)
+}
+
+// ReadMipChain reads all levels of a 3D texture into a mip chain of the
+// specified format, e.g. to save the texture with SaveKTX2. The levels are
+// converted to the format by OpenGL. An error is returned if the texture
+// isn't a 3D texture.
+func (tex *Texture) ReadMipChain(format image3d.Format) (image3d.MipChain, error) {
+	if tex.target != gl.TEXTURE_3D {
+		return image3d.MipChain{}, errors.New("Only 3D textures can be read into a mip chain")
+	}
+
+	var chain image3d.MipChain
+	tex.Bind(0)
+	defer tex.Unbind()
+	var previousAlignment int32
+	gl.GetIntegerv(gl.PACK_ALIGNMENT, &previousAlignment)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	defer gl.PixelStorei(gl.PACK_ALIGNMENT, previousAlignment)
+	for level := int32(0); ; level++ {
+		// levels that haven't been specified have a size of 0
+		var width, height, depth int32
+		gl.GetTexLevelParameteriv(tex.target, level, gl.TEXTURE_WIDTH, &width)
+		gl.GetTexLevelParameteriv(tex.target, level, gl.TEXTURE_HEIGHT, &height)
+		gl.GetTexLevelParameteriv(tex.target, level, gl.TEXTURE_DEPTH, &depth)
+		if width == 0 || height == 0 || depth == 0 {
+			break
+		}
+
+		// rows of the levels are tightly packed
+		data := make([]uint8, int(width*height*depth)*format.GetPixelSize())
+		gl.GetTexImage(tex.target, level, format.GetPixelFormat(), format.GetPixelType(), gl.Ptr(data))
+		var err error
+		if level == 0 {
+			chain, err = image3d.MakeMipChain(format, int(width), int(height), int(depth), data)
+		} else {
+			err = chain.AddLevel(int(width), int(height), int(depth), data)
+		}
+		if err != nil {
+			return image3d.MipChain{}, err
+		}
+
+		if width == 1 && height == 1 && depth == 1 {
+			break
+		}
+	}
+	if chain.GetLevelCount() == 0 {
+		return image3d.MipChain{}, errors.New("Texture has no levels")
+	}
+
+	return chain, nil
+}
+
+// GetSize returns the width and height of the first level of the texture.
+func (tex *Texture) GetSize() (int, int) {
+	var width, height int32
+	tex.Bind(0)
+	gl.GetTexLevelParameteriv(tex.target, 0, gl.TEXTURE_WIDTH, &width)
+	gl.GetTexLevelParameteriv(tex.target, 0, gl.TEXTURE_HEIGHT, &height)
+	tex.Unbind()
+	return int(width), int(height)
+}
+
 // Delete destroys the Texture.
 func (tex *Texture) Delete() {
 	gl.DeleteTextures(1, &tex.handle)
@@ -311,3 +474,11 @@ func (tex *Texture) Unbind() {
 	tex.texPos = 0
 	gl.BindTexture(tex.target, 0)
 }
+
+// halveSize returns the size of the next mip level.
+func halveSize(size int32) int32 {
+	if size > 1 {
+		return size / 2
+	}
+	return 1
+}